@@ -3,6 +3,7 @@ module github.com/gussf/backend-challenge
 go 1.17
 
 require (
+	github.com/lib/pq v1.10.4
 	google.golang.org/grpc v1.42.0
 	google.golang.org/protobuf v1.27.1
 )
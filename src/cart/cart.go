@@ -0,0 +1,131 @@
+package cart
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/checkout"
+)
+
+var (
+	ErrCartNotFound    = errors.New("cart not found")
+	ErrCartExpired     = errors.New("cart expired")
+	ErrDuplicatedCart  = errors.New("cart id already exists")
+	ErrVersionConflict = errors.New("cart was changed by someone else")
+	ErrInvalidQuantity = errors.New("quantity must be positive")
+	ErrItemNotFound    = errors.New("product is not in the cart")
+	ErrGiftNotForSale  = errors.New("gift products cannot be added to carts")
+	ErrEmptyCart       = errors.New("cart has no items")
+	ErrCartCheckedOut  = errors.New("cart is being checked out")
+	ErrNotCartOwner    = errors.New("cart belongs to another customer")
+)
+
+// Cart keeps what a customer is about to check out, it is priced again every time it is read
+type Cart struct {
+	Id          string
+	Items       []checkout.ProductRequest
+	CouponCodes []string
+	Currency    string
+	TimeZone    string
+	Storefront  string
+	// DestinationRegion decides the tax rates, like the checkout request one
+	DestinationRegion string
+	// CustomerId is who created the cart, empty for anonymous carts
+	CustomerId string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ExpiresAt  time.Time
+	// Version grows with every change, updates must say which version they were made on
+	Version int
+	// CheckedOut claims the cart for the checkout turning it into an order, it can't change anymore
+	CheckedOut bool
+}
+
+// Request is the checkout request the cart stands for
+func (c Cart) Request() checkout.CheckoutRequest {
+	return checkout.CheckoutRequest{
+		Products:          append([]checkout.ProductRequest(nil), c.Items...),
+		Currency:          c.Currency,
+		CouponCodes:       append([]string(nil), c.CouponCodes...),
+		TimeZone:          c.TimeZone,
+		Storefront:        c.Storefront,
+		DestinationRegion: c.DestinationRegion,
+		CustomerId:        c.CustomerId,
+	}
+}
+
+// OwnedBy tells whether customerId can use the cart, anonymous carts being anyone's
+func (c Cart) OwnedBy(customerId string) bool {
+	return c.CustomerId == "" || c.CustomerId == customerId
+}
+
+func (c Cart) IsExpired(t time.Time) bool {
+	return !t.Before(c.ExpiresAt)
+}
+
+// AddItem adds quantity units of productId, on top of the ones already in the cart
+func (c *Cart) AddItem(productId int, quantity int) error {
+	if quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+	for i, item := range c.Items {
+		if item.Id == productId {
+			c.Items[i].Quantity += quantity
+			return nil
+		}
+	}
+	c.Items = append(c.Items, checkout.ProductRequest{Id: productId, Quantity: quantity})
+	return nil
+}
+
+// SetItem replaces the quantity of productId, which must already be in the cart
+func (c *Cart) SetItem(productId int, quantity int) error {
+	if quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+	for i, item := range c.Items {
+		if item.Id == productId {
+			c.Items[i].Quantity = quantity
+			return nil
+		}
+	}
+	return ErrItemNotFound
+}
+
+func (c *Cart) RemoveItem(productId int) error {
+	for i, item := range c.Items {
+		if item.Id == productId {
+			c.Items = append(c.Items[:i:i], c.Items[i+1:]...)
+			return nil
+		}
+	}
+	return ErrItemNotFound
+}
+
+// AddCoupon keeps codes in the order they were added, adding the same code twice does nothing
+func (c *Cart) AddCoupon(code string) {
+	for _, applied := range c.CouponCodes {
+		if applied == code {
+			return
+		}
+	}
+	c.CouponCodes = append(c.CouponCodes, code)
+}
+
+func (c *Cart) RemoveCoupon(code string) {
+	for i, applied := range c.CouponCodes {
+		if applied == code {
+			c.CouponCodes = append(c.CouponCodes[:i:i], c.CouponCodes[i+1:]...)
+			return
+		}
+	}
+}
+
+// Store persists carts. Update replaces a cart only if the stored one is still at version,
+// failing with ErrVersionConflict otherwise
+type Store interface {
+	Save(c Cart) error
+	Find(id string) (Cart, error)
+	Update(c Cart, version int) error
+	Delete(id string) error
+}
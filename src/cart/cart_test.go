@@ -0,0 +1,192 @@
+package cart
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/checkout"
+	"github.com/gussf/backend-challenge/src/clock"
+	"github.com/gussf/backend-challenge/src/coupon"
+	"github.com/gussf/backend-challenge/src/discount"
+	"github.com/gussf/backend-challenge/src/order"
+	"github.com/gussf/backend-challenge/src/promotion"
+	"github.com/gussf/backend-challenge/src/repository"
+)
+
+type StubDiscountService struct{}
+
+func (s StubDiscountService) GetDiscountForProduct(req discount.Request) discount.Discount {
+	return discount.FromBasisPoints(1000)
+}
+
+func newTestCartService(cl clock.Clock, coupons coupon.CouponService) (CartService, *order.MemoryStore) {
+	repo := repository.InMemoryRepository{Products: []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 100, Is_gift: false},
+		{Id: 2, Title: "b", Description: "b", Amount: 200, Is_gift: false},
+		{Id: 3, Title: "c", Description: "c", Amount: 50, Is_gift: true},
+	}}
+	checkoutSvc := checkout.NewCheckoutService(repo, StubDiscountService{}, promotion.NewCalendar(),
+		checkout.WithCoupons(coupons), checkout.WithClock(cl))
+	orders := order.NewMemoryStore()
+	return NewCartService(repo, checkoutSvc, order.NewOrderService(checkoutSvc, orders), NewMemoryStore(cl),
+		WithClock(cl), WithTTL(time.Hour)), orders
+}
+
+func TestCartItems(t *testing.T) {
+	c := Cart{}
+
+	c.AddItem(1, 1)
+	c.AddItem(2, 1)
+	c.AddItem(1, 2)
+	if len(c.Items) != 2 || c.Items[0].Quantity != 3 {
+		t.Errorf("Incorrect items after adding: got=%+v", c.Items)
+	}
+
+	if err := c.SetItem(2, 5); err != nil || c.Items[1].Quantity != 5 {
+		t.Errorf("Incorrect items after setting: got=%+v, err=%v", c.Items, err)
+	}
+	if err := c.SetItem(1, 0); err != ErrInvalidQuantity {
+		t.Errorf("Incorrect error setting zero units: want=%v, got=%v", ErrInvalidQuantity, err)
+	}
+
+	if err := c.RemoveItem(1); err != nil || len(c.Items) != 1 || c.Items[0].Id != 2 {
+		t.Errorf("Incorrect items after removing: got=%+v, err=%v", c.Items, err)
+	}
+	if err := c.RemoveItem(1); err != ErrItemNotFound {
+		t.Errorf("Incorrect error removing missing item: want=%v, got=%v", ErrItemNotFound, err)
+	}
+}
+
+func TestCartServiceCheckout(t *testing.T) {
+	cl := &clock.MovableClock{T: time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)}
+	coupons := coupon.NewCouponService([]coupon.Coupon{{Code: "TENOFF", Type: coupon.FixedAmount, Amount: 10, UsageLimit: 1}})
+	cartSvc, orders := newTestCartService(cl, coupons)
+
+	c, _ := cartSvc.Create(Cart{})
+	cartSvc.AddItem(c.Id, 1, 2)
+	if _, err := cartSvc.AddItem(c.Id, 3, 1); err != ErrGiftNotForSale {
+		t.Errorf("Incorrect error adding a gift: want=%v, got=%v", ErrGiftNotForSale, err)
+	}
+	if _, err := cartSvc.AddItem(c.Id, 9, 1); err != repository.ErrProductNotFound {
+		t.Errorf("Incorrect error adding a missing product: want=%v, got=%v", repository.ErrProductNotFound, err)
+	}
+	c, _ = cartSvc.ApplyCoupon(c.Id, "tenoff")
+
+	// Pricing the cart doesn't use up the coupon
+	want := 20 + 10
+	got := cartSvc.Price(c).TotalDiscount
+	if want != got {
+		t.Errorf("Incorrect TotalDiscount: want=%d, got=%d", want, got)
+	}
+	if coupons.Used("TENOFF") != 0 {
+		t.Errorf("Coupon should not be redeemed by pricing the cart")
+	}
+
+	o, err := cartSvc.Checkout(c.Id)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if o.Checkout.TotalDiscount != want || coupons.Used("TENOFF") != 1 {
+		t.Errorf("Incorrect order: got=%+v", o)
+	}
+	if _, err := orders.Find(o.Id); err != nil {
+		t.Errorf("Order should be stored: %v", err)
+	}
+	if _, err := cartSvc.Find(c.Id); err != ErrCartNotFound {
+		t.Errorf("Incorrect error after checkout: want=%v, got=%v", ErrCartNotFound, err)
+	}
+
+	// A cart claimed by another checkout can't be confirmed again nor changed
+	c, _ = cartSvc.Create(Cart{})
+	c, _ = cartSvc.AddItem(c.Id, 1, 1)
+	claimed := c
+	claimed.CheckedOut, claimed.Version = true, c.Version+1
+	cartSvc.store.Update(claimed, c.Version)
+	if _, err := cartSvc.Checkout(c.Id); err != ErrCartCheckedOut {
+		t.Errorf("Incorrect error checking out a claimed cart: want=%v, got=%v", ErrCartCheckedOut, err)
+	}
+	if _, err := cartSvc.AddItem(c.Id, 2, 1); err != ErrCartCheckedOut {
+		t.Errorf("Incorrect error changing a claimed cart: want=%v, got=%v", ErrCartCheckedOut, err)
+	}
+	if orders, _ := orders.List(order.Filter{}); len(orders) != 1 {
+		t.Errorf("Incorrect orders: want=1, got=%d", len(orders))
+	}
+}
+
+func TestCartServiceExpiry(t *testing.T) {
+	cl := &clock.MovableClock{T: time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)}
+	cartSvc, _ := newTestCartService(cl, coupon.NewCouponService(nil))
+
+	c, _ := cartSvc.Create(Cart{})
+	cl.T = cl.T.Add(50 * time.Minute)
+	cartSvc.AddItem(c.Id, 1, 1)
+
+	// Changes extend the cart life
+	cl.T = cl.T.Add(50 * time.Minute)
+	if _, err := cartSvc.Find(c.Id); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cl.T = cl.T.Add(time.Hour)
+	if _, err := cartSvc.AddItem(c.Id, 1, 1); err != ErrCartExpired {
+		t.Errorf("Incorrect error on expired cart: want=%v, got=%v", ErrCartExpired, err)
+	}
+}
+
+func TestCartServiceAuthorize(t *testing.T) {
+	cl := clock.FixedClock{T: time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)}
+	cartSvc, _ := newTestCartService(cl, coupon.NewCouponService(nil))
+
+	owned, _ := cartSvc.Create(Cart{CustomerId: "jane"})
+	anonymous, _ := cartSvc.Create(Cart{})
+
+	tests := []struct {
+		name       string
+		id         string
+		customerId string
+		want       error
+	}{
+		{name: "Owner uses the cart", id: owned.Id, customerId: "jane"},
+		{name: "Another customer can't", id: owned.Id, customerId: "john", want: ErrNotCartOwner},
+		{name: "Anonymous caller can't", id: owned.Id, want: ErrNotCartOwner},
+		{name: "Anyone uses an anonymous cart", id: anonymous.Id, customerId: "john"},
+		{name: "Unknown cart", id: "nope", customerId: "jane", want: ErrCartNotFound},
+	}
+
+	for _, tt := range tests {
+		if err := cartSvc.Authorize(tt.id, tt.customerId); err != tt.want {
+			t.Errorf("%s: Incorrect error: want=%v, got=%v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestCartServiceConcurrentUpdates(t *testing.T) {
+	cartSvc, _ := newTestCartService(clock.SystemClock{}, coupon.NewCouponService(nil))
+	c, _ := cartSvc.Create(Cart{})
+
+	var wg sync.WaitGroup
+	added := make(chan bool, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cartSvc.AddItem(c.Id, 1, 1)
+			added <- err == nil
+		}()
+	}
+	wg.Wait()
+	close(added)
+
+	want := 0
+	for ok := range added {
+		if ok {
+			want++
+		}
+	}
+
+	c, _ = cartSvc.Find(c.Id)
+	if len(c.Items) != 1 || c.Items[0].Quantity != want {
+		t.Errorf("Incorrect items after concurrent updates: want=%d units, got=%+v", want, c.Items)
+	}
+}
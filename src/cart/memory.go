@@ -0,0 +1,91 @@
+package cart
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/checkout"
+	"github.com/gussf/backend-challenge/src/clock"
+)
+
+// MemoryStore keeps carts until the process exits or they expire, it is safe for concurrent use
+type MemoryStore struct {
+	mu        *sync.Mutex
+	carts     map[string]Cart
+	clock     clock.Clock
+	lastSweep time.Time
+}
+
+func NewMemoryStore(cl clock.Clock) *MemoryStore {
+	return &MemoryStore{mu: &sync.Mutex{}, carts: make(map[string]Cart), clock: cl}
+}
+
+func (m *MemoryStore) Save(c Cart) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep()
+	if _, ok := m.carts[c.Id]; ok {
+		return ErrDuplicatedCart
+	}
+	m.carts[c.Id] = copyCart(c)
+	return nil
+}
+
+func (m *MemoryStore) Find(id string) (Cart, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.carts[id]
+	if !ok {
+		return Cart{}, ErrCartNotFound
+	}
+	return copyCart(c), nil
+}
+
+func (m *MemoryStore) Update(c Cart, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.carts[c.Id]
+	if !ok {
+		return ErrCartNotFound
+	}
+	if stored.Version != version {
+		return ErrVersionConflict
+	}
+	m.carts[c.Id] = copyCart(c)
+	return nil
+}
+
+func (m *MemoryStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.carts[id]; !ok {
+		return ErrCartNotFound
+	}
+	delete(m.carts, id)
+	return nil
+}
+
+// sweep drops expired carts, at most once a minute
+func (m *MemoryStore) sweep() {
+	now := m.clock.Now()
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	for id, c := range m.carts {
+		if c.IsExpired(now) {
+			delete(m.carts, id)
+		}
+	}
+	m.lastSweep = now
+}
+
+// copyCart keeps callers from changing stored carts through their slices
+func copyCart(c Cart) Cart {
+	c.Items = append([]checkout.ProductRequest(nil), c.Items...)
+	c.CouponCodes = append([]string(nil), c.CouponCodes...)
+	return c
+}
@@ -0,0 +1,225 @@
+package cart
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/checkout"
+	"github.com/gussf/backend-challenge/src/clock"
+	"github.com/gussf/backend-challenge/src/coupon"
+	"github.com/gussf/backend-challenge/src/order"
+	"github.com/gussf/backend-challenge/src/repository"
+)
+
+// updateAttempts is how many times a change is tried again when another one got in first
+const updateAttempts = 5
+
+type CartService struct {
+	repo        repository.Repository
+	checkoutSvc checkout.CheckoutService
+	orderSvc    order.OrderService
+	store       Store
+	clock       clock.Clock
+	ttl         time.Duration
+}
+
+// Option configures optional behavior of the CartService
+type Option func(*CartService)
+
+func WithClock(cl clock.Clock) Option {
+	return func(s *CartService) {
+		s.clock = cl
+	}
+}
+
+// WithTTL sets how long carts live after their last change
+func WithTTL(ttl time.Duration) Option {
+	return func(s *CartService) {
+		s.ttl = ttl
+	}
+}
+
+func NewCartService(r repository.Repository, cs checkout.CheckoutService, orders order.OrderService, store Store, opts ...Option) CartService {
+	s := CartService{
+		repo:        r,
+		checkoutSvc: cs,
+		orderSvc:    orders,
+		store:       store,
+		clock:       clock.SystemClock{},
+		ttl:         24 * time.Hour,
+	}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
+
+// Create starts an empty cart with the currency, time zone, storefront, destination region
+// and customer of template, which work like the checkout request ones
+func (s CartService) Create(template Cart) (Cart, error) {
+	now := s.clock.Now()
+	c := Cart{
+		Id:                NewId(),
+		Currency:          template.Currency,
+		TimeZone:          template.TimeZone,
+		Storefront:        template.Storefront,
+		DestinationRegion: template.DestinationRegion,
+		CustomerId:        template.CustomerId,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		ExpiresAt:         now.Add(s.ttl),
+		Version:           1,
+	}
+	return c, s.store.Save(c)
+}
+
+// Find returns the cart unless it expired or is being checked out
+func (s CartService) Find(id string) (Cart, error) {
+	c, err := s.store.Find(id)
+	if err != nil {
+		return c, err
+	}
+	if c.IsExpired(s.clock.Now()) {
+		return c, ErrCartExpired
+	}
+	if c.CheckedOut {
+		return c, ErrCartCheckedOut
+	}
+	return c, nil
+}
+
+// Authorize tells whether customerId, empty for anonymous requests, can read and change the cart.
+// Carts created by a customer are only theirs, anonymous ones can be used by anyone with their id
+func (s CartService) Authorize(id string, customerId string) error {
+	c, err := s.store.Find(id)
+	if err != nil {
+		return err
+	}
+	if !c.OwnedBy(customerId) {
+		return ErrNotCartOwner
+	}
+	return nil
+}
+
+// Price quotes the cart through the checkout pipeline, without redeeming its coupons
+func (s CartService) Price(c Cart) *checkout.CheckoutResponse {
+	return s.checkoutSvc.Quote(c.Request())
+}
+
+func (s CartService) AddItem(id string, productId int, quantity int) (Cart, error) {
+	if err := s.CanBeAdded(productId); err != nil {
+		return Cart{}, err
+	}
+	return s.modify(id, func(c *Cart) error { return c.AddItem(productId, quantity) })
+}
+
+func (s CartService) SetItem(id string, productId int, quantity int) (Cart, error) {
+	return s.modify(id, func(c *Cart) error { return c.SetItem(productId, quantity) })
+}
+
+func (s CartService) RemoveItem(id string, productId int) (Cart, error) {
+	return s.modify(id, func(c *Cart) error { return c.RemoveItem(productId) })
+}
+
+// ApplyCoupon only stores the code, whether it can be used is decided every time the cart is priced
+func (s CartService) ApplyCoupon(id string, code string) (Cart, error) {
+	code = coupon.NormalizeCode(code)
+	return s.modify(id, func(c *Cart) error {
+		c.AddCoupon(code)
+		return nil
+	})
+}
+
+func (s CartService) RemoveCoupon(id string, code string) (Cart, error) {
+	code = coupon.NormalizeCode(code)
+	return s.modify(id, func(c *Cart) error {
+		c.RemoveCoupon(code)
+		return nil
+	})
+}
+
+func (s CartService) Delete(id string) error {
+	return s.store.Delete(id)
+}
+
+// Checkout confirms the cart into an order and deletes it. The cart is claimed first at the version
+// that was read, so that it is confirmed once and without changes made in between
+func (s CartService) Checkout(id string) (order.Order, error) {
+	c, err := s.Find(id)
+	if err != nil {
+		return order.Order{}, err
+	}
+	if len(c.Items) == 0 {
+		return order.Order{}, ErrEmptyCart
+	}
+
+	claimed := c
+	claimed.CheckedOut = true
+	claimed.Version++
+	if err := s.store.Update(claimed, c.Version); err != nil {
+		return order.Order{}, err
+	}
+
+	o, err := s.orderSvc.Confirm(c.Request())
+	if err != nil {
+		c.Version = claimed.Version + 1
+		if err := s.store.Update(c, claimed.Version); err != nil {
+			log.Printf("Failed to give back cart=%s after its checkout failed: %v", id, err)
+		}
+		return o, err
+	}
+
+	if err := s.store.Delete(id); err != nil {
+		log.Printf("Failed to delete cart=%s after it became order=%s: %v", id, o.Id, err)
+	}
+	log.Printf("Cart=%s checked out as order=%s", id, o.Id)
+	return o, nil
+}
+
+// CanBeAdded tells whether productId can be sold
+func (s CartService) CanBeAdded(productId int) error {
+	p, err := s.repo.Find(productId)
+	if err != nil {
+		return err
+	}
+	if checkout.CheckedOutProductIsAGift(p) {
+		return ErrGiftNotForSale
+	}
+	return nil
+}
+
+// modify applies change to the latest version of the cart, trying again when
+// another change was stored in between, and extends the cart life
+func (s CartService) modify(id string, change func(*Cart) error) (Cart, error) {
+	for attempt := 0; attempt < updateAttempts; attempt++ {
+		c, err := s.Find(id)
+		if err != nil {
+			return c, err
+		}
+
+		version := c.Version
+		if err := change(&c); err != nil {
+			return c, err
+		}
+		now := s.clock.Now()
+		c.UpdatedAt, c.ExpiresAt = now, now.Add(s.ttl)
+		c.Version++
+
+		err = s.store.Update(c, version)
+		if err != ErrVersionConflict {
+			return c, err
+		}
+	}
+	return Cart{}, ErrVersionConflict
+}
+
+// NewId returns a random id that can't be guessed from other carts
+func NewId() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic("failed to generate cart id: " + err.Error())
+	}
+	return "cart_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/cart"
+	"github.com/gussf/backend-challenge/src/checkout"
+	"github.com/gussf/backend-challenge/src/order"
+	"github.com/gussf/backend-challenge/src/repository"
+)
+
+type CartJSONRequest struct {
+	Currency   string `json:"currency"`
+	Time_zone  string `json:"time_zone"`
+	Storefront string `json:"storefront"`
+	Region     string `json:"destination_region"`
+}
+
+type CartItemJSONRequest struct {
+	Id       int `json:"id"`
+	Quantity int `json:"quantity"`
+}
+
+type CartCouponJSONRequest struct {
+	Code string `json:"code"`
+}
+
+type CartItemJSONResponse struct {
+	Id       int `json:"id"`
+	Quantity int `json:"quantity"`
+}
+
+// CartJSONResponse has the cart contents, and the cart priced as a checkout would be right now
+type CartJSONResponse struct {
+	Id           string                 `json:"id"`
+	Version      int                    `json:"version"`
+	Expires_at   string                 `json:"expires_at"`
+	Items        []CartItemJSONResponse `json:"items"`
+	Coupon_codes []string               `json:"coupon_codes,omitempty"`
+	CheckoutJSONResponse
+}
+
+// Carts creates carts on POST /carts
+func (router ECommerceRouter) Carts(w http.ResponseWriter, r *http.Request) {
+
+	if router.cartSvc == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Carts are not enabled"))
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte("Only POST method is allowed"))
+		return
+	}
+
+	var req CartJSONRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Failed to parse request: " + err.Error()))
+			return
+		}
+	}
+
+	if !router.checkoutSvc.SupportsCurrency(req.Currency) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Unsupported currency: " + req.Currency))
+		return
+	}
+
+	if _, err := router.checkoutSvc.EvaluationTime(checkout.CheckoutRequest{TimeZone: req.Time_zone, Storefront: req.Storefront}); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to resolve time zone: " + err.Error()))
+		return
+	}
+
+	customerId, err := router.CustomerId(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Failed to authenticate customer: " + err.Error()))
+		return
+	}
+
+	c, err := router.cartSvc.Create(cart.Cart{
+		Currency:          req.Currency,
+		TimeZone:          req.Time_zone,
+		Storefront:        req.Storefront,
+		DestinationRegion: req.Region,
+		CustomerId:        customerId,
+	})
+	if err != nil {
+		WriteCartError(w, err)
+		return
+	}
+
+	w.Header().Add("Location", "/carts/"+c.Id)
+	router.WriteCart(w, c, http.StatusCreated)
+}
+
+// Cart handles everything under /carts/{id}: reading and deleting the cart, its items, its coupons and its checkout.
+// Carts created with a customer token can only be used with a token of the same customer
+func (router ECommerceRouter) Cart(w http.ResponseWriter, r *http.Request) {
+
+	if router.cartSvc == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Carts are not enabled"))
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/carts/"), "/")
+	id := parts[0]
+
+	customerId, err := router.CustomerId(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Failed to authenticate customer: " + err.Error()))
+		return
+	}
+	if err := router.cartSvc.Authorize(id, customerId); err != nil {
+		WriteCartError(w, err)
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		c, err := router.cartSvc.Find(id)
+		router.WriteCartOrError(w, c, err)
+
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		if err := router.cartSvc.Delete(id); err != nil {
+			WriteCartError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case len(parts) == 2 && parts[1] == "items" && r.Method == http.MethodPost:
+		var req CartItemJSONRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Failed to parse request: " + err.Error()))
+			return
+		}
+		c, err := router.cartSvc.AddItem(id, req.Id, req.Quantity)
+		router.WriteCartOrError(w, c, err)
+
+	case len(parts) == 3 && parts[1] == "items" && (r.Method == http.MethodPut || r.Method == http.MethodDelete):
+		productId, err := strconv.Atoi(parts[2])
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Invalid product id: " + parts[2]))
+			return
+		}
+		if r.Method == http.MethodDelete {
+			c, err := router.cartSvc.RemoveItem(id, productId)
+			router.WriteCartOrError(w, c, err)
+			return
+		}
+		var req CartItemJSONRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Failed to parse request: " + err.Error()))
+			return
+		}
+		c, err := router.cartSvc.SetItem(id, productId, req.Quantity)
+		router.WriteCartOrError(w, c, err)
+
+	case len(parts) == 2 && parts[1] == "coupons" && r.Method == http.MethodPost:
+		var req CartCouponJSONRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Request must have a coupon code"))
+			return
+		}
+		c, err := router.cartSvc.ApplyCoupon(id, req.Code)
+		router.WriteCartOrError(w, c, err)
+
+	case len(parts) == 3 && parts[1] == "coupons" && r.Method == http.MethodDelete:
+		c, err := router.cartSvc.RemoveCoupon(id, parts[2])
+		router.WriteCartOrError(w, c, err)
+
+	case len(parts) == 2 && parts[1] == "checkout" && r.Method == http.MethodPost:
+		o, err := router.cartSvc.Checkout(id)
+		if err != nil {
+			WriteCartError(w, err)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.Header().Add("Location", "/orders/"+o.Id)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(ConvertOrderToOrderJSONResponse(o))
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Unknown cart operation: " + r.Method + " " + r.URL.Path))
+	}
+}
+
+func (router ECommerceRouter) WriteCartOrError(w http.ResponseWriter, c cart.Cart, err error) {
+	if err != nil {
+		WriteCartError(w, err)
+		return
+	}
+	router.WriteCart(w, c, http.StatusOK)
+}
+
+func (router ECommerceRouter) WriteCart(w http.ResponseWriter, c cart.Cart, status int) {
+	resp := CartJSONResponse{
+		Id:                   c.Id,
+		Version:              c.Version,
+		Expires_at:           c.ExpiresAt.Format(time.RFC3339),
+		Items:                make([]CartItemJSONResponse, 0, len(c.Items)),
+		Coupon_codes:         c.CouponCodes,
+		CheckoutJSONResponse: ConvertCheckoutResponseToCheckoutJSONResponse(router.cartSvc.Price(c)),
+	}
+	for _, item := range c.Items {
+		resp.Items = append(resp.Items, CartItemJSONResponse{Id: item.Id, Quantity: item.Quantity})
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func WriteCartError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, cart.ErrCartNotFound), errors.Is(err, cart.ErrItemNotFound), errors.Is(err, repository.ErrProductNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, cart.ErrCartExpired):
+		status = http.StatusGone
+	case errors.Is(err, cart.ErrNotCartOwner):
+		status = http.StatusForbidden
+	case errors.Is(err, cart.ErrVersionConflict), errors.Is(err, cart.ErrCartCheckedOut):
+		status = http.StatusConflict
+	case errors.Is(err, cart.ErrInvalidQuantity), errors.Is(err, cart.ErrGiftNotForSale),
+		errors.Is(err, cart.ErrEmptyCart), errors.Is(err, order.ErrEmptyOrder):
+		status = http.StatusBadRequest
+	default:
+		log.Printf("Unexpected cart error: %v", err)
+		w.WriteHeader(status)
+		w.Write([]byte("Something went wrong with the cart"))
+		return
+	}
+
+	w.WriteHeader(status)
+	w.Write([]byte(err.Error()))
+}
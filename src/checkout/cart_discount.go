@@ -0,0 +1,101 @@
+package checkout
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gussf/backend-challenge/src/discount"
+	"github.com/gussf/backend-challenge/src/money"
+)
+
+const AdjustmentCartDiscount = "cart_discount"
+
+// CartDiscount is the order level discount the discount service gave, Amount being spread
+// between the lines as adjustments so that refunds of a line give back its share
+type CartDiscount struct {
+	Id     string
+	Reason string
+	Amount int
+}
+
+// ApplyCartDiscount asks the discount service for an order level discount on the cart priced so far
+func (c CheckoutService) ApplyCartDiscount(r *CheckoutResponse, channel string) {
+	if c.cartDiscounts == nil || len(r.Products) == 0 {
+		return
+	}
+
+	d := c.cartDiscounts.GetCartDiscount(r.CartDiscountRequest(channel))
+	if d.Error != "" {
+		r.Explain(AdjustmentCartDiscount, d.Provider, "failed: "+d.Error, 0)
+		return
+	}
+	if d.IsZero() {
+		r.Explain(AdjustmentCartDiscount, d.Id, "none given", 0)
+		return
+	}
+
+	reference := d.Id
+	if reference == "" {
+		reference = d.Provider
+	}
+
+	amount := 0
+	if d.AmountCents > 0 {
+		converted, err := c.ConvertToCatalogCurrency(d.AmountCents, d.Currency)
+		if err != nil {
+			log.Printf("Ignoring cart discount=%s amount=%d%s: %v", reference, d.AmountCents, d.Currency, err)
+			r.Explain(AdjustmentCartDiscount, reference, "ignored: "+err.Error(), 0)
+			return
+		}
+		amount = converted
+	} else {
+		bp := d.BasisPoints
+		if bp > money.OneHundredPercent {
+			bp = money.OneHundredPercent
+		}
+		amount = r.Rounding.Mode.ApplyBasisPoints(r.EligibleRemaining(AdjustmentCartDiscount), bp)
+	}
+
+	before := r.TotalDiscount
+	ApportionAmount(r, amount, AdjustmentCartDiscount, reference, func(int) bool { return true })
+	r.RecalculateTotals()
+
+	r.CartDiscount = &CartDiscount{Id: d.Id, Reason: d.Reason, Amount: r.TotalDiscount - before}
+	log.Printf("Cart discount=%s of %d applied to checkout: %s", reference, r.CartDiscount.Amount, d.Reason)
+	r.Explain(AdjustmentCartDiscount, reference, fmt.Sprintf("applied, reason=%s", d.Reason), r.CartDiscount.Amount)
+}
+
+// CartDiscountRequest is the checkout priced so far, for the discount service
+func (r *CheckoutResponse) CartDiscountRequest(channel string) discount.CartRequest {
+	req := discount.CartRequest{
+		TotalAmount:   r.TotalAmount,
+		TotalDiscount: r.TotalDiscount,
+		Currency:      r.Currency,
+		Channel:       channel,
+	}
+	if r.Customer != nil {
+		req.CustomerId, req.CustomerSegment, req.CustomerTier = r.Customer.Id, r.Customer.Segment, r.Customer.Tier
+	}
+	for _, p := range r.Products {
+		req.Lines = append(req.Lines, discount.CartLine{
+			ProductId:  int32(p.Id),
+			Quantity:   p.Quantity,
+			UnitAmount: p.UnitAmount,
+			Amount:     p.TotalAmount,
+			Discount:   p.DiscountGiven,
+			IsGift:     p.IsGift,
+		})
+	}
+	return req
+}
+
+// CartDiscountShare is the part of the cart discount the line got
+func (p ProductResponse) CartDiscountShare() int {
+	share := 0
+	for _, a := range p.Adjustments {
+		if a.Kind == AdjustmentCartDiscount {
+			share += a.Amount
+		}
+	}
+	return share
+}
@@ -1,9 +1,42 @@
 package checkout
 
-import "github.com/gussf/backend-challenge/src/repository"
+import (
+	"time"
+
+	"github.com/gussf/backend-challenge/src/customer"
+	"github.com/gussf/backend-challenge/src/money"
+	"github.com/gussf/backend-challenge/src/promotion"
+	"github.com/gussf/backend-challenge/src/repository"
+	"github.com/gussf/backend-challenge/src/shipping"
+	"github.com/gussf/backend-challenge/src/tax"
+)
 
 type CheckoutRequest struct {
 	Products []ProductRequest
+	// Currency the customer wants to see prices in, defaults to the catalog currency
+	Currency    string
+	CouponCodes []string `json:"coupon_codes"`
+	// TimeZone of the customer (IANA name), or the Storefront the request came from,
+	// decide the zone promotions are evaluated in
+	TimeZone   string `json:"time_zone"`
+	Storefront string
+	// AsOf prices the cart as if it was checked out at that instant, without redeeming anything.
+	// Only admins can send it, the router is responsible for checking that
+	AsOf *time.Time `json:"as_of"`
+	// Quote asks for the prices to be signed so that they can be confirmed later, without redeeming anything yet
+	Quote bool `json:"quote"`
+	// Explain asks for a Trace of how every price was reached
+	Explain bool `json:"explain"`
+	// DestinationRegion decides the tax rates, such as "US-CA". Requests without one are not taxed
+	DestinationRegion string `json:"destination_region"`
+	// ShippingAddress lists the shipping options, ShippingOption selects one by id
+	// instead of the cheapest. Requests without an address are not shipped
+	ShippingAddress *shipping.Address `json:"shipping_address"`
+	ShippingOption  string            `json:"shipping_option"`
+	// CustomerId is the authenticated customer, the router replaces whatever the body says with it
+	CustomerId string `json:"customer_id"`
+	// Channel the request came through, such as "web" or "app"
+	Channel string `json:"channel"`
 }
 
 type ProductRequest struct {
@@ -15,49 +48,384 @@ type CheckoutResponse struct {
 	TotalAmount   int
 	TotalDiscount int
 	Products      []ProductResponse
+	Rounding      money.Rounding
+	// Currency of the catalog, which every amount above is in
+	Currency    string
+	Presentment *Presentment
+	Warnings    []Warning
+	// EvaluatedAt is the instant promotions were evaluated at, in the zone that was used
+	EvaluatedAt time.Time
+	WhatIf      bool
+	Trace       *Trace
+	// TotalTax is added to the discounted total in tax exclusive mode,
+	// in tax inclusive mode it is the part of the discounted total that is tax
+	TotalTax  int
+	TaxMode   tax.Mode
+	TaxRegion string
+	// ShippingOptions can deliver the checkout to ShippingAddress, Shipping is the
+	// selected one, which costs TotalShipping
+	ShippingAddress *shipping.Address
+	ShippingOptions []shipping.Option
+	Shipping        *shipping.Option
+	TotalShipping   int
+	// Customer is nil for anonymous checkouts
+	Customer *customer.Customer
+	// CartDiscount is nil unless the discount service gave an order level discount
+	CartDiscount *CartDiscount
+}
+
+// Warning explains why part of the request was ignored, without failing the whole checkout
+type Warning struct {
+	// Kind is what the warning is about, such as "coupon"
+	Kind string
+	// Reference identifies the item of the request, such as the coupon code
+	Reference string
+	// Reason is machine readable, Message is meant for people
+	Reason  string
+	Message string
+}
+
+const AdjustmentCoupon = "coupon"
+
+// Adjustment records a discount given to a line on top of the discount service one
+type Adjustment struct {
+	Kind      string
+	Reference string
+	Amount    int
+}
+
+// Presentment has the totals converted to the currency requested by the customer.
+// They are the sum of the converted lines, not a conversion of the catalog totals
+type Presentment struct {
+	TotalAmount   money.Money
+	TotalDiscount money.Money
+	TotalTax      money.Money
+	TotalShipping money.Money
+}
+
+type ProductPresentment struct {
+	UnitAmount    money.Money
+	TotalAmount   money.Money
+	DiscountGiven money.Money
+	TaxAmount     money.Money
 }
 
 type ProductResponse struct {
-	Id            int
-	Quantity      int
-	UnitAmount    int
-	TotalAmount   int
-	DiscountGiven int
-	IsGift        bool
+	Id       int
+	Quantity int
+	// Currency of every amount of the line, the catalog one
+	Currency            string
+	UnitAmount          int
+	TotalAmount         int
+	DiscountGiven       int
+	DiscountBasisPoints money.BasisPoints
+	FixedDiscount       int
+	Category            string
+	IsGift              bool
+	Presentment         *ProductPresentment
+	Adjustments         []Adjustment
+	TaxCategory         string
+	TaxBasisPoints      money.BasisPoints
+	TaxAmount           int
+	WeightGrams         int
+	Dimensions          shipping.Dimensions
+	// Flags of the product, see repository.ProductDAO. Exclusions is what they kept the line out of
+	NoDiscount        bool
+	NoCoupon          bool
+	NoGiftEligibility bool
+	Exclusions        []Exclusion
 }
 
 // AddProduct converts DAO and updates totals for safety, the caller might forget to call one or the other
-func (r *CheckoutResponse) AddProduct(pDAO repository.ProductDAO, quantity int, discount float32) {
+func (r *CheckoutResponse) AddProduct(pDAO repository.ProductDAO, quantity int, discount money.BasisPoints) {
 
-	p := ConvertProductDAOToProductResponse(pDAO, quantity, discount)
+	p := r.NewLine(pDAO, quantity, discount)
 	r.Products = append(r.Products, p)
 	r.UpdateCheckoutTotals(p)
 }
 
-func ConvertProductDAOToProductResponse(p repository.ProductDAO, quantity int, discount float32) ProductResponse {
+// AddProductWithFixedDiscount is AddProduct for absolute discounts, which are never above the product total
+func (r *CheckoutResponse) AddProductWithFixedDiscount(pDAO repository.ProductDAO, quantity int, discount int) {
+
+	p := r.NewLine(pDAO, quantity, 0)
+	if discount > p.TotalAmount {
+		discount = p.TotalAmount
+	}
+	p.FixedDiscount, p.DiscountGiven = discount, discount
+	r.Products = append(r.Products, p)
+	r.UpdateCheckoutTotals(p)
+}
+
+// Price is the unit price of the product, products without a currency being in the catalog one like the repository assumes
+func (r *CheckoutResponse) Price(pDAO repository.ProductDAO) money.Money {
+	price := pDAO.Price()
+	if price.Currency == "" {
+		price.Currency = r.Currency
+	}
+	return price
+}
+
+// NewLine converts the product into a line of the checkout
+func (r *CheckoutResponse) NewLine(pDAO repository.ProductDAO, quantity int, discount money.BasisPoints) ProductResponse {
+	p := ConvertProductDAOToProductResponse(pDAO, quantity, discount, r.Rounding.Mode)
+	p.Currency = r.Price(pDAO).Currency
+	return p
+}
+
+func ConvertProductDAOToProductResponse(p repository.ProductDAO, quantity int, discount money.BasisPoints, mode money.RoundingMode) ProductResponse {
+	price := p.Price()
 	return ProductResponse{
-		Id:            p.Id,
-		Quantity:      quantity,
-		UnitAmount:    p.Amount,
-		TotalAmount:   p.Amount * quantity,
-		DiscountGiven: int(float32(p.Amount*quantity) * discount),
-		IsGift:        p.Is_gift,
+		Id:                  p.Id,
+		Quantity:            quantity,
+		Currency:            price.Currency,
+		UnitAmount:          price.Amount,
+		TotalAmount:         price.Amount * quantity,
+		DiscountGiven:       mode.ApplyBasisPoints(price.Amount*quantity, discount),
+		DiscountBasisPoints: discount,
+		Category:            p.Category,
+		IsGift:              p.Is_gift,
+		TaxCategory:         p.Tax_category,
+		WeightGrams:         p.Weight_grams,
+		Dimensions:          shipping.Dimensions{LengthCm: p.Length_cm, WidthCm: p.Width_cm, HeightCm: p.Height_cm},
+		NoDiscount:          p.No_discount,
+		NoCoupon:            p.No_coupon,
+		NoGiftEligibility:   p.No_gift_eligibility,
 	}
 }
 
 func (r *CheckoutResponse) UpdateCheckoutTotals(p ProductResponse) {
 	r.TotalAmount += p.TotalAmount
 	r.TotalDiscount += p.DiscountGiven
+	r.TotalTax += p.TaxAmount
+}
+
+// CatalogTotals are the totals of the checkout as money of the catalog currency
+func (r *CheckoutResponse) CatalogTotals() Presentment {
+	return Presentment{
+		TotalAmount:   money.New(r.TotalAmount, r.Currency),
+		TotalDiscount: money.New(r.TotalDiscount, r.Currency),
+		TotalTax:      money.New(r.TotalTax, r.Currency),
+		TotalShipping: money.New(r.TotalShipping, r.Currency),
+	}
+}
+
+// RecalculateTotals rebuilds the totals from the lines, for when line amounts were changed in place
+func (r *CheckoutResponse) RecalculateTotals() {
+	r.TotalAmount, r.TotalDiscount, r.TotalTax = 0, 0, 0
+	for _, p := range r.Products {
+		r.UpdateCheckoutTotals(p)
+	}
+}
+
+// RoundAtOrderLevel rounds the exact discount of the whole order once, then spreads it
+// back into the lines so that they still add up to the order discount
+func (r *CheckoutResponse) RoundAtOrderLevel() {
+	numerators := make([]int64, len(r.Products))
+	for i, p := range r.Products {
+		numerators[i] = int64(p.FixedDiscount)*int64(money.OneHundredPercent) + int64(p.TotalAmount)*int64(p.DiscountBasisPoints)
+	}
+
+	discounts := r.Rounding.Mode.Allocate(numerators, int64(money.OneHundredPercent))
+	for i := range r.Products {
+		r.Products[i].DiscountGiven = int(discounts[i])
+	}
+	r.RecalculateTotals()
+}
+
+// LimitTotalDiscount scales every line discount down proportionally so that the
+// order discount is exactly max. Cents lost to truncation go to the first lines.
+// The adjustments of each line are scaled with it, so that the cart discount and
+// coupon shares still add up to what the lines were given
+func (r *CheckoutResponse) LimitTotalDiscount(max int) {
+	if max < 0 {
+		max = 0
+	}
+	if r.TotalDiscount <= max {
+		return
+	}
+
+	given := 0
+	original := make([]int, len(r.Products))
+	for i, p := range r.Products {
+		original[i] = p.DiscountGiven
+		r.Products[i].DiscountGiven = p.DiscountGiven * max / r.TotalDiscount
+		given += r.Products[i].DiscountGiven
+	}
+
+	for i := range r.Products {
+		if given == max {
+			break
+		}
+		if r.Products[i].DiscountGiven < original[i] {
+			r.Products[i].DiscountGiven++
+			given++
+		}
+	}
+
+	for i := range r.Products {
+		r.Products[i].scaleDiscountParts(original[i])
+	}
+	if r.CartDiscount != nil {
+		r.CartDiscount.Amount = 0
+		for _, p := range r.Products {
+			r.CartDiscount.Amount += p.CartDiscountShare()
+		}
+	}
+
+	r.TotalDiscount = given
+}
+
+// scaleDiscountParts splits DiscountGiven between the parts the line discount had when it was
+// original: the basis points one, the fixed one and each adjustment, in proportion to what they were
+func (p *ProductResponse) scaleDiscountParts(original int) {
+	if original == 0 || p.DiscountGiven == original {
+		return
+	}
+
+	adjusted := 0
+	for _, a := range p.Adjustments {
+		adjusted += a.Amount
+	}
+	numerators := []int64{
+		int64(original-p.FixedDiscount) * int64(p.DiscountGiven),
+		int64(p.FixedDiscount-adjusted) * int64(p.DiscountGiven),
+	}
+	for _, a := range p.Adjustments {
+		numerators = append(numerators, int64(a.Amount)*int64(p.DiscountGiven))
+	}
+
+	parts := money.Floor.Allocate(numerators, int64(original))
+	p.FixedDiscount = int(parts[1])
+	for j := range p.Adjustments {
+		p.Adjustments[j].Amount = int(parts[j+2])
+		p.FixedDiscount += p.Adjustments[j].Amount
+	}
 }
 
 // Gifts shouldn't cost anything
 func (r *CheckoutResponse) AddGiftProduct(pDAO repository.ProductDAO, quantity int) {
 
-	p := ConvertProductDAOToProductResponse(pDAO, quantity, 0.00)
+	p := r.NewLine(pDAO, quantity, 0)
 	p.TotalAmount, p.UnitAmount, p.DiscountGiven = 0, 0, 0
 	r.Products = append(r.Products, p)
 }
 
+// Cart summarizes the products that aren't gifts, for promotion conditions.
+// Products excluded from gift eligibility don't count towards them either
+func (r *CheckoutResponse) Cart() promotion.Cart {
+	var cart promotion.Cart
+	for _, p := range r.Products {
+		if p.IsGift || p.NoGiftEligibility {
+			continue
+		}
+		cart.Amount += p.Remaining()
+		cart.ProductIds = append(cart.ProductIds, p.Id)
+		if p.Category != "" {
+			cart.Categories = append(cart.Categories, p.Category)
+		}
+	}
+	return cart
+}
+
+// AmountDue is what the customer pays, tax and shipping included
+func (r *CheckoutResponse) AmountDue() int {
+	due := r.TotalAmount - r.TotalDiscount + r.TotalShipping
+	if r.TaxMode == tax.Exclusive {
+		due += r.TotalTax
+	}
+	return due
+}
+
+// Remaining is what is left to pay for the line
+func (p ProductResponse) UnitPrice() money.Money {
+	return money.New(p.UnitAmount, p.Currency)
+}
+
+func (p ProductResponse) Total() money.Money {
+	return money.New(p.TotalAmount, p.Currency)
+}
+
+func (p ProductResponse) Discount() money.Money {
+	return money.New(p.DiscountGiven, p.Currency)
+}
+
+func (p ProductResponse) Tax() money.Money {
+	return money.New(p.TaxAmount, p.Currency)
+}
+
+func (p ProductResponse) Remaining() int {
+	return p.TotalAmount - p.DiscountGiven
+}
+
+// AddAdjustment discounts up to amount from the line, never going below zero, and returns what was discounted.
+// Lines excluded from kind get nothing. Checkout totals are not updated, RecalculateTotals must be called once every adjustment is made
+func (p *ProductResponse) AddAdjustment(kind string, reference string, amount int) int {
+	if p.Exclude(kind, reference) {
+		return 0
+	}
+	if amount > p.Remaining() {
+		amount = p.Remaining()
+	}
+	p.FixedDiscount += amount
+	p.DiscountGiven += amount
+	p.Adjustments = append(p.Adjustments, Adjustment{Kind: kind, Reference: reference, Amount: amount})
+	return amount
+}
+
+func (r *CheckoutResponse) AddWarning(kind string, reference string, reason string, message string) {
+	r.Warnings = append(r.Warnings, Warning{Kind: kind, Reference: reference, Reason: reason, Message: message})
+}
+
+// ConvertToPresentment converts each line unit price and discount to currency, line totals
+// are derived from the converted unit price so that every amount is consistent with the others
+func (r *CheckoutResponse) ConvertToPresentment(currency string, rates money.RatesTable) error {
+	currency = money.NormalizeCurrency(currency)
+	presentment := &Presentment{TotalAmount: money.New(0, currency), TotalDiscount: money.New(0, currency), TotalTax: money.New(0, currency)}
+	products := make([]ProductPresentment, len(r.Products))
+
+	for i, p := range r.Products {
+		unit, err := rates.Convert(p.UnitPrice(), currency, r.Rounding.Mode)
+		if err != nil {
+			return err
+		}
+		discount, err := rates.Convert(p.Discount(), currency, r.Rounding.Mode)
+		if err != nil {
+			return err
+		}
+
+		tax, err := rates.Convert(p.Tax(), currency, r.Rounding.Mode)
+		if err != nil {
+			return err
+		}
+
+		total := money.New(unit.Amount*p.Quantity, currency)
+		if discount.Amount > total.Amount {
+			discount.Amount = total.Amount
+		}
+
+		products[i] = ProductPresentment{UnitAmount: unit, TotalAmount: total, DiscountGiven: discount, TaxAmount: tax}
+		presentment.TotalAmount.Amount += total.Amount
+		presentment.TotalDiscount.Amount += discount.Amount
+		presentment.TotalTax.Amount += tax.Amount
+	}
+
+	shippingAmount, err := rates.Convert(r.CatalogTotals().TotalShipping, currency, r.Rounding.Mode)
+	if err != nil {
+		return err
+	}
+	presentment.TotalShipping = shippingAmount
+
+	for i := range r.Products {
+		r.Products[i].Presentment = &products[i]
+	}
+	r.Presentment = presentment
+	return nil
+}
+
+func (c CheckoutRequest) IsWhatIf() bool {
+	return c.AsOf != nil
+}
+
 func (c CheckoutRequest) HasNoProducts() bool {
 	return len(c.Products) == 0
 }
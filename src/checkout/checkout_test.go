@@ -3,6 +3,7 @@ package checkout
 import (
 	"testing"
 
+	"github.com/gussf/backend-challenge/src/money"
 	"github.com/gussf/backend-challenge/src/repository"
 )
 
@@ -29,9 +30,9 @@ func TestAddProduct(t *testing.T) {
 	response := CheckoutResponse{}
 	pDAO := repository.ProductDAO{Id: 1, Amount: 200}
 	quantity := 1
-	var discount float32 = 0.10
+	var discount money.BasisPoints = 1000
 
-	response.AddProduct(pDAO, quantity, float32(discount))
+	response.AddProduct(pDAO, quantity, discount)
 	want := 1
 	got := len(response.Products)
 
@@ -47,7 +48,7 @@ func TestAddProduct(t *testing.T) {
 		t.Errorf("Incorrect Response TotalAmount: want=%d, got=%d", want, got)
 	}
 
-	want = int(float32(pDAO.Amount*quantity) * discount)
+	want = pDAO.Amount * quantity * int(discount) / int(money.OneHundredPercent)
 	got = response.TotalDiscount
 	if want != got {
 		t.Errorf("Incorrect Response TotalDiscount: want=%d, got=%d", want, got)
@@ -56,12 +57,12 @@ func TestAddProduct(t *testing.T) {
 
 func TestConvertProductDAOToProductResponse(t *testing.T) {
 	p := repository.ProductDAO{
-		Id: 1, Title: "a", Description: "a", Amount: 200, Is_gift: false,
+		Id: 1, Title: "a", Description: "a", Amount: 200, Currency: "usd", Is_gift: false,
 	}
 	quantity := 2
-	var discount float32 = 0.05
+	var discount money.BasisPoints = 500
 
-	pResp := ConvertProductDAOToProductResponse(p, quantity, float32(discount))
+	pResp := ConvertProductDAOToProductResponse(p, quantity, discount, money.HalfUp)
 
 	want := p.Id
 	got := pResp.Id
@@ -69,6 +70,10 @@ func TestConvertProductDAOToProductResponse(t *testing.T) {
 		t.Errorf("Incorrect Product ID: want=%d, got=%d", want, got)
 	}
 
+	if pResp.UnitPrice() != p.Price() || pResp.Currency != "USD" {
+		t.Errorf("Incorrect Product UnitPrice: want=%s, got=%s", p.Price(), pResp.UnitPrice())
+	}
+
 	want = p.Amount
 	got = pResp.UnitAmount
 	if want != got {
@@ -87,7 +92,7 @@ func TestConvertProductDAOToProductResponse(t *testing.T) {
 		t.Errorf("Incorrect Product TotalAmount: want=%d, got=%d", want, got)
 	}
 
-	want = int(float32(p.Amount*quantity) * discount)
+	want = p.Amount * quantity * int(discount) / int(money.OneHundredPercent)
 	got = pResp.DiscountGiven
 	if want != got {
 		t.Errorf("Incorrect Product DiscountGiven: want=%d, got=%d", want, got)
@@ -143,3 +148,167 @@ func TestAddGiftProduct(t *testing.T) {
 		t.Errorf("Incorrect Gift Product IsGift: want=%t, got=%t", want, gotB)
 	}
 }
+
+func TestLimitTotalDiscount(t *testing.T) {
+
+	resp := &CheckoutResponse{}
+	resp.AddProduct(repository.ProductDAO{Id: 1, Amount: 333}, 1, 5000)
+	resp.AddProduct(repository.ProductDAO{Id: 2, Amount: 333}, 1, 5000)
+	resp.AddProduct(repository.ProductDAO{Id: 3, Amount: 333}, 1, 0)
+
+	want := 100
+	resp.LimitTotalDiscount(want)
+
+	if want != resp.TotalDiscount {
+		t.Errorf("Incorrect TotalDiscount: want=%d, got=%d", want, resp.TotalDiscount)
+	}
+
+	sum := 0
+	for _, p := range resp.Products {
+		sum += p.DiscountGiven
+	}
+	if want != sum {
+		t.Errorf("Incorrect sum of line discounts: want=%d, got=%d", want, sum)
+	}
+
+	if resp.Products[2].DiscountGiven != 0 {
+		t.Errorf("Incorrect DiscountGiven for line without discount: want=0, got=%d", resp.Products[2].DiscountGiven)
+	}
+}
+
+func TestLimitTotalDiscountAdjustments(t *testing.T) {
+
+	resp := &CheckoutResponse{}
+	resp.AddProduct(repository.ProductDAO{Id: 1, Amount: 1000}, 1, 0)
+	resp.AddProduct(repository.ProductDAO{Id: 2, Amount: 3000}, 1, 0)
+	ApportionAmount(resp, 2000, AdjustmentCartDiscount, "summer", func(int) bool { return true })
+	resp.RecalculateTotals()
+	resp.CartDiscount = &CartDiscount{Id: "summer", Amount: 2000}
+
+	resp.LimitTotalDiscount(400)
+
+	given, shares := 0, 0
+	for _, p := range resp.Products {
+		given += p.DiscountGiven
+		shares += p.CartDiscountShare()
+		if p.CartDiscountShare() > p.DiscountGiven || p.FixedDiscount > p.DiscountGiven {
+			t.Errorf("Incorrect parts of product %d: discount=%d, fixed=%d, cart share=%d", p.Id, p.DiscountGiven, p.FixedDiscount, p.CartDiscountShare())
+		}
+	}
+	if given != 400 {
+		t.Errorf("Incorrect sum of line discounts: want=400, got=%d", given)
+	}
+	if shares != resp.CartDiscount.Amount {
+		t.Errorf("Incorrect cart discount: want=%d, got=%d", shares, resp.CartDiscount.Amount)
+	}
+	if want := []int{100, 300}; resp.Products[0].CartDiscountShare() != want[0] || resp.Products[1].CartDiscountShare() != want[1] {
+		t.Errorf("Incorrect cart discount shares: want=%v, got=[%d %d]", want, resp.Products[0].CartDiscountShare(), resp.Products[1].CartDiscountShare())
+	}
+
+	// The basis points part of the line is scaled along with its coupon
+	resp = &CheckoutResponse{}
+	resp.AddProduct(repository.ProductDAO{Id: 1, Amount: 1000}, 1, 5000)
+	resp.Products[0].AddAdjustment(AdjustmentCoupon, "TWO", 200)
+	resp.RecalculateTotals()
+
+	resp.LimitTotalDiscount(350)
+
+	if p := resp.Products[0]; p.DiscountGiven != 350 || p.FixedDiscount != 100 || p.Adjustments[0].Amount != 100 {
+		t.Errorf("Incorrect scaled line: want discount=350 fixed=100 coupon=100, got discount=%d fixed=%d coupon=%d", p.DiscountGiven, p.FixedDiscount, p.Adjustments[0].Amount)
+	}
+}
+
+func TestDiscountRounding(t *testing.T) {
+
+	// 3 lines of 5 cents with a 10% discount, 0.5 cent each
+	tests := []struct {
+		name     string
+		rounding money.Rounding
+		want     int
+	}{
+		{name: "Half up at line level", rounding: money.Rounding{Mode: money.HalfUp, Level: money.LineLevel}, want: 3},
+		{name: "Floor at line level", rounding: money.Rounding{Mode: money.Floor, Level: money.LineLevel}, want: 0},
+		{name: "Half up at order level", rounding: money.Rounding{Mode: money.HalfUp, Level: money.OrderLevel}, want: 2},
+		{name: "Half even at order level", rounding: money.Rounding{Mode: money.HalfEven, Level: money.OrderLevel}, want: 2},
+		{name: "Floor at order level", rounding: money.Rounding{Mode: money.Floor, Level: money.OrderLevel}, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &CheckoutResponse{Rounding: tt.rounding}
+			for id := 1; id <= 3; id++ {
+				resp.AddProduct(repository.ProductDAO{Id: id, Amount: 5}, 1, 1000)
+			}
+			if tt.rounding.Level == money.OrderLevel {
+				resp.RoundAtOrderLevel()
+			}
+
+			if tt.want != resp.TotalDiscount {
+				t.Errorf("%s: Incorrect TotalDiscount: want=%d, got=%d", tt.name, tt.want, resp.TotalDiscount)
+			}
+
+			sum := 0
+			for _, p := range resp.Products {
+				sum += p.DiscountGiven
+			}
+			if resp.TotalDiscount != sum {
+				t.Errorf("%s: Totals do not reconcile with lines: total=%d, sum=%d", tt.name, resp.TotalDiscount, sum)
+			}
+		})
+	}
+}
+
+func TestAddProductWithFixedDiscount(t *testing.T) {
+
+	resp := &CheckoutResponse{}
+	resp.AddProductWithFixedDiscount(repository.ProductDAO{Id: 1, Amount: 100}, 2, 50)
+	resp.AddProductWithFixedDiscount(repository.ProductDAO{Id: 2, Amount: 10}, 1, 50)
+
+	want := 60
+	if want != resp.TotalDiscount {
+		t.Errorf("Incorrect TotalDiscount: want=%d, got=%d", want, resp.TotalDiscount)
+	}
+
+	// A fixed discount can't be above the product total
+	want = 10
+	got := resp.Products[1].DiscountGiven
+	if want != got {
+		t.Errorf("Incorrect capped DiscountGiven: want=%d, got=%d", want, got)
+	}
+}
+
+func TestConvertToPresentment(t *testing.T) {
+
+	rates := money.NewRatesTable("USD")
+	rates.SetRate("EUR", "0.5")
+
+	resp := &CheckoutResponse{Currency: "USD"}
+	resp.AddProduct(repository.ProductDAO{Id: 1, Amount: 101}, 3, 1000)
+
+	err := resp.ConvertToPresentment("eur", rates)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Unit 101 USD -> 51 EUR (half up), line total is derived from the converted unit price
+	want := money.New(153, "EUR")
+	got := resp.Products[0].Presentment.TotalAmount
+	if want != got {
+		t.Errorf("Incorrect Product presentment TotalAmount: want=%s, got=%s", want, got)
+	}
+
+	if resp.Presentment.TotalAmount != got {
+		t.Errorf("Presentment totals do not reconcile with lines: total=%s, line=%s", resp.Presentment.TotalAmount, got)
+	}
+
+	// Catalog amounts are kept
+	if resp.TotalAmount != 303 {
+		t.Errorf("Incorrect TotalAmount: want=%d, got=%d", 303, resp.TotalAmount)
+	}
+	if want := money.New(101, "USD"); resp.Products[0].UnitPrice() != want {
+		t.Errorf("Incorrect catalog unit price: want=%s, got=%s", want, resp.Products[0].UnitPrice())
+	}
+	if want := money.New(303, "USD"); resp.CatalogTotals().TotalAmount != want {
+		t.Errorf("Incorrect catalog total: want=%s, got=%s", want, resp.CatalogTotals().TotalAmount)
+	}
+}
@@ -0,0 +1,147 @@
+package checkout
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/coupon"
+	"github.com/gussf/backend-challenge/src/money"
+)
+
+// ApplyCoupons applies the codes in the order they were sent. Codes that can't be used
+// are reported as warnings, a coupon is only redeemed once it is certain to be applied,
+// and only if redeem is true
+func (c CheckoutService) ApplyCoupons(codes []string, r *CheckoutResponse, now time.Time, redeem bool) {
+	var applied []coupon.Coupon
+	orderAmount := r.TotalAmount - r.TotalDiscount
+
+	for _, code := range codes {
+		cp, err := c.coupons.Find(code, now, orderAmount)
+		if err == nil {
+			err = CanBeCombined(cp, applied)
+		}
+		if err == nil {
+			err = c.HasEligibleProducts(cp, r)
+		}
+		if err == nil && redeem {
+			err = c.coupons.Redeem(cp)
+		}
+		if err != nil {
+			log.Printf("Coupon=%s not applied: %v", code, err)
+			r.AddWarning(AdjustmentCoupon, code, coupon.Reason(err), err.Error())
+			r.Explain(AdjustmentCoupon, code, "rejected: "+coupon.Reason(err), 0)
+			continue
+		}
+
+		before := r.TotalDiscount
+		c.ApplyCoupon(cp, r)
+		r.RecalculateTotals()
+		r.Explain(AdjustmentCoupon, cp.Code, fmt.Sprintf("applied, type=%s redeemed=%t", cp.Type, redeem), r.TotalDiscount-before)
+		applied = append(applied, cp)
+		log.Printf("Coupon=%s applied to checkout", cp.Code)
+	}
+
+	r.RecalculateTotals()
+}
+
+// CanBeCombined checks stacking rules: a coupon that isn't stackable must be the only one in the checkout
+func CanBeCombined(cp coupon.Coupon, applied []coupon.Coupon) error {
+	for _, a := range applied {
+		if a.Code == cp.Code {
+			return coupon.ErrDuplicatedCoupon
+		}
+		if !a.Stackable || !cp.Stackable {
+			return coupon.ErrNotStackable
+		}
+	}
+	return nil
+}
+
+// HasEligibleProducts checks that the coupon would change something, a free gift still needs something to be bought.
+// Products excluded from coupons are not eligible
+func (c CheckoutService) HasEligibleProducts(cp coupon.Coupon, r *CheckoutResponse) error {
+	for _, p := range r.Products {
+		if p.IsGift || !cp.AppliesTo(p.Id) || p.ExcludedFrom(AdjustmentCoupon) != "" {
+			continue
+		}
+		if cp.Type == coupon.FreeGift {
+			if _, err := c.repo.Find(cp.GiftProductId); err != nil {
+				return coupon.ErrGiftUnavailable
+			}
+			return nil
+		}
+		if p.Remaining() > 0 {
+			return nil
+		}
+	}
+	return coupon.ErrNoEligibleProducts
+}
+
+func (c CheckoutService) ApplyCoupon(cp coupon.Coupon, r *CheckoutResponse) {
+	switch cp.Type {
+	case coupon.Percentage:
+		for i, p := range r.Products {
+			if !p.IsGift && cp.AppliesTo(p.Id) {
+				amount := r.Rounding.Mode.ApplyBasisPoints(p.Remaining(), cp.BasisPoints)
+				r.Products[i].AddAdjustment(AdjustmentCoupon, cp.Code, amount)
+			}
+		}
+
+	case coupon.FixedAmount:
+		ApportionAmount(r, cp.Amount, AdjustmentCoupon, cp.Code, cp.AppliesTo)
+
+	case coupon.FreeGift:
+		c.AddGift(r, cp.GiftProductId, AdjustmentCoupon, cp.Code)
+	}
+}
+
+// ApportionAmount spreads an absolute discount between the eligible lines, proportionally
+// to what is left to pay for each of them. Lines always add up to the discounted amount
+func ApportionAmount(r *CheckoutResponse, amount int, kind string, reference string, eligible func(productId int) bool) {
+	var lines []int
+	var numerators []int64
+	remaining := 0
+
+	for i, p := range r.Products {
+		if !p.IsGift && eligible(p.Id) && p.Remaining() > 0 && !r.Products[i].Exclude(kind, reference) {
+			lines = append(lines, i)
+			remaining += p.Remaining()
+		}
+	}
+	if remaining == 0 {
+		return
+	}
+	if amount > remaining {
+		amount = remaining
+	}
+
+	for _, i := range lines {
+		numerators = append(numerators, int64(amount)*int64(r.Products[i].Remaining()))
+	}
+
+	parts := money.Floor.Allocate(numerators, int64(remaining))
+	for j, i := range lines {
+		r.Products[i].AddAdjustment(kind, reference, int(parts[j]))
+	}
+}
+
+// RedeemCoupons redeems every code or none of them
+func (c CheckoutService) RedeemCoupons(codes []string) error {
+	for i, code := range codes {
+		if err := c.coupons.RedeemCode(code); err != nil {
+			for _, redeemed := range codes[:i] {
+				c.coupons.Release(redeemed)
+			}
+			return fmt.Errorf("coupon %s: %w", code, err)
+		}
+	}
+	return nil
+}
+
+// ReleaseCoupons undoes RedeemCoupons
+func (c CheckoutService) ReleaseCoupons(codes []string) {
+	for _, code := range codes {
+		c.coupons.Release(code)
+	}
+}
@@ -0,0 +1,89 @@
+package checkout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/coupon"
+	"github.com/gussf/backend-challenge/src/promotion"
+	"github.com/gussf/backend-challenge/src/repository"
+)
+
+func TestApplyCoupons(t *testing.T) {
+
+	products := []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 1000, Is_gift: false},
+		{Id: 2, Title: "b", Description: "b", Amount: 3000, Is_gift: false},
+		{Id: 3, Title: "c", Description: "c", Amount: 500, Is_gift: true},
+	}
+	now := time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)
+
+	coupons := []coupon.Coupon{
+		{Code: "TEN", Type: coupon.Percentage, BasisPoints: 1000, Stackable: true},
+		{Code: "FIXED", Type: coupon.FixedAmount, Amount: 100, Stackable: true},
+		{Code: "ONLY2", Type: coupon.Percentage, BasisPoints: 5000, ProductIds: []int{2}, Stackable: true},
+		{Code: "ALONE", Type: coupon.Percentage, BasisPoints: 5000, Stackable: false},
+		{Code: "GIFT", Type: coupon.FreeGift, GiftProductId: 3, Stackable: true},
+		{Code: "EXPIRED", Type: coupon.Percentage, BasisPoints: 1000, ValidUntil: now.Add(-time.Hour), Stackable: true},
+		{Code: "BIGORDER", Type: coupon.Percentage, BasisPoints: 1000, MinOrderAmount: 100000, Stackable: true},
+		{Code: "ONCE", Type: coupon.FixedAmount, Amount: 100, UsageLimit: 1, Stackable: true},
+	}
+
+	tests := []struct {
+		name          string
+		codes         []string
+		wantDiscount  int
+		wantLength    int
+		wantWarnings  []string
+		wantRedeemed  string
+		redeemedTimes int
+	}{
+		{name: "Percentage coupon on every line", codes: []string{"ten"}, wantDiscount: 400, wantLength: 2},
+		{name: "Fixed coupon is apportioned", codes: []string{"FIXED"}, wantDiscount: 100, wantLength: 2},
+		{name: "Product scoped coupon", codes: []string{"ONLY2"}, wantDiscount: 1500, wantLength: 2},
+		{name: "Stacked coupons apply in order", codes: []string{"TEN", "FIXED"}, wantDiscount: 500, wantLength: 2},
+		{name: "Free gift coupon", codes: []string{"GIFT"}, wantDiscount: 0, wantLength: 3},
+		{name: "Not stackable after another coupon", codes: []string{"TEN", "ALONE"}, wantDiscount: 400, wantLength: 2, wantWarnings: []string{"coupon_not_stackable"}},
+		{name: "Unknown, expired and below minimum", codes: []string{"NOPE", "EXPIRED", "BIGORDER"}, wantDiscount: 0, wantLength: 2,
+			wantWarnings: []string{"coupon_not_found", "coupon_expired", "coupon_min_order_amount"}},
+		{name: "Same coupon twice", codes: []string{"TEN", "TEN"}, wantDiscount: 400, wantLength: 2, wantWarnings: []string{"coupon_duplicated"}},
+		{name: "Usage limit", codes: []string{"ONCE"}, wantDiscount: 100, wantLength: 2, wantRedeemed: "ONCE", redeemedTimes: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			couponSvc := coupon.NewCouponService(coupons)
+			checkoutSvc := NewCheckoutService(repository.InMemoryRepository{Products: products}, nil, promotion.NewCalendar(), WithCoupons(couponSvc))
+
+			resp := &CheckoutResponse{}
+			resp.AddProduct(products[0], 1, 0)
+			resp.AddProduct(products[1], 1, 0)
+
+			checkoutSvc.ApplyCoupons(tt.codes, resp, now, true)
+
+			if tt.wantDiscount != resp.TotalDiscount {
+				t.Errorf("%s: Incorrect TotalDiscount: want=%d, got=%d", tt.name, tt.wantDiscount, resp.TotalDiscount)
+			}
+
+			if tt.wantLength != len(resp.Products) {
+				t.Errorf("%s: Incorrect Products length: want=%d, got=%d", tt.name, tt.wantLength, len(resp.Products))
+			}
+
+			if len(tt.wantWarnings) != len(resp.Warnings) {
+				t.Fatalf("%s: Incorrect Warnings: want=%v, got=%v", tt.name, tt.wantWarnings, resp.Warnings)
+			}
+			for i, w := range resp.Warnings {
+				if tt.wantWarnings[i] != w.Reason {
+					t.Errorf("%s: Incorrect Warning reason: want=%s, got=%s", tt.name, tt.wantWarnings[i], w.Reason)
+				}
+			}
+
+			if tt.wantRedeemed != "" {
+				checkoutSvc.ApplyCoupons(tt.codes, resp, now, true)
+				if got := couponSvc.Used(tt.wantRedeemed); tt.redeemedTimes != got {
+					t.Errorf("%s: Incorrect usage count: want=%d, got=%d", tt.name, tt.redeemedTimes, got)
+				}
+			}
+		})
+	}
+}
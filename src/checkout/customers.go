@@ -0,0 +1,83 @@
+package checkout
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gussf/backend-challenge/src/customer"
+	"github.com/gussf/backend-challenge/src/discount"
+	"github.com/gussf/backend-challenge/src/repository"
+)
+
+const (
+	AdjustmentPriceList = "price_list"
+	WarningCustomer     = "customer"
+)
+
+// FindCustomer identifies who is checking out, customers that can't be found are priced as anonymous
+func (c CheckoutService) FindCustomer(r *CheckoutResponse, customerId string) {
+	if customerId == "" {
+		return
+	}
+	if c.customers == nil {
+		r.AddWarning(WarningCustomer, customerId, "unknown_customer", customer.ErrCustomerNotFound.Error())
+		return
+	}
+
+	cust, err := c.customers.Find(customerId)
+	if err != nil {
+		log.Printf("Customer=%s not found, pricing as anonymous: %v", customerId, err)
+		r.AddWarning(WarningCustomer, customerId, "unknown_customer", err.Error())
+		return
+	}
+	r.Customer = &cust
+	r.Explain("customer", cust.Id, fmt.Sprintf("segment=%s tier=%s", cust.Segment, cust.Tier), 0)
+}
+
+// DiscountRequest asks for the discount of p with the context of the checkout it is in,
+// cartAmount being the cart before any discount
+func (r *CheckoutResponse) DiscountRequest(p repository.ProductDAO, quantity int, cartAmount int, channel string) discount.Request {
+	price := r.Price(p)
+	req := discount.Request{
+		ProductId:  int32(p.Id),
+		Quantity:   quantity,
+		UnitAmount: price.Amount,
+		CartAmount: cartAmount,
+		Currency:   price.Currency,
+		Channel:    channel,
+	}
+	if r.Customer != nil {
+		req.CustomerId, req.CustomerSegment, req.CustomerTier = r.Customer.Id, r.Customer.Segment, r.Customer.Tier
+	}
+	return req
+}
+
+// ApplyPriceLists gives the customer the prices of every list of their segment and tier, in the
+// order the lists were defined, as adjustments on what is left to pay
+func (c CheckoutService) ApplyPriceLists(r *CheckoutResponse) {
+	if r.Customer == nil {
+		return
+	}
+
+	for _, l := range c.priceLists {
+		if !l.AppliesTo(*r.Customer) {
+			continue
+		}
+		given := 0
+		for i, p := range r.Products {
+			if p.IsGift {
+				continue
+			}
+			if price, ok := l.Prices[p.Id]; ok && price < p.UnitAmount {
+				given += r.Products[i].AddAdjustment(AdjustmentPriceList, l.Id, (p.UnitAmount-price)*p.Quantity)
+			}
+			if l.BasisPoints > 0 {
+				amount := r.Rounding.Mode.ApplyBasisPoints(r.Products[i].Remaining(), l.BasisPoints)
+				given += r.Products[i].AddAdjustment(AdjustmentPriceList, l.Id, amount)
+			}
+		}
+		log.Printf("Price list=%s discounted %d for customer=%s", l.Id, given, r.Customer.Id)
+		r.Explain(AdjustmentPriceList, l.Id, "applied to customer="+r.Customer.Id, given)
+	}
+	r.RecalculateTotals()
+}
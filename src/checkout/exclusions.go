@@ -0,0 +1,59 @@
+package checkout
+
+// Kinds lines can be excluded from besides the adjustment ones
+const (
+	ExclusionDiscountService   = "discount"
+	ExclusionGiftEligibility   = "gift_eligibility"
+	ExclusionNoDiscount        = "no_discount"
+	ExclusionNoCoupon          = "no_coupon"
+	ExclusionNoGiftEligibility = "no_gift_eligibility"
+)
+
+// Exclusion is a discount or promotion a line was kept out of, Kind being an adjustment kind or
+// one of the exclusion ones, and Reason the flag of the product that excluded it
+type Exclusion struct {
+	Kind      string
+	Reference string
+	Reason    string
+}
+
+// ExcludedFrom returns the flag that keeps the line out of kind, "" when the line isn't excluded
+func (p ProductResponse) ExcludedFrom(kind string) string {
+	switch {
+	case kind == ExclusionGiftEligibility:
+		if p.NoGiftEligibility {
+			return ExclusionNoGiftEligibility
+		}
+	case p.NoDiscount:
+		return ExclusionNoDiscount
+	case kind == AdjustmentCoupon && p.NoCoupon:
+		return ExclusionNoCoupon
+	}
+	return ""
+}
+
+// Exclude tells whether the line is excluded from kind, and records the exclusion once when it is
+func (p *ProductResponse) Exclude(kind string, reference string) bool {
+	reason := p.ExcludedFrom(kind)
+	if reason == "" {
+		return false
+	}
+	for _, e := range p.Exclusions {
+		if e.Kind == kind && e.Reference == reference {
+			return true
+		}
+	}
+	p.Exclusions = append(p.Exclusions, Exclusion{Kind: kind, Reference: reference, Reason: reason})
+	return true
+}
+
+// EligibleRemaining is what is left to pay for the lines that aren't gifts and aren't excluded from kind
+func (r *CheckoutResponse) EligibleRemaining(kind string) int {
+	remaining := 0
+	for _, p := range r.Products {
+		if !p.IsGift && p.ExcludedFrom(kind) == "" {
+			remaining += p.Remaining()
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,88 @@
+package checkout
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/purchase"
+)
+
+const WarningPurchaseLimit = "purchase_limit"
+
+// Reasons of purchase limit warnings
+const (
+	LimitMaxPerOrder      = "max_per_order"
+	LimitMaxPerCustomer   = "max_per_customer"
+	LimitCustomerRequired = "customer_required"
+)
+
+// ApplyPurchaseLimits caps or drops the lines that ask for more than their product limits allow,
+// before any discount is asked for them. Lines of the same product share the per order limit.
+// Products limited per customer can't be bought in anonymous checkouts, and what customers buy
+// is only counted when record is true, for orders being placed. Release gives it back if they are not.
+// Previews count what earlier lines of the same product were granted themselves, since it isn't recorded
+func (c CheckoutService) ApplyPurchaseLimits(r *CheckoutResponse, lookups []ProductLookup, now time.Time, record bool) {
+	ordered := make(map[int]int)
+	pending := make(map[int]int)
+
+	for i, l := range lookups {
+		if l.Trace.Lookup != LookupFound {
+			continue
+		}
+		limit, ok := c.limits.For(l.Product.Id)
+		if !ok {
+			continue
+		}
+
+		requested := l.Request.Quantity
+		allowed, reason := requested, ""
+		if left := limit.MaxPerOrder - ordered[limit.ProductId]; limit.MaxPerOrder > 0 && allowed > left {
+			allowed, reason = left, LimitMaxPerOrder
+			if limit.Action == purchase.Reject || allowed < 0 {
+				allowed = 0
+			}
+		}
+		if allowed > 0 && limit.MaxPerCustomer > 0 {
+			if r.Customer == nil {
+				allowed, reason = 0, LimitCustomerRequired
+			} else if granted := c.purchases.Reserve(r.Customer.Id, limit, allowed, pending[limit.ProductId], now, record); granted < allowed {
+				allowed, reason = granted, LimitMaxPerCustomer
+			}
+			if !record {
+				pending[limit.ProductId] += allowed
+			}
+		}
+		ordered[limit.ProductId] += allowed
+		if reason == "" {
+			continue
+		}
+
+		reference := strconv.Itoa(limit.ProductId)
+		if allowed == 0 {
+			lookups[i].Trace.Lookup = LookupLimitExceeded
+			log.Printf("Product=%d rejected, purchase limit reached: %s", limit.ProductId, reason)
+			r.AddWarning(WarningPurchaseLimit, reference, reason, fmt.Sprintf("product %d can't be bought, %s", limit.ProductId, LimitMessage(reason)))
+			r.Explain(WarningPurchaseLimit, reference, fmt.Sprintf("rejected %d, %s", requested, reason), 0)
+			continue
+		}
+
+		lookups[i].Request.Quantity = allowed
+		lookups[i].Trace.Quantity = allowed
+		log.Printf("Product=%d capped from %d to %d, purchase limit reached: %s", limit.ProductId, requested, allowed, reason)
+		r.AddWarning(WarningPurchaseLimit, reference, reason, fmt.Sprintf("quantity of product %d capped from %d to %d, %s", limit.ProductId, requested, allowed, LimitMessage(reason)))
+		r.Explain(WarningPurchaseLimit, reference, fmt.Sprintf("capped from %d to %d, %s", requested, allowed, reason), 0)
+	}
+}
+
+func LimitMessage(reason string) string {
+	switch reason {
+	case LimitMaxPerOrder:
+		return "it is limited per order"
+	case LimitMaxPerCustomer:
+		return "the customer already bought as many as allowed"
+	default:
+		return "it is limited per customer and the checkout is anonymous"
+	}
+}
@@ -0,0 +1,119 @@
+package checkout
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/customer"
+	"github.com/gussf/backend-challenge/src/promotion"
+)
+
+const AdjustmentPromotion = "promotion"
+
+// ActivePromotions returns the calendar events running at t
+func (c CheckoutService) ActivePromotions(t time.Time) []promotion.Event {
+	active := c.calendar.ActiveAt(t)
+	for _, e := range active {
+		log.Printf("Promotion=%s (%s) is active, effect=%s", e.Id, e.Name, e.Effect.Type)
+	}
+	return active
+}
+
+func EnabledRuleSets(promotions []promotion.Event) map[string]bool {
+	sets := make(map[string]bool)
+	for _, e := range promotions {
+		if e.Effect.Type == promotion.EnableRuleSet {
+			sets[e.Effect.RuleSet] = true
+		}
+	}
+	return sets
+}
+
+// ApplyStoreWideDiscounts discounts every product that isn't a gift, on what is left to pay
+func (c CheckoutService) ApplyStoreWideDiscounts(r *CheckoutResponse, promotions []promotion.Event) {
+	for _, e := range promotions {
+		if e.Effect.Type != promotion.StoreWideDiscount {
+			continue
+		}
+		given := 0
+		for i, p := range r.Products {
+			if !p.IsGift {
+				amount := r.Rounding.Mode.ApplyBasisPoints(p.Remaining(), e.Effect.BasisPoints)
+				given += r.Products[i].AddAdjustment(AdjustmentPromotion, e.Id, amount)
+			}
+		}
+		r.Explain("store_wide_discount", e.Id, fmt.Sprintf("%dbp on every product", e.Effect.BasisPoints), given)
+	}
+	r.RecalculateTotals()
+}
+
+// AddPromotionGifts adds the gifts of every promotion whose conditions the cart meets, each promotion
+// on its own line. Conditions are checked against the cart before any gift is added.
+// Daily caps are only used up when record is true, so what-if requests don't take gifts from customers
+func (c CheckoutService) AddPromotionGifts(r *CheckoutResponse, promotions []promotion.Event, now time.Time, record bool) {
+	cart := r.Cart()
+	for _, e := range promotions {
+		if e.Effect.Type != promotion.AddGift {
+			continue
+		}
+
+		for i := range r.Products {
+			if !r.Products[i].IsGift {
+				r.Products[i].Exclude(ExclusionGiftEligibility, e.Id)
+			}
+		}
+		quantity := e.Effect.GiftQuantity(cart)
+		if quantity == 0 {
+			log.Printf("Promotion=%s: cart does not meet the gift conditions", e.Id)
+			r.Explain("gift", e.Id, fmt.Sprintf("not given, cart amount=%d does not meet the conditions", cart.Amount), 0)
+			continue
+		}
+
+		log.Printf("Promotion=%s: attempt to add %d gift product(s) to checkout", e.Id, quantity)
+		gift, ok := c.FindGift(e.Effect.GiftProductId)
+		if !ok {
+			r.Explain("gift", e.Id, "not given, no gift product was found", 0)
+			continue
+		}
+
+		if e.Effect.MaxPerCustomer > 0 && r.Customer == nil {
+			log.Printf("Promotion=%s: gifts are limited per customer and the checkout is anonymous", e.Id)
+			r.Explain("gift", e.Id, "not given, limited per customer and the checkout is anonymous", 0)
+			continue
+		}
+
+		if limits := GiftLimits(e, now, r.Customer); len(limits) > 0 {
+			quantity = c.gifts.Reserve(e.Id, quantity, record, limits...)
+			if quantity == 0 {
+				log.Printf("Promotion=%s: gift limits reached", e.Id)
+				r.Explain("gift", e.Id, fmt.Sprintf("not given, daily cap of %d or customer limit of %d reached", e.Effect.DailyCap, e.Effect.MaxPerCustomer), 0)
+				continue
+			}
+		}
+
+		c.AddGiftQuantity(r, gift, quantity, AdjustmentPromotion, e.Id)
+		r.Explain("gift", e.Id, fmt.Sprintf("added %d of product=%d", quantity, gift.Id), 0)
+	}
+}
+
+// GiftLimits are the daily cap and the customer limit of the promotion, the ones it has
+func GiftLimits(e promotion.Event, now time.Time, cust *customer.Customer) []promotion.Limit {
+	var limits []promotion.Limit
+	if e.Effect.DailyCap > 0 {
+		limits = append(limits, promotion.Limit{Bucket: GiftDay(e, now), Cap: e.Effect.DailyCap})
+	}
+	if e.Effect.MaxPerCustomer > 0 && cust != nil {
+		limits = append(limits, promotion.Limit{Bucket: "customer/" + cust.Id, Cap: e.Effect.MaxPerCustomer})
+	}
+	return limits
+}
+
+// GiftDay is the day daily caps are counted in, in the promotion zone or in the
+// zone the checkout was evaluated in for floating promotions
+func GiftDay(e promotion.Event, now time.Time) string {
+	if !e.IsFloating() {
+		now = now.In(e.Location)
+	}
+	return now.Format("2006-01-02")
+}
@@ -0,0 +1,139 @@
+package checkout
+
+import (
+	"fmt"
+
+	"github.com/gussf/backend-challenge/src/promotion"
+	"github.com/gussf/backend-challenge/src/purchase"
+)
+
+// CouponCodes returns the codes that made it into the checkout, rejected ones are only warnings
+func (r *CheckoutResponse) CouponCodes() []string {
+	var codes []string
+	seen := make(map[string]bool)
+	for _, p := range r.Products {
+		for _, a := range p.Adjustments {
+			if a.Kind == AdjustmentCoupon && !seen[a.Reference] {
+				seen[a.Reference] = true
+				codes = append(codes, a.Reference)
+			}
+		}
+	}
+	return codes
+}
+
+// Redeem redeems what a checkout priced without redeeming anything uses up: its coupons, the gifts
+// its promotions gave and what the customer buys of products limited per customer. Everything is
+// redeemed or nothing is, so a quote whose coupon, gifts or limits ran out in the meantime can be rejected
+func (c CheckoutService) Redeem(r *CheckoutResponse) error {
+	codes := r.CouponCodes()
+	if err := c.RedeemCoupons(codes); err != nil {
+		return err
+	}
+
+	gifts := c.promotionGifts(r)
+	for i, g := range gifts {
+		if granted := c.gifts.Reserve(g.event.Id, g.quantity, true, g.limits...); granted < g.quantity {
+			c.gifts.Release(g.event.Id, granted, g.limits...)
+			c.releaseGifts(gifts[:i])
+			c.ReleaseCoupons(codes)
+			return fmt.Errorf("promotion %s: %w", g.event.Id, promotion.ErrGiftLimitReached)
+		}
+	}
+
+	limited := c.limitedPurchases(r)
+	now := c.clock.Now()
+	for i, p := range limited {
+		// The quoted quantity is honoured as a whole or the quote is rejected
+		limit := p.limit
+		limit.Action = purchase.Reject
+		if c.purchases.Reserve(r.Customer.Id, limit, p.quantity, 0, now, true) < p.quantity {
+			c.releasePurchases(r.Customer.Id, limited[:i])
+			c.releaseGifts(gifts)
+			c.ReleaseCoupons(codes)
+			return fmt.Errorf("product %d: %w", limit.ProductId, purchase.ErrLimitReached)
+		}
+	}
+	return nil
+}
+
+// Release gives back what ProcessRequest or Redeem redeemed for r, when the order it was priced for is not placed
+func (c CheckoutService) Release(r *CheckoutResponse) {
+	c.ReleaseCoupons(r.CouponCodes())
+	c.releaseGifts(c.promotionGifts(r))
+	if r.Customer != nil {
+		c.releasePurchases(r.Customer.Id, c.limitedPurchases(r))
+	}
+}
+
+func (c CheckoutService) releaseGifts(gifts []promotionGift) {
+	for _, g := range gifts {
+		c.gifts.Release(g.event.Id, g.quantity, g.limits...)
+	}
+}
+
+func (c CheckoutService) releasePurchases(customerId string, limited []limitedPurchase) {
+	for _, p := range limited {
+		c.purchases.Release(customerId, p.limit, p.quantity)
+	}
+}
+
+// limitedPurchase is how many units of a product limited per customer a checkout buys
+type limitedPurchase struct {
+	limit    purchase.Limit
+	quantity int
+}
+
+// limitedPurchases sums the lines of every product r buys that is limited per customer,
+// anonymous checkouts buy none of them
+func (c CheckoutService) limitedPurchases(r *CheckoutResponse) []limitedPurchase {
+	if r.Customer == nil {
+		return nil
+	}
+
+	var limited []limitedPurchase
+	index := make(map[int]int)
+	for _, p := range r.Products {
+		limit, ok := c.limits.For(p.Id)
+		if p.IsGift || !ok || limit.MaxPerCustomer == 0 {
+			continue
+		}
+		if i, ok := index[p.Id]; ok {
+			limited[i].quantity += p.Quantity
+			continue
+		}
+		index[p.Id] = len(limited)
+		limited = append(limited, limitedPurchase{limit: limit, quantity: p.Quantity})
+	}
+	return limited
+}
+
+// promotionGift is a gift line added by a promotion, with the caps it was counted in
+type promotionGift struct {
+	event    promotion.Event
+	quantity int
+	limits   []promotion.Limit
+}
+
+// promotionGifts finds the promotion of every gift line of r, skipping promotions that no longer exist
+func (c CheckoutService) promotionGifts(r *CheckoutResponse) []promotionGift {
+	events := make(map[string]promotion.Event)
+	for _, e := range c.calendar.Events() {
+		events[e.Id] = e
+	}
+
+	var gifts []promotionGift
+	for _, p := range r.Products {
+		if !p.IsGift {
+			continue
+		}
+		for _, a := range p.Adjustments {
+			e, ok := events[a.Reference]
+			if a.Kind != AdjustmentPromotion || !ok {
+				continue
+			}
+			gifts = append(gifts, promotionGift{event: e, quantity: p.Quantity, limits: GiftLimits(e, r.EvaluatedAt, r.Customer)})
+		}
+	}
+	return gifts
+}
@@ -0,0 +1,30 @@
+package checkout
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gussf/backend-challenge/src/pricing"
+)
+
+const AdjustmentRule = "rule"
+
+// ApplyPricingRules evaluates the pricing rules against the whole cart, gifts and products
+// excluded from discounts are never part of a promotion
+func (c CheckoutService) ApplyPricingRules(r *CheckoutResponse, enabledSets map[string]bool) {
+	lines := make([]pricing.Line, len(r.Products))
+	for i, p := range r.Products {
+		if p.IsGift || r.Products[i].Exclude(AdjustmentRule, "") {
+			continue
+		}
+		lines[i] = pricing.Line{ProductId: p.Id, Quantity: p.Quantity, UnitAmount: p.UnitAmount, Remaining: p.Remaining()}
+	}
+
+	for _, result := range c.rules.Evaluate(lines, enabledSets) {
+		given := r.Products[result.Line].AddAdjustment(AdjustmentRule, result.RuleId, result.Amount)
+		log.Printf("Pricing rule=%s discounted %d from product=%d", result.RuleId, given, r.Products[result.Line].Id)
+		r.Explain("pricing_rule", result.RuleId, fmt.Sprintf("applied to product=%d", r.Products[result.Line].Id), given)
+	}
+
+	r.RecalculateTotals()
+}
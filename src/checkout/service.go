@@ -1,83 +1,361 @@
 package checkout
 
 import (
+	"fmt"
 	"log"
-	"time"
 
+	"github.com/gussf/backend-challenge/src/clock"
+	"github.com/gussf/backend-challenge/src/coupon"
+	"github.com/gussf/backend-challenge/src/customer"
 	"github.com/gussf/backend-challenge/src/discount"
+	"github.com/gussf/backend-challenge/src/money"
+	"github.com/gussf/backend-challenge/src/pricing"
+	"github.com/gussf/backend-challenge/src/promotion"
+	"github.com/gussf/backend-challenge/src/purchase"
 	"github.com/gussf/backend-challenge/src/repository"
+	"github.com/gussf/backend-challenge/src/shipping"
+	"github.com/gussf/backend-challenge/src/tax"
 )
 
 type CheckoutService struct {
-	repo            repository.Repository
-	discountSvc     discount.DiscountService
-	blackFridayDate time.Time
+	repo             repository.Repository
+	discountSvc      discount.DiscountService
+	calendar         promotion.Calendar
+	maxOrderDiscount money.BasisPoints
+	rounding         money.Rounding
+	currency         string
+	rates            money.RatesTable
+	coupons          coupon.CouponService
+	rules            pricing.Engine
+	timeZones        TimeZones
+	clock            clock.Clock
+	gifts            *promotion.GiftCounter
+	taxes            tax.Table
+	taxMode          tax.Mode
+	shipping         []shipping.ShippingRateProvider
+	customers        customer.Store
+	priceLists       []customer.PriceList
+	cartDiscounts    discount.CartDiscountService
+	limits           purchase.Limits
+	purchases        *purchase.Counter
 }
 
-func NewCheckoutService(r repository.Repository, d discount.DiscountService, bf time.Time) CheckoutService {
-	return CheckoutService{
-		repo:            r,
-		discountSvc:     d,
-		blackFridayDate: bf,
+// Option configures optional behavior of the CheckoutService
+type Option func(*CheckoutService)
+
+// WithMaxOrderDiscount limits the total discount of an order to a percentage of its total amount
+func WithMaxOrderDiscount(p float32) Option {
+	return func(c *CheckoutService) {
+		c.maxOrderDiscount = money.BasisPointsFromPercentage(p)
+	}
+}
+
+// WithRounding sets how and where line discounts are rounded to cents
+func WithRounding(r money.Rounding) Option {
+	return func(c *CheckoutService) {
+		c.rounding = r
+	}
+}
+
+// WithCurrency sets the catalog currency and the rates used to present prices in other currencies
+func WithCurrency(catalog string, rates money.RatesTable) Option {
+	return func(c *CheckoutService) {
+		c.currency = money.NormalizeCurrency(catalog)
+		c.rates = rates
+	}
+}
+
+func WithCoupons(svc coupon.CouponService) Option {
+	return func(c *CheckoutService) {
+		c.coupons = svc
+	}
+}
+
+func WithPricingRules(e pricing.Engine) Option {
+	return func(c *CheckoutService) {
+		c.rules = e
+	}
+}
+
+func WithTimeZones(tz TimeZones) Option {
+	return func(c *CheckoutService) {
+		c.timeZones = tz
+	}
+}
+
+func WithClock(cl clock.Clock) Option {
+	return func(c *CheckoutService) {
+		c.clock = cl
+	}
+}
+
+// WithTaxes sets the rates of each destination region and whether catalog prices include them
+func WithTaxes(t tax.Table, mode tax.Mode) Option {
+	return func(c *CheckoutService) {
+		c.taxes = t
+		c.taxMode = mode
+	}
+}
+
+// WithShipping sets the providers of the shipping options, which are listed in their order
+func WithShipping(providers ...shipping.ShippingRateProvider) Option {
+	return func(c *CheckoutService) {
+		c.shipping = providers
 	}
 }
 
+// WithCustomers identifies customers in store, and gives them the prices of their price lists
+func WithCustomers(store customer.Store, lists ...customer.PriceList) Option {
+	return func(c *CheckoutService) {
+		c.customers = store
+		c.priceLists = lists
+	}
+}
+
+// WithCartDiscounts asks svc for an order level discount once every other discount is given
+func WithCartDiscounts(svc discount.CartDiscountService) Option {
+	return func(c *CheckoutService) {
+		c.cartDiscounts = svc
+	}
+}
+
+// WithPurchaseLimits caps how many units of some products orders and customers can buy
+func WithPurchaseLimits(l purchase.Limits) Option {
+	return func(c *CheckoutService) {
+		c.limits = l
+	}
+}
+
+func NewCheckoutService(r repository.Repository, d discount.DiscountService, cal promotion.Calendar, opts ...Option) CheckoutService {
+	c := CheckoutService{
+		repo:             r,
+		discountSvc:      d,
+		calendar:         cal,
+		maxOrderDiscount: money.OneHundredPercent,
+		currency:         money.DefaultCurrency,
+		rates:            money.NewRatesTable(money.DefaultCurrency),
+		coupons:          coupon.NewCouponService(nil),
+		rules:            pricing.NewEngine(nil, money.HalfUp),
+		clock:            clock.SystemClock{},
+		gifts:            promotion.NewGiftCounter(),
+		purchases:        purchase.NewCounter(),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// ProcessRequest prices the request, redeeming its coupons unless it is a what-if request
 func (c CheckoutService) ProcessRequest(req CheckoutRequest) *CheckoutResponse {
-	response := &CheckoutResponse{}
+	return c.process(req, !req.IsWhatIf())
+}
 
-	for _, p := range req.Products {
-		productDAO, err := c.repo.Find(p.Id)
-		if err != nil {
-			switch err {
-			case repository.ErrProductNotFound:
-				log.Printf("Product with id=%d not found in repository", p.Id)
-				continue
-			default:
-				log.Printf("Something unexpected went wrong obtaining product=%d: %v", p.Id, err)
-				continue
-			}
+// Quote prices the request without redeeming anything, for carts that are still being filled
+func (c CheckoutService) Quote(req CheckoutRequest) *CheckoutResponse {
+	return c.process(req, false)
+}
+
+func (c CheckoutService) process(req CheckoutRequest, redeem bool) *CheckoutResponse {
+	response := &CheckoutResponse{Rounding: c.rounding, Currency: c.currency, WhatIf: req.IsWhatIf(), TaxMode: c.taxMode}
+	if req.Explain {
+		response.Trace = &Trace{}
+	}
+
+	now, err := c.EvaluationTime(req)
+	if err != nil {
+		log.Printf("Failed to resolve time zone=%s storefront=%s, using the default one: %v", req.TimeZone, req.Storefront, err)
+		now, _ = c.EvaluationTime(CheckoutRequest{AsOf: req.AsOf})
+	}
+	response.EvaluatedAt = now
+	c.FindCustomer(response, req.CustomerId)
+	promotions := c.ActivePromotions(now)
+	for _, e := range promotions {
+		response.Explain("promotion", e.Id, "active, effect="+string(e.Effect.Type), 0)
+	}
+
+	lookups := c.LookupProducts(req)
+	c.ApplyPurchaseLimits(response, lookups, now, redeem)
+	cartAmount := CartAmount(lookups)
+	for _, l := range lookups {
+		p, productDAO, line := l.Request, l.Product, l.Trace
+		if line.Lookup != LookupFound {
+			response.ExplainLine(line)
+			continue
 		}
 
-		if CheckedOutProductIsAGift(productDAO) {
-			log.Printf("Product with id=%d is a gift and therefore cannot be checked out", p.Id)
+		if productDAO.No_discount {
+			response.AddProduct(productDAO, p.Quantity, 0)
+			response.Products[len(response.Products)-1].Exclude(ExclusionDiscountService, "")
+			response.ExplainLastLine(line, discount.Discount{Reason: ExclusionNoDiscount})
 			continue
 		}
 
-		discount := c.discountSvc.GetDiscountForProduct(int32(p.Id))
-		response.AddProduct(productDAO, p.Quantity, discount)
+		discount := c.discountSvc.GetDiscountForProduct(response.DiscountRequest(productDAO, p.Quantity, cartAmount, req.Channel))
+		if discount.AmountCents > 0 {
+			amount, err := c.ConvertToCatalogCurrency(discount.AmountCents, discount.Currency)
+			if err == nil {
+				response.AddProductWithFixedDiscount(productDAO, p.Quantity, amount*p.Quantity)
+				response.ExplainLastLine(line, discount)
+				continue
+			}
+			log.Printf("Ignoring discount amount=%d%s for product=%d, using %dbp: %v", discount.AmountCents, discount.Currency, p.Id, discount.BasisPoints, err)
+			line.Discount = NewDiscountTrace(discount)
+			line.Discount.Ignored = err.Error()
+		}
+		response.AddProduct(productDAO, p.Quantity, discount.BasisPoints)
+		response.ExplainLastLine(line, discount)
 	}
 
-	// Only add gift if there are products in checkout
-	if len(response.Products) > 0 && c.ItsBlackFriday() {
-		log.Println("Its black friday! Attempt to add gift product to checkout")
-		c.AddBlackFridayGift(response)
+	if c.rounding.Level == money.OrderLevel {
+		before := response.TotalDiscount
+		response.RoundAtOrderLevel()
+		response.Explain("order_rounding", c.rounding.Mode.String(), fmt.Sprintf("line discounts rounded once for the order, %d before", before), response.TotalDiscount)
+	}
+
+	c.ApplyPriceLists(response)
+
+	if len(c.rules.Rules()) > 0 {
+		c.ApplyPricingRules(response, EnabledRuleSets(promotions))
+	}
+
+	c.ApplyStoreWideDiscounts(response, promotions)
+
+	if len(req.CouponCodes) > 0 {
+		c.ApplyCoupons(req.CouponCodes, response, now, redeem)
+	}
+
+	c.ApplyCartDiscount(response, req.Channel)
+
+	// The limit itself is never rounded up, otherwise it could be exceeded by a cent
+	maxDiscount := money.Floor.ApplyBasisPoints(response.TotalAmount, c.maxOrderDiscount)
+	if response.TotalDiscount > maxDiscount {
+		log.Printf("Order discount=%d is above the maximum allowed, limiting to %d", response.TotalDiscount, maxDiscount)
+		response.Explain("max_order_discount", fmt.Sprintf("%dbp", c.maxOrderDiscount), fmt.Sprintf("discount of %d limited", response.TotalDiscount), maxDiscount)
+		response.LimitTotalDiscount(maxDiscount)
+	}
+
+	// Only add gifts if there are products in checkout
+	if len(response.Products) > 0 {
+		c.AddPromotionGifts(response, promotions, now, redeem)
+	}
+
+	c.ApplyTaxes(response, req.DestinationRegion)
+	c.ApplyShipping(response, req.ShippingAddress, req.ShippingOption)
+
+	if c.NeedsConversion(req.Currency) {
+		if err := response.ConvertToPresentment(req.Currency, c.rates); err != nil {
+			log.Printf("Failed to convert checkout to currency=%s: %v", req.Currency, err)
+			response.Explain("presentment", req.Currency, "failed: "+err.Error(), 0)
+		} else {
+			response.Explain("presentment", req.Currency, "converted", response.Presentment.TotalAmount.Amount)
+		}
 	}
 
 	return response
 }
 
+// ProductLookup is a product of the request and what the repository has for it,
+// Trace.Lookup telling whether it can be checked out
+type ProductLookup struct {
+	Request ProductRequest
+	Product repository.ProductDAO
+	Trace   LineTrace
+}
+
+// LookupProducts finds every product of the request before any discount is asked for
+func (c CheckoutService) LookupProducts(req CheckoutRequest) []ProductLookup {
+	lookups := make([]ProductLookup, 0, len(req.Products))
+
+	for _, p := range req.Products {
+		l := ProductLookup{Request: p, Trace: LineTrace{ProductId: p.Id, Quantity: p.Quantity, Lookup: LookupFound}}
+		productDAO, err := c.repo.Find(p.Id)
+		switch {
+		case err == repository.ErrProductNotFound:
+			log.Printf("Product with id=%d not found in repository", p.Id)
+			l.Trace.Lookup = LookupNotFound
+		case err != nil:
+			log.Printf("Something unexpected went wrong obtaining product=%d: %v", p.Id, err)
+			l.Trace.Lookup = LookupError
+		case CheckedOutProductIsAGift(productDAO):
+			log.Printf("Product with id=%d is a gift and therefore cannot be checked out", p.Id)
+			l.Trace.Lookup = LookupGiftNotForSale
+		default:
+			l.Product = productDAO
+		}
+		lookups = append(lookups, l)
+	}
+
+	return lookups
+}
+
+// CartAmount is what the products that can be checked out cost, before any discount
+func CartAmount(lookups []ProductLookup) int {
+	amount := 0
+	for _, l := range lookups {
+		if l.Trace.Lookup == LookupFound {
+			amount += l.Product.Amount * l.Request.Quantity
+		}
+	}
+	return amount
+}
+
+// SupportsCurrency tells whether prices can be presented in currency
+func (c CheckoutService) SupportsCurrency(currency string) bool {
+	return !c.NeedsConversion(currency) || c.rates.Supports(currency)
+}
+
+func (c CheckoutService) NeedsConversion(currency string) bool {
+	currency = money.NormalizeCurrency(currency)
+	return currency != "" && currency != c.currency
+}
+
+// ConvertToCatalogCurrency converts an amount received in currency, an empty currency meaning the catalog one
+func (c CheckoutService) ConvertToCatalogCurrency(amount int, currency string) (int, error) {
+	if !c.NeedsConversion(currency) {
+		return amount, nil
+	}
+	converted, err := c.rates.Convert(money.New(amount, currency), c.currency, c.rounding.Mode)
+	return converted.Amount, err
+}
+
 func CheckedOutProductIsAGift(p repository.ProductDAO) bool {
 	return p.Is_gift
 }
 
-func (c CheckoutService) ItsBlackFriday() bool {
-	today := time.Now()
-	return c.blackFridayDate.Month() == today.Month() && c.blackFridayDate.Day() == today.Day()
+// AddGift adds productId to the checkout for free, or any gift from the repository when productId is 0
+func (c CheckoutService) AddGift(r *CheckoutResponse, productId int, kind string, reference string) {
+	if gift, ok := c.FindGift(productId); ok {
+		c.AddGiftQuantity(r, gift, 1, kind, reference)
+	}
 }
 
-func (c CheckoutService) AddBlackFridayGift(r *CheckoutResponse) {
-	gift, err := c.repo.FindGift()
+// FindGift looks up productId, or any gift from the repository when productId is 0
+func (c CheckoutService) FindGift(productId int) (repository.ProductDAO, bool) {
+	var gift repository.ProductDAO
+	var err error
+	if productId == 0 {
+		gift, err = c.repo.FindGift()
+	} else {
+		gift, err = c.repo.Find(productId)
+	}
+
 	if err != nil {
 		switch err {
 		case repository.ErrNoGiftFound:
 			log.Printf("No gift was found in repository")
-			return
+			return gift, false
 		default:
 			log.Printf("Something went wrong obtaining a gift: %v", err)
-			return
+			return gift, false
 		}
 	}
+	return gift, true
+}
 
-	r.AddGiftProduct(gift, 1)
-	log.Printf("Gift product=%d added to checkout", gift.Id)
+func (c CheckoutService) AddGiftQuantity(r *CheckoutResponse, gift repository.ProductDAO, quantity int, kind string, reference string) {
+	r.AddGiftProduct(gift, quantity)
+	last := &r.Products[len(r.Products)-1]
+	last.Adjustments = append(last.Adjustments, Adjustment{Kind: kind, Reference: reference})
+	log.Printf("Gift product=%d (x%d) added to checkout by %s=%s", gift.Id, quantity, kind, reference)
 }
@@ -1,16 +1,27 @@
 package checkout
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/gussf/backend-challenge/src/clock"
+	"github.com/gussf/backend-challenge/src/coupon"
+	"github.com/gussf/backend-challenge/src/customer"
+	"github.com/gussf/backend-challenge/src/discount"
+	"github.com/gussf/backend-challenge/src/money"
+	"github.com/gussf/backend-challenge/src/pricing"
+	"github.com/gussf/backend-challenge/src/promotion"
+	"github.com/gussf/backend-challenge/src/purchase"
 	"github.com/gussf/backend-challenge/src/repository"
+	"github.com/gussf/backend-challenge/src/shipping"
+	"github.com/gussf/backend-challenge/src/tax"
 )
 
 type StubDiscountService struct{}
 
-func (s StubDiscountService) GetDiscountForProduct(id int32) float32 {
-	return 0.1
+func (s StubDiscountService) GetDiscountForProduct(req discount.Request) discount.Discount {
+	return discount.FromBasisPoints(1000)
 }
 
 func TestCheckoutProcessRequest(t *testing.T) {
@@ -82,7 +93,7 @@ func TestCheckoutProcessRequest(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			inmemoryRepo := repository.InMemoryRepository{Products: tt.testProducts}
-			checkoutSvc := NewCheckoutService(inmemoryRepo, StubDiscountService{}, time.Now().Add(24*time.Hour)) // Add 1 day to avoid Black Friday
+			checkoutSvc := NewCheckoutService(inmemoryRepo, StubDiscountService{}, promotion.NewCalendar()) // No promotions, so no gift
 			request := CheckoutRequest{
 				Products: tt.testProductRequest,
 			}
@@ -106,6 +117,27 @@ func TestCheckoutProcessRequest(t *testing.T) {
 	}
 }
 
+func TestCheckoutProcessRequestMaxOrderDiscount(t *testing.T) {
+
+	inmemoryRepo := repository.InMemoryRepository{Products: []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 1000, Is_gift: false},
+	}}
+	checkoutSvc := NewCheckoutService(inmemoryRepo, StubDiscountService{}, promotion.NewCalendar(), WithMaxOrderDiscount(0.05))
+
+	response := checkoutSvc.ProcessRequest(CheckoutRequest{Products: []ProductRequest{{Id: 1, Quantity: 1}}})
+
+	want := 50
+	got := response.TotalDiscount
+	if want != got {
+		t.Errorf("Incorrect TotalDiscount: want=%d, got=%d", want, got)
+	}
+
+	got = response.Products[0].DiscountGiven
+	if want != got {
+		t.Errorf("Incorrect Product DiscountGiven: want=%d, got=%d", want, got)
+	}
+}
+
 func TestCheckedOutProductIsAGift(t *testing.T) {
 
 	product := repository.ProductDAO{Is_gift: true}
@@ -118,37 +150,100 @@ func TestCheckedOutProductIsAGift(t *testing.T) {
 	}
 }
 
-func TestItsBlackFriday(t *testing.T) {
+func TestActivePromotions(t *testing.T) {
 	inmemoryRepo := repository.InMemoryRepository{}
+	today := time.Now()
+	tomorrow := today.Add(24 * time.Hour)
 
 	tests := []struct {
 		name            string
 		checkoutService CheckoutService
-		want            bool
+		want            int
 	}{
 		{
 			name:            "Should be black friday",
-			checkoutService: NewCheckoutService(inmemoryRepo, StubDiscountService{}, time.Now()),
-			want:            true,
+			checkoutService: NewCheckoutService(inmemoryRepo, StubDiscountService{}, promotion.NewCalendar(promotion.BlackFriday(today.Month(), today.Day(), time.Local))),
+			want:            1,
 		},
 		{
 			name:            "Should Not be black friday",
-			checkoutService: NewCheckoutService(inmemoryRepo, StubDiscountService{}, time.Now().Add(24*time.Hour)),
-			want:            false,
+			checkoutService: NewCheckoutService(inmemoryRepo, StubDiscountService{}, promotion.NewCalendar(promotion.BlackFriday(tomorrow.Month(), tomorrow.Day(), time.Local))),
+			want:            0,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := tt.checkoutService.ItsBlackFriday()
+			got := len(tt.checkoutService.ActivePromotions(today))
 			if tt.want != got {
-				t.Errorf("%s: Incorrect black friday assertion: want=%t, got=%t", tt.name, tt.want, got)
+				t.Errorf("%s: Incorrect active promotions: want=%d, got=%d", tt.name, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckoutProcessRequestClock(t *testing.T) {
+
+	inmemoryRepo := repository.InMemoryRepository{Products: []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 100, Is_gift: false},
+		{Id: 2, Title: "b", Description: "b", Amount: 50, Is_gift: true},
+	}}
+	calendar := promotion.NewCalendar(promotion.BlackFriday(time.November, 26, time.UTC))
+	blackFriday := time.Date(2021, 11, 26, 10, 0, 0, 0, time.UTC)
+	dayBefore := blackFriday.Add(-24 * time.Hour)
+	coupons := coupon.NewCouponService([]coupon.Coupon{{Code: "ONCE", Type: coupon.FixedAmount, Amount: 10, UsageLimit: 1}})
+
+	tests := []struct {
+		name       string
+		now        time.Time
+		asOf       *time.Time
+		wantLength int
+		wantWhatIf bool
+	}{
+		{name: "Black friday gift by the clock", now: blackFriday, wantLength: 2},
+		{name: "No gift the day before", now: dayBefore, wantLength: 1},
+		{name: "What-if on black friday", now: dayBefore, asOf: &blackFriday, wantLength: 2, wantWhatIf: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkoutSvc := NewCheckoutService(inmemoryRepo, StubDiscountService{}, calendar,
+				WithClock(clock.FixedClock{T: tt.now}),
+				WithTimeZones(TimeZones{Default: time.UTC}),
+				WithCoupons(coupons))
+
+			response := checkoutSvc.ProcessRequest(CheckoutRequest{
+				Products:    []ProductRequest{{Id: 1, Quantity: 1}},
+				CouponCodes: []string{"ONCE"},
+				AsOf:        tt.asOf,
+			})
+
+			got := len(response.Products)
+			if tt.wantLength != got {
+				t.Errorf("%s: Incorrect Products length: want=%d, got=%d", tt.name, tt.wantLength, got)
+			}
+
+			want := tt.now
+			if tt.asOf != nil {
+				want = *tt.asOf
+			}
+			if !want.Equal(response.EvaluatedAt) {
+				t.Errorf("%s: Incorrect EvaluatedAt: want=%s, got=%s", tt.name, want, response.EvaluatedAt)
+			}
+
+			if tt.wantWhatIf != response.WhatIf {
+				t.Errorf("%s: Incorrect WhatIf: want=%t, got=%t", tt.name, tt.wantWhatIf, response.WhatIf)
 			}
 		})
 	}
+
+	// Only the two real checkouts redeemed the coupon, the second one was over the limit
+	if got := coupons.Used("ONCE"); got != 1 {
+		t.Errorf("Incorrect coupon usage: want=1, got=%d", got)
+	}
 }
 
-func TestAddBlackFridayGift(t *testing.T) {
+func TestAddGift(t *testing.T) {
 
 	tests := []struct {
 		name       string
@@ -178,10 +273,10 @@ func TestAddBlackFridayGift(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 
 			inmemoryRepo := repository.InMemoryRepository{Products: tt.products}
-			checkoutSvc := NewCheckoutService(inmemoryRepo, StubDiscountService{}, time.Now())
+			checkoutSvc := NewCheckoutService(inmemoryRepo, StubDiscountService{}, promotion.NewCalendar())
 			checkoutResp := &CheckoutResponse{}
 
-			checkoutSvc.AddBlackFridayGift(checkoutResp)
+			checkoutSvc.AddGift(checkoutResp, 0, AdjustmentPromotion, "black-friday")
 			got := len(checkoutResp.Products)
 			if tt.wantLength != got {
 				t.Errorf("%s: Incorrect Products Length: want=%d, got=%d", tt.name, tt.wantLength, got)
@@ -195,3 +290,526 @@ func TestAddBlackFridayGift(t *testing.T) {
 	}
 
 }
+
+func TestCheckoutProcessRequestPromotions(t *testing.T) {
+
+	inmemoryRepo := repository.InMemoryRepository{Products: []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 100, Is_gift: false},
+		{Id: 2, Title: "b", Description: "b", Amount: 50, Is_gift: true},
+	}}
+	now := time.Now()
+	calendar := promotion.NewCalendar(
+		promotion.Event{Id: "sale", Start: now.Add(-time.Hour), End: now.Add(time.Hour), Effect: promotion.Effect{Type: promotion.StoreWideDiscount, BasisPoints: 5000}},
+		promotion.Event{Id: "gift", Start: now.Add(-time.Hour), End: now.Add(time.Hour), Effect: promotion.Effect{Type: promotion.AddGift, GiftProductId: 2}},
+		promotion.Event{Id: "rules", Start: now.Add(-time.Hour), End: now.Add(time.Hour), Effect: promotion.Effect{Type: promotion.EnableRuleSet, RuleSet: "bf"}},
+		promotion.Event{Id: "over", Start: now.Add(-2 * time.Hour), End: now.Add(-time.Hour), Effect: promotion.Effect{Type: promotion.StoreWideDiscount, BasisPoints: 5000}},
+	)
+	rules := []pricing.Rule{{Id: "2for1", Type: pricing.BuyXPayY, ProductIds: []int{1}, Buy: 2, Pay: 1, Set: "bf"}}
+	checkoutSvc := NewCheckoutService(inmemoryRepo, StubDiscountService{}, calendar,
+		WithPricingRules(pricing.NewEngine(rules, money.HalfUp)),
+		WithClock(clock.FixedClock{T: now}))
+
+	response := checkoutSvc.ProcessRequest(CheckoutRequest{Products: []ProductRequest{{Id: 1, Quantity: 2}}})
+
+	// 10% from the discount service (20), one unit free from the rule set (90), then 50% store wide (45)
+	want := 20 + 90 + 45
+	got := response.TotalDiscount
+	if want != got {
+		t.Errorf("Incorrect TotalDiscount: want=%d, got=%d", want, got)
+	}
+
+	want = 2
+	got = len(response.Products)
+	if want != got {
+		t.Errorf("Incorrect Products length: want=%d, got=%d", want, got)
+	}
+}
+
+func TestCheckoutProcessRequestPricingRules(t *testing.T) {
+
+	inmemoryRepo := repository.InMemoryRepository{Products: []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 100, Is_gift: false},
+	}}
+	rules := []pricing.Rule{{Id: "3for2", Type: pricing.BuyXPayY, ProductIds: []int{1}, Buy: 3, Pay: 2}}
+	checkoutSvc := NewCheckoutService(inmemoryRepo, StubDiscountService{}, promotion.NewCalendar(),
+		WithPricingRules(pricing.NewEngine(rules, money.HalfUp)))
+
+	response := checkoutSvc.ProcessRequest(CheckoutRequest{Products: []ProductRequest{{Id: 1, Quantity: 3}}})
+
+	// 10% from the discount service, then one of the three units for free
+	want := 30 + 90
+	got := response.TotalDiscount
+	if want != got {
+		t.Errorf("Incorrect TotalDiscount: want=%d, got=%d", want, got)
+	}
+
+	adjustments := response.Products[0].Adjustments
+	if len(adjustments) != 1 || adjustments[0].Kind != AdjustmentRule || adjustments[0].Reference != "3for2" {
+		t.Errorf("Incorrect Adjustments: got=%+v", adjustments)
+	}
+}
+
+func TestCheckoutProcessRequestGiftWithPurchase(t *testing.T) {
+
+	inmemoryRepo := repository.InMemoryRepository{Products: []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 100, Category: "home", Is_gift: false},
+		{Id: 2, Title: "b", Description: "b", Amount: 50, Is_gift: true},
+		{Id: 3, Title: "c", Description: "c", Amount: 50, Is_gift: true},
+	}}
+	now := time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)
+	calendar := promotion.NewCalendar(
+		promotion.Event{Id: "spend", Effect: promotion.Effect{Type: promotion.AddGift, GiftProductId: 2,
+			GiftConditions: promotion.GiftConditions{SpendStep: 150, MaxPerOrder: 3, DailyCap: 4}}},
+		promotion.Event{Id: "home", Effect: promotion.Effect{Type: promotion.AddGift, GiftProductId: 3,
+			GiftConditions: promotion.GiftConditions{RequiredCategories: []string{"home"}, MinCartAmount: 1000}}},
+	)
+	checkoutSvc := NewCheckoutService(inmemoryRepo, StubDiscountService{}, calendar, WithClock(clock.FixedClock{T: now}))
+
+	// 500 minus 10% is 450, three spend steps of 150 and too little for the home gift
+	request := CheckoutRequest{Products: []ProductRequest{{Id: 1, Quantity: 5}}}
+	asOf := now
+	preview := checkoutSvc.ProcessRequest(CheckoutRequest{Products: request.Products, AsOf: &asOf})
+	if len(preview.Products) != 2 || preview.Products[1].Quantity != 3 {
+		t.Errorf("Incorrect what-if gifts: got=%+v", preview.Products)
+	}
+
+	response := checkoutSvc.ProcessRequest(request)
+	if len(response.Products) != 2 || response.Products[1].Quantity != 3 {
+		t.Errorf("Incorrect gifts: got=%+v", response.Products)
+	}
+
+	// Only one gift is left for the day, the preview didn't use any
+	response = checkoutSvc.ProcessRequest(request)
+	if len(response.Products) != 2 || response.Products[1].Quantity != 1 {
+		t.Errorf("Incorrect gifts after the daily cap: got=%+v", response.Products)
+	}
+
+	// Promotions are capped on their own, the home gift is still given once the spend one runs out
+	response = checkoutSvc.ProcessRequest(CheckoutRequest{Products: []ProductRequest{{Id: 1, Quantity: 20}}})
+	if len(response.Products) != 2 || response.Products[1].Id != 3 {
+		t.Errorf("Incorrect gifts after the daily cap was reached: got=%+v", response.Products)
+	}
+}
+
+func TestCheckoutProcessRequestExplain(t *testing.T) {
+
+	inmemoryRepo := repository.InMemoryRepository{Products: []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 105, Is_gift: false},
+		{Id: 2, Title: "b", Description: "b", Amount: 50, Is_gift: true},
+	}}
+	now := time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)
+	calendar := promotion.NewCalendar(
+		promotion.Event{Id: "gift", Effect: promotion.Effect{Type: promotion.AddGift, GiftProductId: 2}},
+	)
+	checkoutSvc := NewCheckoutService(inmemoryRepo, StubDiscountService{}, calendar, WithClock(clock.FixedClock{T: now}))
+	request := CheckoutRequest{Products: []ProductRequest{{Id: 1, Quantity: 1}, {Id: 2, Quantity: 1}, {Id: 9, Quantity: 1}}}
+
+	if response := checkoutSvc.ProcessRequest(request); response.Trace != nil {
+		t.Errorf("Trace should only be built when asked for: got=%+v", response.Trace)
+	}
+
+	request.Explain = true
+	trace := checkoutSvc.ProcessRequest(request).Trace
+	if trace == nil || len(trace.Lines) != 3 {
+		t.Fatalf("Incorrect trace lines: got=%+v", trace)
+	}
+
+	wantLookups := []string{LookupFound, LookupGiftNotForSale, LookupNotFound}
+	for i, want := range wantLookups {
+		if got := trace.Lines[i].Lookup; want != got {
+			t.Errorf("Incorrect Lookup of line %d: want=%s, got=%s", i, want, got)
+		}
+	}
+
+	// 10% of 105 is 10.5, rounded half up
+	line := trace.Lines[0]
+	if line.Discount == nil || line.Discount.BasisPoints != 1000 || line.ExactDiscount != "10.5000" || line.RoundedDiscount != 11 {
+		t.Errorf("Incorrect trace of line 0: got=%+v", line)
+	}
+
+	wantSteps := []string{"promotion", "gift"}
+	if len(trace.Steps) != len(wantSteps) {
+		t.Fatalf("Incorrect trace steps: want=%v, got=%+v", wantSteps, trace.Steps)
+	}
+	for i, want := range wantSteps {
+		if got := trace.Steps[i].Step; want != got {
+			t.Errorf("Incorrect step %d: want=%s, got=%s", i, want, got)
+		}
+	}
+}
+
+func TestCheckoutProcessRequestTax(t *testing.T) {
+
+	inmemoryRepo := repository.InMemoryRepository{Products: []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 1000},
+		{Id: 2, Title: "b", Description: "b", Amount: 500, Tax_category: "food"},
+	}}
+	taxes, _ := tax.NewTable(map[string]map[string]money.BasisPoints{"US-CA": {"standard": 725, "food": 0}})
+
+	tests := []struct {
+		name          string
+		region        string
+		mode          tax.Mode
+		wantTax       int
+		wantAmountDue int
+		wantWarnings  int
+	}{
+		// Discounted lines are 900 and 450, food isn't taxed
+		{name: "Exclusive tax is added", region: "us-ca", mode: tax.Exclusive, wantTax: 65, wantAmountDue: 1415},
+		{name: "Inclusive tax is broken down", region: "US-CA", mode: tax.Inclusive, wantTax: 61, wantAmountDue: 1350},
+		{name: "No region is not taxed", region: "", mode: tax.Exclusive, wantTax: 0, wantAmountDue: 1350},
+		{name: "Unknown region is a warning", region: "US-NY", mode: tax.Exclusive, wantTax: 0, wantAmountDue: 1350, wantWarnings: 1},
+	}
+
+	for _, tt := range tests {
+		checkoutSvc := NewCheckoutService(inmemoryRepo, StubDiscountService{}, promotion.NewCalendar(), WithTaxes(taxes, tt.mode))
+		request := CheckoutRequest{Products: []ProductRequest{{Id: 1, Quantity: 1}, {Id: 2, Quantity: 1}}, DestinationRegion: tt.region}
+		response := checkoutSvc.ProcessRequest(request)
+
+		if tt.wantTax != response.TotalTax {
+			t.Errorf("%s: Incorrect TotalTax: want=%d, got=%d", tt.name, tt.wantTax, response.TotalTax)
+		}
+		if got := response.AmountDue(); tt.wantAmountDue != got {
+			t.Errorf("%s: Incorrect AmountDue: want=%d, got=%d", tt.name, tt.wantAmountDue, got)
+		}
+		if got := len(response.Warnings); tt.wantWarnings != got {
+			t.Errorf("%s: Incorrect number of warnings: want=%d, got=%d", tt.name, tt.wantWarnings, got)
+		}
+		if got := response.Products[1].TaxAmount; got != 0 {
+			t.Errorf("%s: Incorrect TaxAmount of food: want=0, got=%d", tt.name, got)
+		}
+	}
+}
+
+func TestCheckoutProcessRequestShipping(t *testing.T) {
+
+	inmemoryRepo := repository.InMemoryRepository{Products: []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 1000, Weight_grams: 800},
+		{Id: 2, Title: "b", Description: "b", Amount: 0, Weight_grams: 5000, Is_gift: true},
+	}}
+	standard := shipping.WeightTiers{
+		Service: shipping.Service{Id: "standard"},
+		Tiers:   []shipping.Tier{{UpToGrams: 1000, Amount: 990}, {UpToGrams: 5000, Amount: 1990}},
+	}
+	express := shipping.FlatRate{Service: shipping.Service{Id: "express", Countries: []string{"US"}}, Amount: 2990}
+	calendar := promotion.NewCalendar(promotion.Event{Id: "gift", Effect: promotion.Effect{Type: promotion.AddGift, GiftProductId: 2}})
+	checkoutSvc := NewCheckoutService(inmemoryRepo, StubDiscountService{}, calendar, WithShipping(standard, express))
+
+	tests := []struct {
+		name          string
+		address       *shipping.Address
+		option        string
+		wantOptions   int
+		wantShipping  int
+		wantAmountDue int
+		wantWarnings  int
+	}{
+		{name: "No address is not shipped", wantAmountDue: 900},
+		{name: "Cheapest option by default, the gift ships for free", address: &shipping.Address{Country: "US"}, wantOptions: 2, wantShipping: 990, wantAmountDue: 1890},
+		{name: "Selected option", address: &shipping.Address{Country: "US"}, option: "express", wantOptions: 2, wantShipping: 2990, wantAmountDue: 3890},
+		{name: "Option that doesn't deliver there", address: &shipping.Address{Country: "BR"}, option: "express", wantOptions: 1, wantShipping: 990, wantAmountDue: 1890, wantWarnings: 1},
+	}
+
+	for _, tt := range tests {
+		request := CheckoutRequest{Products: []ProductRequest{{Id: 1, Quantity: 1}}, ShippingAddress: tt.address, ShippingOption: tt.option}
+		response := checkoutSvc.ProcessRequest(request)
+
+		if got := len(response.ShippingOptions); tt.wantOptions != got {
+			t.Errorf("%s: Incorrect number of options: want=%d, got=%d", tt.name, tt.wantOptions, got)
+		}
+		if tt.wantShipping != response.TotalShipping {
+			t.Errorf("%s: Incorrect TotalShipping: want=%d, got=%d", tt.name, tt.wantShipping, response.TotalShipping)
+		}
+		if got := response.AmountDue(); tt.wantAmountDue != got {
+			t.Errorf("%s: Incorrect AmountDue: want=%d, got=%d", tt.name, tt.wantAmountDue, got)
+		}
+		if got := len(response.Warnings); tt.wantWarnings != got {
+			t.Errorf("%s: Incorrect number of warnings: want=%d, got=%d", tt.name, tt.wantWarnings, got)
+		}
+	}
+}
+
+// RecordingDiscountService remembers the last request it got
+type RecordingDiscountService struct {
+	last *discount.Request
+}
+
+func (s RecordingDiscountService) GetDiscountForProduct(req discount.Request) discount.Discount {
+	*s.last = req
+	return discount.FromBasisPoints(0)
+}
+
+func TestCheckoutProcessRequestCustomer(t *testing.T) {
+
+	inmemoryRepo := repository.InMemoryRepository{Products: []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 1000},
+		{Id: 2, Title: "b", Description: "b", Amount: 0, Is_gift: true},
+	}}
+	customers := customer.NewMemoryStore(
+		customer.Customer{Id: "b2b", Segment: "b2b"},
+		customer.Customer{Id: "gold", Segment: "retail", Tier: "gold"},
+	)
+	lists := []customer.PriceList{
+		{Id: "wholesale", Segment: "b2b", Prices: map[int]int{1: 800}},
+		{Id: "loyalty", Tier: "gold", BasisPoints: 1000},
+	}
+	calendar := promotion.NewCalendar(promotion.Event{Id: "gift", Effect: promotion.Effect{
+		Type: promotion.AddGift, GiftProductId: 2, GiftConditions: promotion.GiftConditions{MaxPerCustomer: 1},
+	}})
+	recorder := RecordingDiscountService{last: &discount.Request{}}
+	checkoutSvc := NewCheckoutService(inmemoryRepo, recorder, calendar, WithCustomers(customers, lists...))
+
+	tests := []struct {
+		name         string
+		customerId   string
+		wantDiscount int
+		wantGifts    int
+		wantWarnings int
+	}{
+		{name: "Anonymous gets no gift limited per customer", wantDiscount: 0, wantGifts: 0},
+		{name: "B2B price", customerId: "b2b", wantDiscount: 200, wantGifts: 1},
+		{name: "Gift only once per customer", customerId: "b2b", wantDiscount: 200, wantGifts: 0},
+		{name: "Loyalty tier", customerId: "gold", wantDiscount: 100, wantGifts: 1},
+		{name: "Unknown customer is anonymous", customerId: "nobody", wantDiscount: 0, wantGifts: 0, wantWarnings: 1},
+	}
+
+	for _, tt := range tests {
+		request := CheckoutRequest{Products: []ProductRequest{{Id: 1, Quantity: 1}}, CustomerId: tt.customerId}
+		response := checkoutSvc.ProcessRequest(request)
+
+		if tt.wantDiscount != response.TotalDiscount {
+			t.Errorf("%s: Incorrect TotalDiscount: want=%d, got=%d", tt.name, tt.wantDiscount, response.TotalDiscount)
+		}
+		if got := len(response.Products) - 1; tt.wantGifts != got {
+			t.Errorf("%s: Incorrect number of gifts: want=%d, got=%d", tt.name, tt.wantGifts, got)
+		}
+		if got := len(response.Warnings); tt.wantWarnings != got {
+			t.Errorf("%s: Incorrect number of warnings: want=%d, got=%d", tt.name, tt.wantWarnings, got)
+		}
+		if response.Customer != nil && recorder.last.CustomerSegment != response.Customer.Segment {
+			t.Errorf("%s: Customer segment was not forwarded: want=%s, got=%s", tt.name, response.Customer.Segment, recorder.last.CustomerSegment)
+		}
+	}
+}
+
+func TestCheckoutDiscountRequestContext(t *testing.T) {
+
+	inmemoryRepo := repository.InMemoryRepository{Products: []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 1000},
+		{Id: 2, Title: "b", Description: "b", Amount: 250},
+		{Id: 3, Title: "c", Description: "c", Amount: 90, Is_gift: true},
+	}}
+	recorder := RecordingDiscountService{last: &discount.Request{}}
+	checkoutSvc := NewCheckoutService(inmemoryRepo, recorder, promotion.NewCalendar())
+
+	// Gifts and unknown products are not part of the cart amount
+	request := CheckoutRequest{Products: []ProductRequest{{Id: 1, Quantity: 1}, {Id: 3, Quantity: 1}, {Id: 9, Quantity: 1}, {Id: 2, Quantity: 2}}, Channel: "app"}
+	checkoutSvc.ProcessRequest(request)
+
+	want := discount.Request{ProductId: 2, Quantity: 2, UnitAmount: 250, CartAmount: 1500, Currency: money.DefaultCurrency, Channel: "app"}
+	if *recorder.last != want {
+		t.Errorf("Incorrect discount request: want=%+v, got=%+v", want, *recorder.last)
+	}
+}
+
+type StubCartDiscountService struct {
+	discount discount.CartDiscount
+}
+
+func (s StubCartDiscountService) GetCartDiscount(req discount.CartRequest) discount.CartDiscount {
+	if req.TotalAmount-req.TotalDiscount < 1000 {
+		return discount.CartDiscount{}
+	}
+	return s.discount
+}
+
+func TestCheckoutProcessRequestCartDiscount(t *testing.T) {
+
+	inmemoryRepo := repository.InMemoryRepository{Products: []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 1000},
+		{Id: 2, Title: "b", Description: "b", Amount: 500},
+	}}
+
+	tests := []struct {
+		name         string
+		discount     discount.CartDiscount
+		products     []ProductRequest
+		wantAmount   int
+		wantShares   []int
+		wantNoCartDc bool
+	}{
+		// Lines are 900 and 450 after the 10% product discount
+		{
+			name:       "Percentage of what is left to pay",
+			discount:   discount.CartDiscount{Id: "big-cart", Reason: "10% off orders over $10", BasisPoints: 1000},
+			products:   []ProductRequest{{Id: 1, Quantity: 1}, {Id: 2, Quantity: 1}},
+			wantAmount: 135,
+			wantShares: []int{90, 45},
+		},
+		{
+			name:       "Fixed amount is apportioned",
+			discount:   discount.CartDiscount{Id: "five", Reason: "$5 off", AmountCents: 500},
+			products:   []ProductRequest{{Id: 1, Quantity: 1}, {Id: 2, Quantity: 1}},
+			wantAmount: 500,
+			wantShares: []int{333, 167},
+		},
+		{
+			name:         "Cart below the condition",
+			discount:     discount.CartDiscount{Id: "big-cart", BasisPoints: 1000},
+			products:     []ProductRequest{{Id: 2, Quantity: 1}},
+			wantShares:   []int{0},
+			wantNoCartDc: true,
+		},
+	}
+
+	for _, tt := range tests {
+		checkoutSvc := NewCheckoutService(inmemoryRepo, StubDiscountService{}, promotion.NewCalendar(), WithCartDiscounts(StubCartDiscountService{tt.discount}))
+		response := checkoutSvc.ProcessRequest(CheckoutRequest{Products: tt.products})
+
+		if tt.wantNoCartDc {
+			if response.CartDiscount != nil {
+				t.Errorf("%s: Cart discount should not be given: got=%+v", tt.name, response.CartDiscount)
+			}
+		} else if response.CartDiscount == nil || response.CartDiscount.Amount != tt.wantAmount || response.CartDiscount.Reason != tt.discount.Reason {
+			t.Errorf("%s: Incorrect CartDiscount: want=%d, got=%+v", tt.name, tt.wantAmount, response.CartDiscount)
+		}
+		for i, want := range tt.wantShares {
+			if got := response.Products[i].CartDiscountShare(); want != got {
+				t.Errorf("%s: Incorrect share of line %d: want=%d, got=%d", tt.name, i, want, got)
+			}
+		}
+	}
+}
+
+func TestCheckoutProcessRequestExclusions(t *testing.T) {
+
+	inmemoryRepo := repository.InMemoryRepository{Products: []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 100},
+		{Id: 2, Title: "b", Description: "b", Amount: 100, No_discount: true},
+		{Id: 3, Title: "c", Description: "c", Amount: 100, No_coupon: true, No_gift_eligibility: true},
+		{Id: 4, Title: "d", Description: "d", Amount: 50, Is_gift: true},
+	}}
+	now := time.Now()
+	calendar := promotion.NewCalendar(
+		promotion.Event{Id: "sale", Start: now.Add(-time.Hour), End: now.Add(time.Hour), Effect: promotion.Effect{Type: promotion.StoreWideDiscount, BasisPoints: 5000}},
+		promotion.Event{Id: "gift", Start: now.Add(-time.Hour), End: now.Add(time.Hour), Effect: promotion.Effect{Type: promotion.AddGift, GiftProductId: 4,
+			GiftConditions: promotion.GiftConditions{MinCartAmount: 120}}},
+	)
+	coupons := coupon.NewCouponService([]coupon.Coupon{{Code: "FIXED", Type: coupon.FixedAmount, Amount: 30}})
+	checkoutSvc := NewCheckoutService(inmemoryRepo, StubDiscountService{}, calendar, WithCoupons(coupons), WithClock(clock.FixedClock{T: now}))
+
+	response := checkoutSvc.ProcessRequest(CheckoutRequest{
+		Products:    []ProductRequest{{Id: 1, Quantity: 1}, {Id: 2, Quantity: 1}, {Id: 3, Quantity: 1}},
+		CouponCodes: []string{"FIXED"},
+	})
+
+	// 10% and 50% store wide on products 1 and 3, the whole coupon on product 1, nothing on product 2
+	want := 10 + 45 + 30 + 10 + 45
+	if got := response.TotalDiscount; want != got {
+		t.Errorf("Incorrect TotalDiscount: want=%d, got=%d", want, got)
+	}
+	if got := response.Products[1].DiscountGiven; got != 0 {
+		t.Errorf("Incorrect discount of the product excluded from discounts: want=0, got=%d", got)
+	}
+
+	// Without product 3 the cart amount is 15+100, below the gift condition
+	if got := len(response.Products); got != 3 {
+		t.Errorf("Incorrect Products length, gift should not be given: want=3, got=%d", got)
+	}
+
+	wantExclusions := [][]Exclusion{
+		nil,
+		{
+			{Kind: ExclusionDiscountService, Reason: ExclusionNoDiscount},
+			{Kind: AdjustmentPromotion, Reference: "sale", Reason: ExclusionNoDiscount},
+			{Kind: AdjustmentCoupon, Reference: "FIXED", Reason: ExclusionNoDiscount},
+		},
+		{
+			{Kind: AdjustmentCoupon, Reference: "FIXED", Reason: ExclusionNoCoupon},
+			{Kind: ExclusionGiftEligibility, Reference: "gift", Reason: ExclusionNoGiftEligibility},
+		},
+	}
+	for i, want := range wantExclusions {
+		got := response.Products[i].Exclusions
+		if len(want) != len(got) {
+			t.Errorf("Incorrect Exclusions of line %d: want=%+v, got=%+v", i, want, got)
+			continue
+		}
+		for j := range want {
+			if want[j] != got[j] {
+				t.Errorf("Incorrect Exclusion %d of line %d: want=%+v, got=%+v", j, i, want[j], got[j])
+			}
+		}
+	}
+}
+
+func TestCheckoutProcessRequestPurchaseLimits(t *testing.T) {
+
+	inmemoryRepo := repository.InMemoryRepository{Products: []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 100},
+		{Id: 2, Title: "b", Description: "b", Amount: 100},
+		{Id: 3, Title: "c", Description: "c", Amount: 100},
+	}}
+	limits, _ := purchase.NewLimits(
+		purchase.Limit{ProductId: 1, MaxPerOrder: 2, Action: purchase.Cap},
+		purchase.Limit{ProductId: 2, MaxPerCustomer: 3, Window: 24 * time.Hour, Action: purchase.Reject},
+	)
+	customers := customer.NewMemoryStore(customer.Customer{Id: "jane"})
+	recorder := RecordingDiscountService{last: &discount.Request{}}
+	checkoutSvc := NewCheckoutService(inmemoryRepo, recorder, promotion.NewCalendar(), WithCustomers(customers), WithPurchaseLimits(limits))
+
+	tests := []struct {
+		name           string
+		customerId     string
+		products       []ProductRequest
+		quote          bool
+		wantQuantities []int
+		wantReasons    []string
+		wantNoLookup   bool
+	}{
+		{name: "Capped per order across lines", products: []ProductRequest{{Id: 1, Quantity: 1}, {Id: 1, Quantity: 3}},
+			wantQuantities: []int{1, 1}, wantReasons: []string{LimitMaxPerOrder}},
+		{name: "Anonymous can't buy products limited per customer", products: []ProductRequest{{Id: 2, Quantity: 1}, {Id: 3, Quantity: 5}},
+			wantQuantities: []int{5}, wantReasons: []string{LimitCustomerRequired}},
+		{name: "Customer within the limit", customerId: "jane", products: []ProductRequest{{Id: 2, Quantity: 2}},
+			wantQuantities: []int{2}},
+		{name: "Quotes don't use up the limit", customerId: "jane", products: []ProductRequest{{Id: 2, Quantity: 1}}, quote: true,
+			wantQuantities: []int{1}},
+		{name: "Quotes count earlier lines of the product", customerId: "jane", products: []ProductRequest{{Id: 2, Quantity: 1}, {Id: 2, Quantity: 1}}, quote: true,
+			wantQuantities: []int{1}, wantReasons: []string{LimitMaxPerCustomer}},
+		{name: "Rejected above what is left", customerId: "jane", products: []ProductRequest{{Id: 2, Quantity: 2}},
+			wantReasons: []string{LimitMaxPerCustomer}, wantNoLookup: true},
+	}
+
+	for _, tt := range tests {
+		*recorder.last = discount.Request{}
+		request := CheckoutRequest{Products: tt.products, CustomerId: tt.customerId}
+		var response *CheckoutResponse
+		if tt.quote {
+			response = checkoutSvc.Quote(request)
+		} else {
+			response = checkoutSvc.ProcessRequest(request)
+		}
+
+		var quantities []int
+		for _, p := range response.Products {
+			quantities = append(quantities, p.Quantity)
+		}
+		if fmt.Sprint(tt.wantQuantities) != fmt.Sprint(quantities) {
+			t.Errorf("%s: Incorrect quantities: want=%v, got=%v", tt.name, tt.wantQuantities, quantities)
+		}
+
+		var reasons []string
+		for _, w := range response.Warnings {
+			if w.Kind == WarningPurchaseLimit {
+				reasons = append(reasons, w.Reason)
+			}
+		}
+		if fmt.Sprint(tt.wantReasons) != fmt.Sprint(reasons) {
+			t.Errorf("%s: Incorrect purchase limit warnings: want=%v, got=%v", tt.name, tt.wantReasons, reasons)
+		}
+		if tt.wantNoLookup && recorder.last.ProductId != 0 {
+			t.Errorf("%s: Discount should not be asked for rejected product=%d", tt.name, recorder.last.ProductId)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package checkout
+
+import (
+	"log"
+
+	"github.com/gussf/backend-challenge/src/shipping"
+)
+
+const WarningShipping = "shipping"
+
+// ApplyShipping lists the options that can deliver the checkout to address and adds the one
+// that was selected to the totals, the cheapest one when none was. Requests without an address are not shipped
+func (c CheckoutService) ApplyShipping(r *CheckoutResponse, address *shipping.Address, selected string) {
+	if address == nil || len(r.Products) == 0 {
+		return
+	}
+	r.ShippingAddress = address
+
+	r.ShippingOptions = shipping.Options(c.shipping, r.Shipment(*address))
+	option, err := shipping.Select(r.ShippingOptions, selected)
+	if err == shipping.ErrUnknownOption {
+		log.Printf("Shipping option=%s not available, using the cheapest one", selected)
+		r.AddWarning(WarningShipping, selected, "unknown_option", err.Error())
+		option, err = shipping.Select(r.ShippingOptions, "")
+	}
+	if err != nil {
+		log.Printf("Checkout can't be shipped to country=%s: %v", address.Country, err)
+		r.AddWarning(WarningShipping, address.Country, "no_options", err.Error())
+		r.Explain("shipping", address.Country, "not shipped: "+err.Error(), 0)
+		return
+	}
+
+	r.Shipping = &option
+	r.TotalShipping = option.Amount
+	outcome := "selected"
+	if option.Free {
+		outcome = "selected, free over threshold"
+	}
+	r.Explain("shipping", option.Id, outcome, option.Amount)
+}
+
+// Shipment has every line of the checkout, gifts included so that they are shipped too
+func (r *CheckoutResponse) Shipment(address shipping.Address) shipping.Shipment {
+	s := shipping.Shipment{Address: address, Amount: r.TotalAmount - r.TotalDiscount}
+	for _, p := range r.Products {
+		s.Items = append(s.Items, shipping.Item{
+			ProductId:   p.Id,
+			Quantity:    p.Quantity,
+			WeightGrams: p.WeightGrams,
+			Dimensions:  p.Dimensions,
+			IsGift:      p.IsGift,
+		})
+	}
+	return s
+}
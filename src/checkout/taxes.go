@@ -0,0 +1,38 @@
+package checkout
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gussf/backend-challenge/src/tax"
+)
+
+const WarningTax = "tax"
+
+// ApplyTaxes computes the tax of each line on what is left to pay after every discount.
+// Requests without a destination region are not taxed, unknown regions are reported as warnings
+func (c CheckoutService) ApplyTaxes(r *CheckoutResponse, region string) {
+	if region == "" {
+		return
+	}
+
+	lines := make([]tax.Line, len(r.Products))
+	for i, p := range r.Products {
+		rate, err := c.taxes.Rate(region, p.TaxCategory)
+		if err != nil {
+			log.Printf("Failed to find tax rate for region=%s: %v", region, err)
+			r.AddWarning(WarningTax, region, "unknown_region", err.Error())
+			r.Explain("tax", region, "not taxed: "+err.Error(), 0)
+			return
+		}
+		lines[i] = tax.Line{Amount: r.Products[i].Remaining(), Rate: rate}
+	}
+
+	r.TaxRegion = tax.NormalizeRegion(region)
+	for i, amount := range tax.Calculate(lines, r.TaxMode, c.rounding) {
+		r.Products[i].TaxBasisPoints = lines[i].Rate
+		r.Products[i].TaxAmount = amount
+	}
+	r.RecalculateTotals()
+	r.Explain("tax", r.TaxRegion, fmt.Sprintf("mode=%s rounding=%s", r.TaxMode, c.rounding.Level), r.TotalTax)
+}
@@ -0,0 +1,54 @@
+package checkout
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrUnknownTimeZone   = errors.New("unknown time zone")
+	ErrUnknownStorefront = errors.New("unknown storefront")
+)
+
+// TimeZones decides the zone promotions are evaluated in: the one sent by the customer,
+// then the one of the storefront the request came from, then Default
+type TimeZones struct {
+	Default     *time.Location
+	Storefronts map[string]*time.Location
+}
+
+func (tz TimeZones) Resolve(timeZone string, storefront string) (*time.Location, error) {
+	if timeZone != "" {
+		loc, err := time.LoadLocation(timeZone)
+		if err != nil {
+			return nil, ErrUnknownTimeZone
+		}
+		return loc, nil
+	}
+
+	if storefront != "" {
+		loc, ok := tz.Storefronts[storefront]
+		if !ok {
+			return nil, ErrUnknownStorefront
+		}
+		return loc, nil
+	}
+
+	if tz.Default == nil {
+		return time.Local, nil
+	}
+	return tz.Default, nil
+}
+
+// EvaluationTime is the instant promotions are evaluated at, in the zone resolved for the request.
+// It is the current time, unless the request is a what-if one asking for another instant
+func (c CheckoutService) EvaluationTime(req CheckoutRequest) (time.Time, error) {
+	loc, err := c.timeZones.Resolve(req.TimeZone, req.Storefront)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if req.IsWhatIf() {
+		return req.AsOf.In(loc), nil
+	}
+	return c.clock.Now().In(loc), nil
+}
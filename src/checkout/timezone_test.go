@@ -0,0 +1,36 @@
+package checkout
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeZonesResolve(t *testing.T) {
+	saoPaulo, _ := time.LoadLocation("America/Sao_Paulo")
+	london, _ := time.LoadLocation("Europe/London")
+	tz := TimeZones{Default: time.UTC, Storefronts: map[string]*time.Location{"br": saoPaulo}}
+
+	tests := []struct {
+		name       string
+		timeZone   string
+		storefront string
+		want       *time.Location
+		wantErr    error
+	}{
+		{name: "Customer zone comes first", timeZone: "Europe/London", storefront: "br", want: london},
+		{name: "Storefront zone", storefront: "br", want: saoPaulo},
+		{name: "Default zone", want: time.UTC},
+		{name: "Unknown zone", timeZone: "Mars/Olympus", wantErr: ErrUnknownTimeZone},
+		{name: "Unknown storefront", storefront: "mars", wantErr: ErrUnknownStorefront},
+	}
+
+	for _, tt := range tests {
+		got, err := tz.Resolve(tt.timeZone, tt.storefront)
+		if tt.wantErr != err {
+			t.Errorf("%s: Incorrect error: want=%v, got=%v", tt.name, tt.wantErr, err)
+		}
+		if err == nil && tt.want.String() != got.String() {
+			t.Errorf("%s: Incorrect zone: want=%s, got=%s", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package checkout
+
+import (
+	"fmt"
+
+	"github.com/gussf/backend-challenge/src/discount"
+	"github.com/gussf/backend-challenge/src/money"
+)
+
+// Lookup results of LineTrace
+const (
+	LookupFound          = "found"
+	LookupNotFound       = "not_found"
+	LookupGiftNotForSale = "gift_not_for_sale"
+	LookupError          = "error"
+	LookupLimitExceeded  = "limit_exceeded"
+)
+
+// Trace explains how a checkout was priced. It is only built for requests that ask for it,
+// every other response has a nil Trace
+type Trace struct {
+	Lines []LineTrace
+	Steps []TraceStep
+}
+
+// LineTrace follows one product of the request until it became a line, or was dropped
+type LineTrace struct {
+	ProductId int
+	Quantity  int
+	Lookup    string
+	Discount  *DiscountTrace
+	// ExactDiscount is the line discount before rounding, RoundedDiscount after it
+	ExactDiscount   string
+	RoundedDiscount int
+	RoundingMode    string
+}
+
+// DiscountTrace is what the discount service answered for the product
+type DiscountTrace struct {
+	Provider    string
+	Raw         float32
+	BasisPoints money.BasisPoints
+	AmountCents int
+	Currency    string
+	Exact       bool
+	Anomaly     string
+	Error       string
+	Reason      string
+	// Ignored explains why the discount amount wasn't used, in favour of BasisPoints
+	Ignored string
+}
+
+// TraceStep is an order level step, such as a promotion that was evaluated or a gift decision
+type TraceStep struct {
+	Step      string
+	Reference string
+	Outcome   string
+	Amount    int
+}
+
+// Explain records an order level step, it does nothing for responses that aren't being explained
+func (r *CheckoutResponse) Explain(step string, reference string, outcome string, amount int) {
+	if r.Trace == nil {
+		return
+	}
+	r.Trace.Steps = append(r.Trace.Steps, TraceStep{Step: step, Reference: reference, Outcome: outcome, Amount: amount})
+}
+
+func (r *CheckoutResponse) ExplainLine(l LineTrace) {
+	if r.Trace == nil {
+		return
+	}
+	r.Trace.Lines = append(r.Trace.Lines, l)
+}
+
+// ExplainLastLine fills in how the line that was just added got its discount
+func (r *CheckoutResponse) ExplainLastLine(l LineTrace, d discount.Discount) {
+	if r.Trace == nil {
+		return
+	}
+
+	p := r.Products[len(r.Products)-1]
+	l.Lookup = LookupFound
+	if l.Discount == nil {
+		l.Discount = NewDiscountTrace(d)
+	}
+	if p.FixedDiscount > 0 {
+		l.ExactDiscount = fmt.Sprintf("%d", p.FixedDiscount)
+	} else {
+		l.ExactDiscount = ExactBasisPoints(p.TotalAmount, p.DiscountBasisPoints)
+	}
+	l.RoundedDiscount = p.DiscountGiven
+	l.RoundingMode = r.Rounding.Mode.String()
+	r.ExplainLine(l)
+}
+
+func NewDiscountTrace(d discount.Discount) *DiscountTrace {
+	return &DiscountTrace{
+		Provider:    d.Provider,
+		Raw:         d.Raw,
+		BasisPoints: d.BasisPoints,
+		AmountCents: d.AmountCents,
+		Currency:    d.Currency,
+		Exact:       d.Exact,
+		Anomaly:     d.Anomaly,
+		Error:       d.Error,
+		Reason:      d.Reason,
+	}
+}
+
+// ExactBasisPoints is bp of amount with every decimal a basis point can produce
+func ExactBasisPoints(amount int, bp money.BasisPoints) string {
+	n := int64(amount) * int64(bp)
+	return fmt.Sprintf("%d.%04d", n/int64(money.OneHundredPercent), n%int64(money.OneHundredPercent))
+}
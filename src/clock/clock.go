@@ -0,0 +1,32 @@
+package clock
+
+import "time"
+
+// Clock is where the current time comes from, so that it can be controlled in tests and previews
+type Clock interface {
+	Now() time.Time
+}
+
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// FixedClock always returns the same instant
+type FixedClock struct {
+	T time.Time
+}
+
+func (c FixedClock) Now() time.Time {
+	return c.T
+}
+
+// MovableClock returns T, which tests move to make time pass
+type MovableClock struct {
+	T time.Time
+}
+
+func (c *MovableClock) Now() time.Time {
+	return c.T
+}
@@ -0,0 +1,102 @@
+package coupon
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/money"
+)
+
+var (
+	ErrCouponNotFound     = errors.New("coupon code does not exist")
+	ErrCouponNotYetValid  = errors.New("coupon is not valid yet")
+	ErrCouponExpired      = errors.New("coupon has expired")
+	ErrUsageLimitReached  = errors.New("coupon usage limit was reached")
+	ErrMinOrderAmount     = errors.New("order amount is below the coupon minimum")
+	ErrNotStackable       = errors.New("coupon can't be combined with other coupons")
+	ErrNoEligibleProducts = errors.New("no product in checkout is eligible for the coupon")
+	ErrDuplicatedCoupon   = errors.New("coupon was already applied")
+	ErrGiftUnavailable    = errors.New("coupon gift is not available")
+)
+
+type Type string
+
+const (
+	Percentage  Type = "percentage"
+	FixedAmount Type = "fixed_amount"
+	FreeGift    Type = "free_gift"
+)
+
+type Coupon struct {
+	Code string `json:"code"`
+	Type Type   `json:"type"`
+	// BasisPoints is used by percentage coupons
+	BasisPoints money.BasisPoints `json:"basis_points"`
+	// Amount is used by fixed amount coupons, in the catalog currency
+	Amount int `json:"amount"`
+	// GiftProductId is used by free gift coupons
+	GiftProductId int `json:"gift_product_id"`
+	// ProductIds restricts percentage and fixed amount coupons to some products, empty means every product
+	ProductIds []int `json:"product_ids"`
+	// Zero values mean the coupon has no start or end
+	ValidFrom  time.Time `json:"valid_from"`
+	ValidUntil time.Time `json:"valid_until"`
+	// UsageLimit of 0 means unlimited
+	UsageLimit     int  `json:"usage_limit"`
+	MinOrderAmount int  `json:"min_order_amount"`
+	Stackable      bool `json:"stackable"`
+}
+
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (c Coupon) AppliesTo(productId int) bool {
+	if len(c.ProductIds) == 0 {
+		return true
+	}
+	for _, id := range c.ProductIds {
+		if id == productId {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidAt checks the validity window, ValidUntil is exclusive
+func (c Coupon) ValidAt(t time.Time) error {
+	if !c.ValidFrom.IsZero() && t.Before(c.ValidFrom) {
+		return ErrCouponNotYetValid
+	}
+	if !c.ValidUntil.IsZero() && !t.Before(c.ValidUntil) {
+		return ErrCouponExpired
+	}
+	return nil
+}
+
+// Reason is a stable, machine readable identifier for a coupon error
+func Reason(err error) string {
+	switch err {
+	case ErrCouponNotFound:
+		return "coupon_not_found"
+	case ErrCouponNotYetValid:
+		return "coupon_not_yet_valid"
+	case ErrCouponExpired:
+		return "coupon_expired"
+	case ErrUsageLimitReached:
+		return "coupon_usage_limit_reached"
+	case ErrMinOrderAmount:
+		return "coupon_min_order_amount"
+	case ErrNotStackable:
+		return "coupon_not_stackable"
+	case ErrNoEligibleProducts:
+		return "coupon_no_eligible_products"
+	case ErrDuplicatedCoupon:
+		return "coupon_duplicated"
+	case ErrGiftUnavailable:
+		return "coupon_gift_unavailable"
+	default:
+		return "coupon_invalid"
+	}
+}
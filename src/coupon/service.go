@@ -0,0 +1,128 @@
+package coupon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+// CouponService holds coupon definitions and how many times each one was redeemed
+type CouponService struct {
+	coupons map[string]Coupon
+	usage   *usageCounter
+}
+
+type usageCounter struct {
+	mu   sync.Mutex
+	used map[string]int
+}
+
+func NewCouponService(coupons []Coupon) CouponService {
+	svc := CouponService{
+		coupons: make(map[string]Coupon, len(coupons)),
+		usage:   &usageCounter{used: make(map[string]int)},
+	}
+	for _, c := range coupons {
+		c.Code = NormalizeCode(c.Code)
+		svc.coupons[c.Code] = c
+	}
+	return svc
+}
+
+func NewCouponServiceFromFile(jsonFilePath string) (CouponService, error) {
+
+	var coupons []Coupon
+
+	content, err := ioutil.ReadFile(jsonFilePath)
+	if err != nil {
+		return CouponService{}, errors.New("error opening file " + jsonFilePath + ": " + err.Error())
+	}
+
+	err = json.Unmarshal(content, &coupons)
+	if err != nil {
+		return CouponService{}, errors.New("error unmarshalling json: " + err.Error())
+	}
+
+	for _, c := range coupons {
+		switch c.Type {
+		case Percentage, FixedAmount, FreeGift:
+		default:
+			return CouponService{}, fmt.Errorf("coupon %s has an unknown type: %s", c.Code, c.Type)
+		}
+	}
+
+	return NewCouponService(coupons), nil
+}
+
+// Find returns a coupon that can be used at t by an order of orderAmount
+func (svc CouponService) Find(code string, t time.Time, orderAmount int) (Coupon, error) {
+	c, ok := svc.coupons[NormalizeCode(code)]
+	if !ok {
+		return Coupon{}, ErrCouponNotFound
+	}
+
+	if err := c.ValidAt(t); err != nil {
+		return c, err
+	}
+
+	if orderAmount < c.MinOrderAmount {
+		return c, ErrMinOrderAmount
+	}
+
+	if c.UsageLimit > 0 && svc.Used(c.Code) >= c.UsageLimit {
+		return c, ErrUsageLimitReached
+	}
+
+	return c, nil
+}
+
+// Redeem counts one usage of the coupon, failing if it would go over its limit
+func (svc CouponService) Redeem(c Coupon) error {
+	if svc.usage == nil {
+		return nil
+	}
+	svc.usage.mu.Lock()
+	defer svc.usage.mu.Unlock()
+
+	if c.UsageLimit > 0 && svc.usage.used[c.Code] >= c.UsageLimit {
+		return ErrUsageLimitReached
+	}
+	svc.usage.used[c.Code]++
+	return nil
+}
+
+// RedeemCode is Redeem for coupons that were already found, such as the ones of a quote,
+// only their usage limit is checked again
+func (svc CouponService) RedeemCode(code string) error {
+	c, ok := svc.coupons[NormalizeCode(code)]
+	if !ok {
+		return ErrCouponNotFound
+	}
+	return svc.Redeem(c)
+}
+
+// Release gives back one usage of the coupon, for redemptions that couldn't be completed
+func (svc CouponService) Release(code string) {
+	if svc.usage == nil {
+		return
+	}
+	svc.usage.mu.Lock()
+	defer svc.usage.mu.Unlock()
+
+	code = NormalizeCode(code)
+	if svc.usage.used[code] > 0 {
+		svc.usage.used[code]--
+	}
+}
+
+func (svc CouponService) Used(code string) int {
+	if svc.usage == nil {
+		return 0
+	}
+	svc.usage.mu.Lock()
+	defer svc.usage.mu.Unlock()
+	return svc.usage.used[NormalizeCode(code)]
+}
@@ -0,0 +1,140 @@
+package customer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/gussf/backend-challenge/src/money"
+)
+
+var (
+	ErrCustomerNotFound = errors.New("customer not found")
+	ErrInvalidCustomer  = errors.New("invalid customer")
+	ErrInvalidPriceList = errors.New("invalid price list")
+)
+
+// Customer is who is checking out. Segment groups customers that are priced alike, such as "b2b",
+// and Tier is their loyalty tier, such as "gold"
+type Customer struct {
+	Id      string `json:"id"`
+	Segment string `json:"segment"`
+	Tier    string `json:"tier"`
+}
+
+type Store interface {
+	Find(id string) (Customer, error)
+}
+
+// MemoryStore is a read-only Store loaded once
+type MemoryStore struct {
+	customers map[string]Customer
+}
+
+func NewMemoryStore(customers ...Customer) MemoryStore {
+	s := MemoryStore{customers: make(map[string]Customer, len(customers))}
+	for _, c := range customers {
+		s.customers[c.Id] = c
+	}
+	return s
+}
+
+func NewMemoryStoreFromFile(jsonFilePath string) (MemoryStore, error) {
+
+	var customers []Customer
+
+	content, err := ioutil.ReadFile(jsonFilePath)
+	if err != nil {
+		return MemoryStore{}, errors.New("error opening file " + jsonFilePath + ": " + err.Error())
+	}
+
+	err = json.Unmarshal(content, &customers)
+	if err != nil {
+		return MemoryStore{}, errors.New("error unmarshalling json: " + err.Error())
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range customers {
+		if c.Id == "" {
+			return MemoryStore{}, fmt.Errorf("%w: missing id", ErrInvalidCustomer)
+		}
+		if seen[c.Id] {
+			return MemoryStore{}, fmt.Errorf("%w: %s is duplicated", ErrInvalidCustomer, c.Id)
+		}
+		seen[c.Id] = true
+	}
+
+	return NewMemoryStore(customers...), nil
+}
+
+func (s MemoryStore) Find(id string) (Customer, error) {
+	c, ok := s.customers[id]
+	if !ok {
+		return Customer{}, ErrCustomerNotFound
+	}
+	return c, nil
+}
+
+func (s MemoryStore) Len() int {
+	return len(s.customers)
+}
+
+// PriceList gives the customers of a segment and/or tier their own unit prices, and a discount
+// on what is left to pay after them. Prices above the catalog one are ignored
+type PriceList struct {
+	Id      string `json:"id"`
+	Segment string `json:"segment"`
+	Tier    string `json:"tier"`
+	// Prices are unit amounts by product id
+	Prices      map[int]int       `json:"prices"`
+	BasisPoints money.BasisPoints `json:"basis_points"`
+}
+
+// AppliesTo tells whether c is in the segment and tier of the list, a list without one of them matching any
+func (l PriceList) AppliesTo(c Customer) bool {
+	return (l.Segment == "" || strings.EqualFold(l.Segment, c.Segment)) && (l.Tier == "" || strings.EqualFold(l.Tier, c.Tier))
+}
+
+func (l PriceList) Validate() error {
+	if l.Id == "" {
+		return fmt.Errorf("%w: missing id", ErrInvalidPriceList)
+	}
+	if l.Segment == "" && l.Tier == "" {
+		return fmt.Errorf("%w: %s needs a segment or a tier", ErrInvalidPriceList, l.Id)
+	}
+	if l.BasisPoints < 0 || l.BasisPoints > money.OneHundredPercent {
+		return fmt.Errorf("%w: %s basis_points must be between 0 and 10000", ErrInvalidPriceList, l.Id)
+	}
+	for id, price := range l.Prices {
+		if price < 0 {
+			return fmt.Errorf("%w: %s has a negative price for product %d", ErrInvalidPriceList, l.Id, id)
+		}
+	}
+	return nil
+}
+
+// LoadPriceListsFromFile reads price lists, which are applied in the order they are defined
+func LoadPriceListsFromFile(jsonFilePath string) ([]PriceList, error) {
+
+	var lists []PriceList
+
+	content, err := ioutil.ReadFile(jsonFilePath)
+	if err != nil {
+		return nil, errors.New("error opening file " + jsonFilePath + ": " + err.Error())
+	}
+
+	err = json.Unmarshal(content, &lists)
+	if err != nil {
+		return nil, errors.New("error unmarshalling json: " + err.Error())
+	}
+
+	for _, l := range lists {
+		if err := l.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return lists, nil
+}
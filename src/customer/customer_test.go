@@ -0,0 +1,72 @@
+package customer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/clock"
+)
+
+func TestAuthenticator(t *testing.T) {
+
+	now := time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)
+	a := NewAuthenticator([]byte("secret"), clock.FixedClock{T: now})
+	token, _ := a.Sign("cus_1", time.Hour)
+
+	if id, err := a.Verify(token); err != nil || id != "cus_1" {
+		t.Errorf("Incorrect customer of a valid token: want=cus_1, got=%s (%v)", id, err)
+	}
+	if _, err := NewAuthenticator([]byte("other"), clock.FixedClock{T: now}).Verify(token); err != ErrInvalidToken {
+		t.Errorf("Token signed with another key should be invalid: got=%v", err)
+	}
+	if _, err := a.Verify(token[1:]); err != ErrInvalidToken {
+		t.Errorf("Tampered token should be invalid: got=%v", err)
+	}
+
+	later := NewAuthenticator([]byte("secret"), clock.FixedClock{T: now.Add(time.Hour)})
+	if _, err := later.Verify(token); err != ErrTokenExpired {
+		t.Errorf("Incorrect error for an expired token: want=%v, got=%v", ErrTokenExpired, err)
+	}
+}
+
+func TestPriceListAppliesTo(t *testing.T) {
+
+	gold := Customer{Id: "c1", Segment: "retail", Tier: "gold"}
+	tests := []struct {
+		list PriceList
+		want bool
+	}{
+		{list: PriceList{Id: "a", Tier: "Gold"}, want: true},
+		{list: PriceList{Id: "b", Segment: "b2b"}, want: false},
+		{list: PriceList{Id: "c", Segment: "retail", Tier: "silver"}, want: false},
+		{list: PriceList{Id: "d", Segment: "retail", Tier: "gold"}, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.list.AppliesTo(gold); tt.want != got {
+			t.Errorf("%s: Incorrect AppliesTo: want=%t, got=%t", tt.list.Id, tt.want, got)
+		}
+	}
+}
+
+func TestLoadFiles(t *testing.T) {
+
+	store, err := NewMemoryStoreFromFile("../../data/customers.json")
+	if err != nil {
+		t.Fatalf("Failed to load customers: %v", err)
+	}
+	if _, err := store.Find("cus_1001"); err != nil {
+		t.Errorf("Failed to find customer: %v", err)
+	}
+	if _, err := store.Find("cus_0"); err != ErrCustomerNotFound {
+		t.Errorf("Incorrect error for unknown customer: want=%v, got=%v", ErrCustomerNotFound, err)
+	}
+
+	lists, err := LoadPriceListsFromFile("../../data/price_lists.json")
+	if err != nil {
+		t.Fatalf("Failed to load price lists: %v", err)
+	}
+	if len(lists) == 0 || len(lists[0].Prices) == 0 {
+		t.Errorf("Price lists were not loaded: got=%+v", lists)
+	}
+}
@@ -0,0 +1,77 @@
+package customer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/clock"
+)
+
+var (
+	ErrInvalidToken = errors.New("customer token is not valid")
+	ErrTokenExpired = errors.New("customer token has expired")
+)
+
+type claims struct {
+	CustomerId string    `json:"customer_id"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Authenticator verifies the tokens the identity service gives customers once they log in,
+// which are signed with a key both services share (HMAC-SHA256)
+type Authenticator struct {
+	key   []byte
+	clock clock.Clock
+}
+
+func NewAuthenticator(key []byte, cl clock.Clock) Authenticator {
+	return Authenticator{key: key, clock: cl}
+}
+
+// Sign issues a token for customerId that is valid for ttl
+func (a Authenticator) Sign(customerId string, ttl time.Duration) (string, error) {
+	payload, err := json.Marshal(claims{CustomerId: customerId, ExpiresAt: a.clock.Now().Add(ttl)})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(a.mac([]byte(encoded))), nil
+}
+
+// Verify returns the customer id of token
+func (a Authenticator) Verify(token string) (string, error) {
+	var c claims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return "", ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, a.mac([]byte(parts[0]))) {
+		return "", ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	if err := json.Unmarshal(payload, &c); err != nil || c.CustomerId == "" {
+		return "", ErrInvalidToken
+	}
+
+	if !a.clock.Now().Before(c.ExpiresAt) {
+		return "", ErrTokenExpired
+	}
+	return c.CustomerId, nil
+}
+
+func (a Authenticator) mac(b []byte) []byte {
+	h := hmac.New(sha256.New, a.key)
+	h.Write(b)
+	return h.Sum(nil)
+}
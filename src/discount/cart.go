@@ -0,0 +1,103 @@
+package discount
+
+import (
+	"context"
+	"log"
+	"time"
+
+	pb "github.com/gussf/backend-challenge/src/discount/pb"
+	"github.com/gussf/backend-challenge/src/money"
+)
+
+// CartDiscountService gives order level discounts, such as "10% off orders over $500"
+type CartDiscountService interface {
+	GetCartDiscount(req CartRequest) CartDiscount
+}
+
+// CartLine is a line of the priced cart, Discount being every discount it already got
+type CartLine struct {
+	ProductId  int32
+	Quantity   int
+	UnitAmount int
+	Amount     int
+	Discount   int
+	IsGift     bool
+}
+
+// CartRequest is the cart priced with every other discount, TotalAmount being before them
+// and TotalDiscount what they discounted. Customer fields work like the Request ones
+type CartRequest struct {
+	Lines           []CartLine
+	TotalAmount     int
+	TotalDiscount   int
+	Currency        string
+	CustomerId      string
+	CustomerSegment string
+	CustomerTier    string
+	Channel         string
+}
+
+// CartDiscount is an order level discount on what is left to pay, BasisPoints of it or AmountCents in Currency.
+// Id identifies the discount, such as a campaign, and Reason explains it to customers
+type CartDiscount struct {
+	Id          string
+	Reason      string
+	BasisPoints money.BasisPoints
+	AmountCents int
+	Currency    string
+	Provider    string
+	Error       string
+}
+
+// IsZero tells the cart doesn't get a discount
+func (d CartDiscount) IsZero() bool {
+	return d.BasisPoints <= 0 && d.AmountCents <= 0
+}
+
+// GetCartDiscount asks the discount service for an order level discount. Servers that don't implement it
+// fail the call, which is the same as not giving one
+func (svc DiscountService_gRPC) GetCartDiscount(req CartRequest) CartDiscount {
+
+	clientDeadline := time.Now().Add(svc.deadline)
+	ctx, cancel := context.WithDeadline(context.Background(), clientDeadline)
+	defer cancel()
+
+	r, err := svc.client.GetCartDiscount(ctx, ConvertCartRequestToGetCartDiscountRequest(req))
+	if err != nil {
+		log.Printf("Failed to get cart discount, returning no discount: %v", err)
+		return CartDiscount{Provider: ProviderGRPC, Error: err.Error()}
+	}
+
+	d := CartDiscount{Id: r.GetId(), Reason: r.GetReason(), Provider: ProviderGRPC}
+	if exact := r.GetDiscount(); exact != nil {
+		d.BasisPoints = money.BasisPoints(exact.GetBasisPoints())
+		d.AmountCents = int(exact.GetAmountCents())
+		d.Currency = exact.GetCurrency()
+	}
+
+	log.Printf("Cart discount=%s %dbp amount=%d%s received", d.Id, d.BasisPoints, d.AmountCents, d.Currency)
+	return d
+}
+
+func ConvertCartRequestToGetCartDiscountRequest(req CartRequest) *pb.GetCartDiscountRequest {
+	r := &pb.GetCartDiscountRequest{
+		TotalCents:      int64(req.TotalAmount),
+		DiscountCents:   int64(req.TotalDiscount),
+		Currency:        req.Currency,
+		CustomerID:      req.CustomerId,
+		CustomerSegment: req.CustomerSegment,
+		CustomerTier:    req.CustomerTier,
+		SalesChannel:    req.Channel,
+	}
+	for _, l := range req.Lines {
+		r.Lines = append(r.Lines, &pb.CartLine{
+			ProductID:      l.ProductId,
+			Quantity:       int32(l.Quantity),
+			UnitPriceCents: int64(l.UnitAmount),
+			TotalCents:     int64(l.Amount),
+			DiscountCents:  int64(l.Discount),
+			IsGift:         l.IsGift,
+		})
+	}
+	return r
+}
@@ -1,5 +1,61 @@
 package discount
 
+import (
+	"math"
+
+	"github.com/gussf/backend-challenge/src/money"
+)
+
 type DiscountService interface {
-	GetDiscountForProduct(id int32) float32
+	GetDiscountForProduct(req Request) Discount
+}
+
+// Request is the product a discount is asked for and the checkout it is in. Only ProductId is
+// required, every other field is left at its zero value when unknown, such as the customer
+// fields for anonymous checkouts
+type Request struct {
+	ProductId int32
+	Quantity  int
+	// UnitAmount and CartAmount are in Currency, CartAmount being the cart before any discount
+	UnitAmount      int
+	CartAmount      int
+	Currency        string
+	CustomerId      string
+	CustomerSegment string
+	CustomerTier    string
+	Channel         string
+}
+
+// Discount is what the discount service granted to one unit of a product
+type Discount struct {
+	BasisPoints money.BasisPoints
+	// AmountCents is an absolute discount per unit, used instead of BasisPoints when greater than 0
+	AmountCents int
+	Currency    string
+	// Exact is false when only the legacy float32 percentage was available
+	Exact bool
+	// Raw is the float32 percentage as received, kept for logging
+	Raw float32
+	// Provider is where the discount came from, Anomaly what validation had to fix in it
+	// and Error why the provider couldn't give one. They are only informative
+	Provider string
+	Anomaly  string
+	Error    string
+	// Reason explains discounts that were set by hand, such as overrides
+	Reason string
+}
+
+// FromPercentage builds a Discount from the legacy float32 percentage.
+// NaN and infinite values have no basis point representation and become 0, but are kept in Raw
+func FromPercentage(p float32) Discount {
+	d := Discount{Raw: p}
+	if !math.IsNaN(float64(p)) && !math.IsInf(float64(p), 0) {
+		d.BasisPoints = money.BasisPointsFromPercentage(p)
+	}
+	return d
+}
+
+// FromBasisPoints builds an exact Discount
+func FromBasisPoints(bp money.BasisPoints) Discount {
+	return Discount{BasisPoints: bp, Exact: true, Raw: float32(bp.Percentage())}
 }
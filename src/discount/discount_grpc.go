@@ -6,9 +6,12 @@ import (
 	"time"
 
 	pb "github.com/gussf/backend-challenge/src/discount/pb"
+	"github.com/gussf/backend-challenge/src/money"
 	"google.golang.org/grpc"
 )
 
+const ProviderGRPC = "grpc"
+
 type DiscountService_gRPC struct {
 	client   pb.DiscountClient
 	deadline time.Duration
@@ -27,19 +30,55 @@ func NewDiscountService_gRPC(connAddress string, deadline time.Duration) Discoun
 	}
 }
 
-func (svc DiscountService_gRPC) GetDiscountForProduct(id int32) float32 {
+func (svc DiscountService_gRPC) GetDiscountForProduct(req Request) Discount {
 
+	id := req.ProductId
 	clientDeadline := time.Now().Add(svc.deadline)
 	ctx, cancel := context.WithDeadline(context.Background(), clientDeadline)
 
-	r, err := svc.client.GetDiscount(ctx, &pb.GetDiscountRequest{ProductID: id})
+	r, err := svc.client.GetDiscount(ctx, ConvertRequestToGetDiscountRequest(req))
 	defer cancel()
 	if err != nil {
 		log.Printf("Failed to get discount for product=%d, returning discount=0.00: %v", id, err)
-		return 0.00
+		return Discount{Provider: ProviderGRPC, Error: err.Error()}
 	}
-	discount := r.GetPercentage()
+	discount := ConvertGetDiscountResponseToDiscount(r)
+	discount.Provider = ProviderGRPC
 
-	log.Printf("Discount=%.2f received for product=%d", discount, id)
+	log.Printf("Discount=%dbp amount=%d%s exact=%t received for product=%d", discount.BasisPoints, discount.AmountCents, discount.Currency, discount.Exact, id)
 	return discount
 }
+
+// ConvertRequestToGetDiscountRequest sends the whole context, servers that don't know about it just ignore it
+func ConvertRequestToGetDiscountRequest(req Request) *pb.GetDiscountRequest {
+	return &pb.GetDiscountRequest{
+		ProductID:       req.ProductId,
+		Quantity:        int32(req.Quantity),
+		UnitPriceCents:  int64(req.UnitAmount),
+		CartTotalCents:  int64(req.CartAmount),
+		Currency:        req.Currency,
+		CustomerID:      req.CustomerId,
+		CustomerSegment: req.CustomerSegment,
+		CustomerTier:    req.CustomerTier,
+		SalesChannel:    req.Channel,
+	}
+}
+
+// ConvertGetDiscountResponseToDiscount prefers the exact discount, older servers only send the float32 percentage
+func ConvertGetDiscountResponseToDiscount(r *pb.GetDiscountResponse) Discount {
+	exact := r.GetDiscount()
+	if exact == nil {
+		return FromPercentage(r.GetPercentage())
+	}
+
+	d := ConvertExactDiscountToDiscount(exact)
+	d.Raw = r.GetPercentage()
+	return d
+}
+
+func ConvertExactDiscountToDiscount(exact *pb.ExactDiscount) Discount {
+	d := FromBasisPoints(money.BasisPoints(exact.GetBasisPoints()))
+	d.AmountCents = int(exact.GetAmountCents())
+	d.Currency = exact.GetCurrency()
+	return d
+}
@@ -0,0 +1,244 @@
+package discount
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/clock"
+	"github.com/gussf/backend-challenge/src/money"
+)
+
+const ProviderOverride = "override"
+
+// Actions recorded in the audit log of overrides
+const (
+	AuditSet     = "set"
+	AuditClear   = "clear"
+	AuditExpired = "expired"
+)
+
+var (
+	ErrOverrideNotFound = errors.New("discount override not found")
+	ErrInvalidOverride  = errors.New("invalid discount override")
+)
+
+// Override pins the discount of a product until ExpiresAt, whatever the discount service says.
+// AmountCents is a per unit discount in the catalog currency, used instead of BasisPoints when greater than 0
+type Override struct {
+	ProductId   int32
+	BasisPoints money.BasisPoints
+	AmountCents int
+	Reason      string
+	SetBy       string
+	SetAt       time.Time
+	ExpiresAt   time.Time
+}
+
+func (o Override) Validate(now time.Time) error {
+	switch {
+	case o.BasisPoints < 0 || o.BasisPoints > money.OneHundredPercent:
+		return fmt.Errorf("%w: basis points must be between 0 and %d", ErrInvalidOverride, money.OneHundredPercent)
+	case o.AmountCents < 0:
+		return fmt.Errorf("%w: amount can't be negative", ErrInvalidOverride)
+	case o.Reason == "":
+		return fmt.Errorf("%w: reason is required", ErrInvalidOverride)
+	case !o.ExpiresAt.After(now):
+		return fmt.Errorf("%w: expiry must be in the future", ErrInvalidOverride)
+	}
+	return nil
+}
+
+func (o Override) Discount() Discount {
+	d := FromBasisPoints(o.BasisPoints)
+	d.AmountCents = o.AmountCents
+	d.Provider = ProviderOverride
+	d.Reason = o.Reason
+	return d
+}
+
+// AuditEntry is a change to the overrides, Override being the one that was set, cleared or expired
+type AuditEntry struct {
+	At       time.Time
+	Action   string
+	Actor    string
+	Reason   string
+	Override Override
+}
+
+// OverrideStore keeps the overrides in memory, and every change made to them.
+// It is safe for concurrent use
+type OverrideStore struct {
+	mu        sync.Mutex
+	overrides map[int32]Override
+	audit     []AuditEntry
+	clock     clock.Clock
+	// file has the audit log as JSON lines, nil when it is only kept in memory
+	file *os.File
+}
+
+func NewOverrideStore(cl clock.Clock) *OverrideStore {
+	return &OverrideStore{overrides: make(map[int32]Override), clock: cl}
+}
+
+// NewFileOverrideStore appends the audit log to a JSON lines file as well. The overrides are
+// found again by replaying the file when the store is created, it must not be shared between processes
+func NewFileOverrideStore(path string, cl clock.Clock) (*OverrideStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.New("error opening file " + path + ": " + err.Error())
+	}
+
+	s := NewOverrideStore(cl)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var e AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			file.Close()
+			return nil, errors.New("error unmarshalling json: " + err.Error())
+		}
+		s.apply(e)
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, errors.New("error reading file " + path + ": " + err.Error())
+	}
+
+	s.file = file
+	return s, nil
+}
+
+// Set pins the discount of o.ProductId, replacing any override it had
+func (s *OverrideStore) Set(o Override) (Override, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if err := o.Validate(now); err != nil {
+		return o, err
+	}
+	o.SetAt = now
+	return o, s.record(AuditEntry{At: now, Action: AuditSet, Actor: o.SetBy, Reason: o.Reason, Override: o})
+}
+
+// Clear removes the override of productId, reason being why it is no longer needed
+func (s *OverrideStore) Clear(productId int32, actor string, reason string) (Override, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.active(productId)
+	if !ok {
+		return o, ErrOverrideNotFound
+	}
+	return o, s.record(AuditEntry{At: s.clock.Now(), Action: AuditClear, Actor: actor, Reason: reason, Override: o})
+}
+
+// Find returns the override of productId, false when it has none or it expired
+func (s *OverrideStore) Find(productId int32) (Override, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active(productId)
+}
+
+// List returns the overrides that haven't expired, by product id
+func (s *OverrideStore) List() []Override {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Override, 0, len(s.overrides))
+	for id := range s.overrides {
+		if o, ok := s.active(id); ok {
+			list = append(list, o)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ProductId < list[j].ProductId })
+	return list
+}
+
+// Audit returns every change made to the overrides, oldest first
+func (s *OverrideStore) Audit() []AuditEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]AuditEntry(nil), s.audit...)
+}
+
+func (s *OverrideStore) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
+
+// active drops the override of productId once it expires, which is recorded as a change of its own
+func (s *OverrideStore) active(productId int32) (Override, bool) {
+	o, ok := s.overrides[productId]
+	if !ok {
+		return o, false
+	}
+	if s.clock.Now().Before(o.ExpiresAt) {
+		return o, true
+	}
+	if err := s.record(AuditEntry{At: o.ExpiresAt, Action: AuditExpired, Reason: o.Reason, Override: o}); err != nil {
+		// It expires again after a restart, the override is not used meanwhile
+		log.Printf("Failed to record the expiry of the discount override for product=%d: %v", productId, err)
+		delete(s.overrides, productId)
+	}
+	return Override{}, false
+}
+
+// record writes e to the file, when there is one, before applying it
+func (s *OverrideStore) record(e AuditEntry) error {
+	if s.file != nil {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if _, err := s.file.Write(append(line, '\n')); err != nil {
+			return err
+		}
+		if err := s.file.Sync(); err != nil {
+			return err
+		}
+	}
+
+	s.apply(e)
+	o := e.Override
+	log.Printf("Audit: discount override for product=%d %s by %q, %dbp amount=%d until %s: %s",
+		o.ProductId, e.Action, e.Actor, o.BasisPoints, o.AmountCents, o.ExpiresAt.Format(time.RFC3339), e.Reason)
+	return nil
+}
+
+// apply makes the change of e to the overrides and keeps it in the audit log
+func (s *OverrideStore) apply(e AuditEntry) {
+	if e.Action == AuditSet {
+		s.overrides[e.Override.ProductId] = e.Override
+	} else {
+		delete(s.overrides, e.Override.ProductId)
+	}
+	s.audit = append(s.audit, e)
+}
+
+// OverridingDiscountService answers with the override of the product when it has one,
+// and only asks next for products that don't
+type OverridingDiscountService struct {
+	next  DiscountService
+	store *OverrideStore
+}
+
+func NewOverridingDiscountService(next DiscountService, store *OverrideStore) OverridingDiscountService {
+	return OverridingDiscountService{next: next, store: store}
+}
+
+func (svc OverridingDiscountService) GetDiscountForProduct(req Request) Discount {
+	if o, ok := svc.store.Find(req.ProductId); ok {
+		log.Printf("Discount of product=%d overridden to %dbp amount=%d: %s", req.ProductId, o.BasisPoints, o.AmountCents, o.Reason)
+		return o.Discount()
+	}
+	return svc.next.GetDiscountForProduct(req)
+}
@@ -0,0 +1,116 @@
+package discount
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/clock"
+)
+
+func TestOverridingDiscountService(t *testing.T) {
+	cl := &clock.MovableClock{T: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}
+	store := NewOverrideStore(cl)
+	unary := &CountingDiscountService{}
+	svc := NewOverridingDiscountService(unary, store)
+
+	_, err := store.Set(Override{ProductId: 1, BasisPoints: 0, Reason: "mispriced", SetBy: "ops", ExpiresAt: cl.T.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("Failed to set override: %v", err)
+	}
+
+	d := svc.GetDiscountForProduct(Request{ProductId: 1})
+	if d.Provider != ProviderOverride || d.BasisPoints != 0 || d.Reason != "mispriced" || unary.calls != 0 {
+		t.Errorf("Override should take precedence: got=%+v, calls=%d", d, unary.calls)
+	}
+	if d := svc.GetDiscountForProduct(Request{ProductId: 2}); d.Provider != ProviderGRPC || unary.calls != 1 {
+		t.Errorf("Product without override should ask the service: got=%+v, calls=%d", d, unary.calls)
+	}
+
+	cl.T = cl.T.Add(time.Hour)
+	if d := svc.GetDiscountForProduct(Request{ProductId: 1}); d.Provider != ProviderGRPC || unary.calls != 2 {
+		t.Errorf("Expired override should not be used: got=%+v, calls=%d", d, unary.calls)
+	}
+
+	store.Set(Override{ProductId: 3, BasisPoints: 500, Reason: "flash sale", SetBy: "ops", ExpiresAt: cl.T.Add(time.Hour)})
+	if _, err := store.Clear(3, "ops", "sale ended"); err != nil {
+		t.Errorf("Failed to clear override: %v", err)
+	}
+	if _, err := store.Clear(3, "ops", "again"); !errors.Is(err, ErrOverrideNotFound) {
+		t.Errorf("Incorrect error clearing a cleared override: want=%v, got=%v", ErrOverrideNotFound, err)
+	}
+	if len(store.List()) != 0 {
+		t.Errorf("Incorrect overrides left: want=0, got=%d", len(store.List()))
+	}
+
+	wantActions := []string{AuditSet, AuditExpired, AuditSet, AuditClear}
+	audit := store.Audit()
+	if len(audit) != len(wantActions) {
+		t.Fatalf("Incorrect audit entries: want=%d, got=%d", len(wantActions), len(audit))
+	}
+	for i, want := range wantActions {
+		if audit[i].Action != want {
+			t.Errorf("Incorrect action of audit entry %d: want=%s, got=%s", i, want, audit[i].Action)
+		}
+	}
+	if audit[3].Reason != "sale ended" || audit[3].Actor != "ops" {
+		t.Errorf("Clear should be audited with its reason and actor: got=%+v", audit[3])
+	}
+	if !audit[1].At.Equal(audit[0].Override.ExpiresAt) {
+		t.Errorf("Expiry should be audited when it happened: want=%s, got=%s", audit[0].Override.ExpiresAt, audit[1].At)
+	}
+}
+
+func TestFileOverrideStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.jsonl")
+	cl := &clock.MovableClock{T: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}
+
+	store, err := NewFileOverrideStore(path, cl)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	store.Set(Override{ProductId: 1, BasisPoints: 0, Reason: "mispriced", SetBy: "ops", ExpiresAt: cl.T.Add(time.Hour)})
+	store.Set(Override{ProductId: 2, BasisPoints: 500, Reason: "flash sale", SetBy: "ops", ExpiresAt: cl.T.Add(time.Hour)})
+	store.Clear(2, "ops", "sale ended")
+	store.Close()
+
+	store, err = NewFileOverrideStore(path, cl)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer store.Close()
+
+	if o, ok := store.Find(1); !ok || o.Reason != "mispriced" || o.SetBy != "ops" {
+		t.Errorf("Incorrect reloaded override: got=%+v, found=%t", o, ok)
+	}
+	if _, ok := store.Find(2); ok {
+		t.Errorf("Cleared override should not be reloaded")
+	}
+	if len(store.Audit()) != 3 {
+		t.Errorf("Incorrect reloaded audit entries: want=%d, got=%d", 3, len(store.Audit()))
+	}
+}
+
+func TestOverrideValidate(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		override Override
+		wantErr  bool
+	}{
+		{name: "Valid override", override: Override{BasisPoints: 0, Reason: "incident", ExpiresAt: now.Add(time.Minute)}},
+		{name: "Above 100%", override: Override{BasisPoints: 10001, Reason: "incident", ExpiresAt: now.Add(time.Minute)}, wantErr: true},
+		{name: "Negative amount", override: Override{AmountCents: -1, Reason: "incident", ExpiresAt: now.Add(time.Minute)}, wantErr: true},
+		{name: "Without reason", override: Override{ExpiresAt: now.Add(time.Minute)}, wantErr: true},
+		{name: "Already expired", override: Override{Reason: "incident", ExpiresAt: now}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := tt.override.Validate(now)
+		if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrInvalidOverride)) {
+			t.Errorf("%s: Incorrect error: want error=%t, got=%v", tt.name, tt.wantErr, err)
+		}
+	}
+}
@@ -21,12 +21,23 @@ const (
 )
 
 // productID used to represent a product. Ilustrative only.
+// Every other field is context of the checkout the product is in, which servers may ignore.
+// They are optional: 0 and "" mean the client didn't send them.
+// Amounts are in cents of currency, cartTotalCents being the cart before any discount.
 type GetDiscountRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	ProductID int32 `protobuf:"varint,1,opt,name=productID,proto3" json:"productID,omitempty"`
+	ProductID       int32  `protobuf:"varint,1,opt,name=productID,proto3" json:"productID,omitempty"`
+	Quantity        int32  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	UnitPriceCents  int64  `protobuf:"varint,3,opt,name=unitPriceCents,proto3" json:"unitPriceCents,omitempty"`
+	CartTotalCents  int64  `protobuf:"varint,4,opt,name=cartTotalCents,proto3" json:"cartTotalCents,omitempty"`
+	Currency        string `protobuf:"bytes,5,opt,name=currency,proto3" json:"currency,omitempty"`
+	CustomerID      string `protobuf:"bytes,6,opt,name=customerID,proto3" json:"customerID,omitempty"`
+	CustomerSegment string `protobuf:"bytes,7,opt,name=customerSegment,proto3" json:"customerSegment,omitempty"`
+	CustomerTier    string `protobuf:"bytes,8,opt,name=customerTier,proto3" json:"customerTier,omitempty"`
+	SalesChannel    string `protobuf:"bytes,9,opt,name=salesChannel,proto3" json:"salesChannel,omitempty"`
 }
 
 func (x *GetDiscountRequest) Reset() {
@@ -68,13 +79,71 @@ func (x *GetDiscountRequest) GetProductID() int32 {
 	return 0
 }
 
+func (x *GetDiscountRequest) GetQuantity() int32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *GetDiscountRequest) GetUnitPriceCents() int64 {
+	if x != nil {
+		return x.UnitPriceCents
+	}
+	return 0
+}
+
+func (x *GetDiscountRequest) GetCartTotalCents() int64 {
+	if x != nil {
+		return x.CartTotalCents
+	}
+	return 0
+}
+
+func (x *GetDiscountRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *GetDiscountRequest) GetCustomerID() string {
+	if x != nil {
+		return x.CustomerID
+	}
+	return ""
+}
+
+func (x *GetDiscountRequest) GetCustomerSegment() string {
+	if x != nil {
+		return x.CustomerSegment
+	}
+	return ""
+}
+
+func (x *GetDiscountRequest) GetCustomerTier() string {
+	if x != nil {
+		return x.CustomerTier
+	}
+	return ""
+}
+
+func (x *GetDiscountRequest) GetSalesChannel() string {
+	if x != nil {
+		return x.SalesChannel
+	}
+	return ""
+}
+
 // The discount percentage is a fixed value.
+// percentage is kept for older clients, newer servers should also fill discount.
 type GetDiscountResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Percentage float32 `protobuf:"fixed32,1,opt,name=percentage,proto3" json:"percentage,omitempty"`
+	Percentage float32        `protobuf:"fixed32,1,opt,name=percentage,proto3" json:"percentage,omitempty"`
+	Discount   *ExactDiscount `protobuf:"bytes,2,opt,name=discount,proto3" json:"discount,omitempty"`
 }
 
 func (x *GetDiscountResponse) Reset() {
@@ -116,27 +185,608 @@ func (x *GetDiscountResponse) GetPercentage() float32 {
 	return 0
 }
 
+func (x *GetDiscountResponse) GetDiscount() *ExactDiscount {
+	if x != nil {
+		return x.Discount
+	}
+	return nil
+}
+
+// Exact representation of a discount, preferred over percentage when present.
+// amountCents is an absolute discount per unit in currency, 0 meaning none.
+type ExactDiscount struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	BasisPoints int32  `protobuf:"varint,1,opt,name=basisPoints,proto3" json:"basisPoints,omitempty"`
+	AmountCents int64  `protobuf:"varint,2,opt,name=amountCents,proto3" json:"amountCents,omitempty"`
+	Currency    string `protobuf:"bytes,3,opt,name=currency,proto3" json:"currency,omitempty"`
+}
+
+func (x *ExactDiscount) Reset() {
+	*x = ExactDiscount{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_pb_discount_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ExactDiscount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExactDiscount) ProtoMessage() {}
+
+func (x *ExactDiscount) ProtoReflect() protoreflect.Message {
+	mi := &file_src_pb_discount_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExactDiscount.ProtoReflect.Descriptor instead.
+func (*ExactDiscount) Descriptor() ([]byte, []int) {
+	return file_src_pb_discount_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ExactDiscount) GetBasisPoints() int32 {
+	if x != nil {
+		return x.BasisPoints
+	}
+	return 0
+}
+
+func (x *ExactDiscount) GetAmountCents() int64 {
+	if x != nil {
+		return x.AmountCents
+	}
+	return 0
+}
+
+func (x *ExactDiscount) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+// A line of the priced cart, amounts are in cents of the cart currency.
+// discountCents is every discount the line already got.
+type CartLine struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ProductID      int32 `protobuf:"varint,1,opt,name=productID,proto3" json:"productID,omitempty"`
+	Quantity       int32 `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	UnitPriceCents int64 `protobuf:"varint,3,opt,name=unitPriceCents,proto3" json:"unitPriceCents,omitempty"`
+	TotalCents     int64 `protobuf:"varint,4,opt,name=totalCents,proto3" json:"totalCents,omitempty"`
+	DiscountCents  int64 `protobuf:"varint,5,opt,name=discountCents,proto3" json:"discountCents,omitempty"`
+	IsGift         bool  `protobuf:"varint,6,opt,name=isGift,proto3" json:"isGift,omitempty"`
+}
+
+func (x *CartLine) Reset() {
+	*x = CartLine{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_pb_discount_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CartLine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CartLine) ProtoMessage() {}
+
+func (x *CartLine) ProtoReflect() protoreflect.Message {
+	mi := &file_src_pb_discount_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CartLine.ProtoReflect.Descriptor instead.
+func (*CartLine) Descriptor() ([]byte, []int) {
+	return file_src_pb_discount_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CartLine) GetProductID() int32 {
+	if x != nil {
+		return x.ProductID
+	}
+	return 0
+}
+
+func (x *CartLine) GetQuantity() int32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *CartLine) GetUnitPriceCents() int64 {
+	if x != nil {
+		return x.UnitPriceCents
+	}
+	return 0
+}
+
+func (x *CartLine) GetTotalCents() int64 {
+	if x != nil {
+		return x.TotalCents
+	}
+	return 0
+}
+
+func (x *CartLine) GetDiscountCents() int64 {
+	if x != nil {
+		return x.DiscountCents
+	}
+	return 0
+}
+
+func (x *CartLine) GetIsGift() bool {
+	if x != nil {
+		return x.IsGift
+	}
+	return false
+}
+
+// The cart priced with every product discount, coupon and promotion.
+// totalCents is before any discount, discountCents what was discounted from it so far.
+type GetCartDiscountRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Lines           []*CartLine `protobuf:"bytes,1,rep,name=lines,proto3" json:"lines,omitempty"`
+	TotalCents      int64       `protobuf:"varint,2,opt,name=totalCents,proto3" json:"totalCents,omitempty"`
+	DiscountCents   int64       `protobuf:"varint,3,opt,name=discountCents,proto3" json:"discountCents,omitempty"`
+	Currency        string      `protobuf:"bytes,4,opt,name=currency,proto3" json:"currency,omitempty"`
+	CustomerID      string      `protobuf:"bytes,5,opt,name=customerID,proto3" json:"customerID,omitempty"`
+	CustomerSegment string      `protobuf:"bytes,6,opt,name=customerSegment,proto3" json:"customerSegment,omitempty"`
+	CustomerTier    string      `protobuf:"bytes,7,opt,name=customerTier,proto3" json:"customerTier,omitempty"`
+	SalesChannel    string      `protobuf:"bytes,8,opt,name=salesChannel,proto3" json:"salesChannel,omitempty"`
+}
+
+func (x *GetCartDiscountRequest) Reset() {
+	*x = GetCartDiscountRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_pb_discount_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetCartDiscountRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCartDiscountRequest) ProtoMessage() {}
+
+func (x *GetCartDiscountRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_src_pb_discount_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCartDiscountRequest.ProtoReflect.Descriptor instead.
+func (*GetCartDiscountRequest) Descriptor() ([]byte, []int) {
+	return file_src_pb_discount_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetCartDiscountRequest) GetLines() []*CartLine {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+func (x *GetCartDiscountRequest) GetTotalCents() int64 {
+	if x != nil {
+		return x.TotalCents
+	}
+	return 0
+}
+
+func (x *GetCartDiscountRequest) GetDiscountCents() int64 {
+	if x != nil {
+		return x.DiscountCents
+	}
+	return 0
+}
+
+func (x *GetCartDiscountRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *GetCartDiscountRequest) GetCustomerID() string {
+	if x != nil {
+		return x.CustomerID
+	}
+	return ""
+}
+
+func (x *GetCartDiscountRequest) GetCustomerSegment() string {
+	if x != nil {
+		return x.CustomerSegment
+	}
+	return ""
+}
+
+func (x *GetCartDiscountRequest) GetCustomerTier() string {
+	if x != nil {
+		return x.CustomerTier
+	}
+	return ""
+}
+
+func (x *GetCartDiscountRequest) GetSalesChannel() string {
+	if x != nil {
+		return x.SalesChannel
+	}
+	return ""
+}
+
+// An order level discount on what is left to pay for the cart, either basisPoints
+// or amountCents. No discount means the cart doesn't get one.
+// id identifies the discount, such as the campaign, and reason explains it to customers.
+type GetCartDiscountResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Discount *ExactDiscount `protobuf:"bytes,1,opt,name=discount,proto3" json:"discount,omitempty"`
+	Id       string         `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Reason   string         `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (x *GetCartDiscountResponse) Reset() {
+	*x = GetCartDiscountResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_pb_discount_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetCartDiscountResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCartDiscountResponse) ProtoMessage() {}
+
+func (x *GetCartDiscountResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_src_pb_discount_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCartDiscountResponse.ProtoReflect.Descriptor instead.
+func (*GetCartDiscountResponse) Descriptor() ([]byte, []int) {
+	return file_src_pb_discount_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetCartDiscountResponse) GetDiscount() *ExactDiscount {
+	if x != nil {
+		return x.Discount
+	}
+	return nil
+}
+
+func (x *GetCartDiscountResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GetCartDiscountResponse) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// sinceVersion is the last version the client has seen, 0 asking for every discount.
+type WatchDiscountsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SinceVersion int64 `protobuf:"varint,1,opt,name=sinceVersion,proto3" json:"sinceVersion,omitempty"`
+}
+
+func (x *WatchDiscountsRequest) Reset() {
+	*x = WatchDiscountsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_pb_discount_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchDiscountsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchDiscountsRequest) ProtoMessage() {}
+
+func (x *WatchDiscountsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_src_pb_discount_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchDiscountsRequest.ProtoReflect.Descriptor instead.
+func (*WatchDiscountsRequest) Descriptor() ([]byte, []int) {
+	return file_src_pb_discount_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *WatchDiscountsRequest) GetSinceVersion() int64 {
+	if x != nil {
+		return x.SinceVersion
+	}
+	return 0
+}
+
+// A change to the discount of productID, each one with a greater version than the previous.
+// The stream has every discount: products that were never streamed, or were removed, have none.
+// contextual means the discount of productID varies with the checkout context (quantity, cart total,
+// customer or channel), so the client asks for it with GetDiscount instead of using the stream.
+// resync means the server can't resume from sinceVersion: the client drops what it has and this is the first update of every discount.
+// snapshotComplete is sent once every discount was sent after sinceVersion 0 or a resync, products the client
+// has no discount for only have none from then on.
+// Updates with productID 0 are heartbeats, they only tell the stream is alive.
+type DiscountUpdate struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Version          int64          `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	ProductID        int32          `protobuf:"varint,2,opt,name=productID,proto3" json:"productID,omitempty"`
+	Discount         *ExactDiscount `protobuf:"bytes,3,opt,name=discount,proto3" json:"discount,omitempty"`
+	Removed          bool           `protobuf:"varint,4,opt,name=removed,proto3" json:"removed,omitempty"`
+	Resync           bool           `protobuf:"varint,5,opt,name=resync,proto3" json:"resync,omitempty"`
+	Contextual       bool           `protobuf:"varint,6,opt,name=contextual,proto3" json:"contextual,omitempty"`
+	SnapshotComplete bool           `protobuf:"varint,7,opt,name=snapshotComplete,proto3" json:"snapshotComplete,omitempty"`
+}
+
+func (x *DiscountUpdate) Reset() {
+	*x = DiscountUpdate{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_pb_discount_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DiscountUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiscountUpdate) ProtoMessage() {}
+
+func (x *DiscountUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_src_pb_discount_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiscountUpdate.ProtoReflect.Descriptor instead.
+func (*DiscountUpdate) Descriptor() ([]byte, []int) {
+	return file_src_pb_discount_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DiscountUpdate) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *DiscountUpdate) GetProductID() int32 {
+	if x != nil {
+		return x.ProductID
+	}
+	return 0
+}
+
+func (x *DiscountUpdate) GetDiscount() *ExactDiscount {
+	if x != nil {
+		return x.Discount
+	}
+	return nil
+}
+
+func (x *DiscountUpdate) GetRemoved() bool {
+	if x != nil {
+		return x.Removed
+	}
+	return false
+}
+
+func (x *DiscountUpdate) GetResync() bool {
+	if x != nil {
+		return x.Resync
+	}
+	return false
+}
+
+func (x *DiscountUpdate) GetContextual() bool {
+	if x != nil {
+		return x.Contextual
+	}
+	return false
+}
+
+func (x *DiscountUpdate) GetSnapshotComplete() bool {
+	if x != nil {
+		return x.SnapshotComplete
+	}
+	return false
+}
+
 var File_src_pb_discount_proto protoreflect.FileDescriptor
 
 var file_src_pb_discount_proto_rawDesc = []byte{
 	0x0a, 0x15, 0x73, 0x72, 0x63, 0x2f, 0x70, 0x62, 0x2f, 0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e,
 	0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x08, 0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e,
-	0x74, 0x22, 0x32, 0x0a, 0x12, 0x47, 0x65, 0x74, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74,
-	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x72, 0x6f, 0x64, 0x75,
-	0x63, 0x74, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x70, 0x72, 0x6f, 0x64,
-	0x75, 0x63, 0x74, 0x49, 0x44, 0x22, 0x35, 0x0a, 0x13, 0x47, 0x65, 0x74, 0x44, 0x69, 0x73, 0x63,
-	0x6f, 0x75, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1e, 0x0a, 0x0a,
-	0x70, 0x65, 0x72, 0x63, 0x65, 0x6e, 0x74, 0x61, 0x67, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x02,
-	0x52, 0x0a, 0x70, 0x65, 0x72, 0x63, 0x65, 0x6e, 0x74, 0x61, 0x67, 0x65, 0x32, 0x58, 0x0a, 0x08,
-	0x44, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x4c, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x44,
-	0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x1c, 0x2e, 0x64, 0x69, 0x73, 0x63, 0x6f, 0x75,
-	0x6e, 0x74, 0x2e, 0x47, 0x65, 0x74, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74,
-	0x2e, 0x47, 0x65, 0x74, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x70,
-	0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x27, 0x5a, 0x25, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
-	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x75, 0x73, 0x73, 0x66, 0x2f, 0x62, 0x61, 0x63, 0x6b, 0x65,
-	0x6e, 0x64, 0x2d, 0x63, 0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65, 0x2f, 0x70, 0x62, 0x62,
-	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x74, 0x22, 0xcc, 0x02, 0x0a, 0x12, 0x47, 0x65, 0x74, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x72, 0x6f, 0x64,
+	0x75, 0x63, 0x74, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x70, 0x72, 0x6f,
+	0x64, 0x75, 0x63, 0x74, 0x49, 0x44, 0x12, 0x1a, 0x0a, 0x08, 0x71, 0x75, 0x61, 0x6e, 0x74, 0x69,
+	0x74, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x71, 0x75, 0x61, 0x6e, 0x74, 0x69,
+	0x74, 0x79, 0x12, 0x26, 0x0a, 0x0e, 0x75, 0x6e, 0x69, 0x74, 0x50, 0x72, 0x69, 0x63, 0x65, 0x43,
+	0x65, 0x6e, 0x74, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0e, 0x75, 0x6e, 0x69, 0x74,
+	0x50, 0x72, 0x69, 0x63, 0x65, 0x43, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x26, 0x0a, 0x0e, 0x63, 0x61,
+	0x72, 0x74, 0x54, 0x6f, 0x74, 0x61, 0x6c, 0x43, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x0e, 0x63, 0x61, 0x72, 0x74, 0x54, 0x6f, 0x74, 0x61, 0x6c, 0x43, 0x65, 0x6e,
+	0x74, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x12, 0x1e,
+	0x0a, 0x0a, 0x63, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x65, 0x72, 0x49, 0x44, 0x18, 0x06, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0a, 0x63, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x65, 0x72, 0x49, 0x44, 0x12, 0x28,
+	0x0a, 0x0f, 0x63, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x65, 0x72, 0x53, 0x65, 0x67, 0x6d, 0x65, 0x6e,
+	0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x63, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x65,
+	0x72, 0x53, 0x65, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x22, 0x0a, 0x0c, 0x63, 0x75, 0x73, 0x74,
+	0x6f, 0x6d, 0x65, 0x72, 0x54, 0x69, 0x65, 0x72, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c,
+	0x63, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x65, 0x72, 0x54, 0x69, 0x65, 0x72, 0x12, 0x22, 0x0a, 0x0c,
+	0x73, 0x61, 0x6c, 0x65, 0x73, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x18, 0x09, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0c, 0x73, 0x61, 0x6c, 0x65, 0x73, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c,
+	0x22, 0x6a, 0x0a, 0x13, 0x47, 0x65, 0x74, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x70, 0x65, 0x72, 0x63, 0x65,
+	0x6e, 0x74, 0x61, 0x67, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x02, 0x52, 0x0a, 0x70, 0x65, 0x72,
+	0x63, 0x65, 0x6e, 0x74, 0x61, 0x67, 0x65, 0x12, 0x33, 0x0a, 0x08, 0x64, 0x69, 0x73, 0x63, 0x6f,
+	0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x64, 0x69, 0x73, 0x63,
+	0x6f, 0x75, 0x6e, 0x74, 0x2e, 0x45, 0x78, 0x61, 0x63, 0x74, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x75,
+	0x6e, 0x74, 0x52, 0x08, 0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x6f, 0x0a, 0x0d,
+	0x45, 0x78, 0x61, 0x63, 0x74, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x20, 0x0a,
+	0x0b, 0x62, 0x61, 0x73, 0x69, 0x73, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x0b, 0x62, 0x61, 0x73, 0x69, 0x73, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x73, 0x12,
+	0x20, 0x0a, 0x0b, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x43, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x43, 0x65, 0x6e, 0x74,
+	0x73, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x22, 0xca, 0x01,
+	0x0a, 0x08, 0x43, 0x61, 0x72, 0x74, 0x4c, 0x69, 0x6e, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x72,
+	0x6f, 0x64, 0x75, 0x63, 0x74, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x70,
+	0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x49, 0x44, 0x12, 0x1a, 0x0a, 0x08, 0x71, 0x75, 0x61, 0x6e,
+	0x74, 0x69, 0x74, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x71, 0x75, 0x61, 0x6e,
+	0x74, 0x69, 0x74, 0x79, 0x12, 0x26, 0x0a, 0x0e, 0x75, 0x6e, 0x69, 0x74, 0x50, 0x72, 0x69, 0x63,
+	0x65, 0x43, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0e, 0x75, 0x6e,
+	0x69, 0x74, 0x50, 0x72, 0x69, 0x63, 0x65, 0x43, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x1e, 0x0a, 0x0a,
+	0x74, 0x6f, 0x74, 0x61, 0x6c, 0x43, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0a, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x43, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x24, 0x0a, 0x0d,
+	0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x43, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x0d, 0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x43, 0x65, 0x6e,
+	0x74, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x69, 0x73, 0x47, 0x69, 0x66, 0x74, 0x18, 0x06, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x06, 0x69, 0x73, 0x47, 0x69, 0x66, 0x74, 0x22, 0xb6, 0x02, 0x0a, 0x16, 0x47,
+	0x65, 0x74, 0x43, 0x61, 0x72, 0x74, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x28, 0x0a, 0x05, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x2e,
+	0x43, 0x61, 0x72, 0x74, 0x4c, 0x69, 0x6e, 0x65, 0x52, 0x05, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x12,
+	0x1e, 0x0a, 0x0a, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x43, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x0a, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x43, 0x65, 0x6e, 0x74, 0x73, 0x12,
+	0x24, 0x0a, 0x0d, 0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x43, 0x65, 0x6e, 0x74, 0x73,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74,
+	0x43, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63,
+	0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63,
+	0x79, 0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x65, 0x72, 0x49, 0x44, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x63, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x65, 0x72, 0x49,
+	0x44, 0x12, 0x28, 0x0a, 0x0f, 0x63, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x65, 0x72, 0x53, 0x65, 0x67,
+	0x6d, 0x65, 0x6e, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x63, 0x75, 0x73, 0x74,
+	0x6f, 0x6d, 0x65, 0x72, 0x53, 0x65, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x22, 0x0a, 0x0c, 0x63,
+	0x75, 0x73, 0x74, 0x6f, 0x6d, 0x65, 0x72, 0x54, 0x69, 0x65, 0x72, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0c, 0x63, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x65, 0x72, 0x54, 0x69, 0x65, 0x72, 0x12,
+	0x22, 0x0a, 0x0c, 0x73, 0x61, 0x6c, 0x65, 0x73, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x18,
+	0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x73, 0x61, 0x6c, 0x65, 0x73, 0x43, 0x68, 0x61, 0x6e,
+	0x6e, 0x65, 0x6c, 0x22, 0x76, 0x0a, 0x17, 0x47, 0x65, 0x74, 0x43, 0x61, 0x72, 0x74, 0x44, 0x69,
+	0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x33,
+	0x0a, 0x08, 0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x17, 0x2e, 0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x2e, 0x45, 0x78, 0x61, 0x63,
+	0x74, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x52, 0x08, 0x64, 0x69, 0x73, 0x63, 0x6f,
+	0x75, 0x6e, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x02, 0x69, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x22, 0x3b, 0x0a, 0x15, 0x57,
+	0x61, 0x74, 0x63, 0x68, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x22, 0x0a, 0x0c, 0x73, 0x69, 0x6e, 0x63, 0x65, 0x56, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c, 0x73, 0x69, 0x6e, 0x63,
+	0x65, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0xfb, 0x01, 0x0a, 0x0e, 0x44, 0x69, 0x73,
+	0x63, 0x6f, 0x75, 0x6e, 0x74, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x76,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74,
+	0x49, 0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63,
+	0x74, 0x49, 0x44, 0x12, 0x33, 0x0a, 0x08, 0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74,
+	0x2e, 0x45, 0x78, 0x61, 0x63, 0x74, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x52, 0x08,
+	0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x72, 0x65, 0x6d, 0x6f,
+	0x76, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x72, 0x65, 0x6d, 0x6f, 0x76,
+	0x65, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x73, 0x79, 0x6e, 0x63, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x06, 0x72, 0x65, 0x73, 0x79, 0x6e, 0x63, 0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x6f,
+	0x6e, 0x74, 0x65, 0x78, 0x74, 0x75, 0x61, 0x6c, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a,
+	0x63, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x75, 0x61, 0x6c, 0x12, 0x2a, 0x0a, 0x10, 0x73, 0x6e,
+	0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x43, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x18, 0x07,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x10, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x43, 0x6f,
+	0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x32, 0x83, 0x02, 0x0a, 0x08, 0x44, 0x69, 0x73, 0x63, 0x6f,
+	0x75, 0x6e, 0x74, 0x12, 0x4c, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x75,
+	0x6e, 0x74, 0x12, 0x1c, 0x2e, 0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x2e, 0x47, 0x65,
+	0x74, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x1d, 0x2e, 0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x2e, 0x47, 0x65, 0x74, 0x44,
+	0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22,
+	0x00, 0x12, 0x58, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x43, 0x61, 0x72, 0x74, 0x44, 0x69, 0x73, 0x63,
+	0x6f, 0x75, 0x6e, 0x74, 0x12, 0x20, 0x2e, 0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x2e,
+	0x47, 0x65, 0x74, 0x43, 0x61, 0x72, 0x74, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x21, 0x2e, 0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e,
+	0x74, 0x2e, 0x47, 0x65, 0x74, 0x43, 0x61, 0x72, 0x74, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e,
+	0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x4f, 0x0a, 0x0e, 0x57,
+	0x61, 0x74, 0x63, 0x68, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x73, 0x12, 0x1f, 0x2e,
+	0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x44, 0x69,
+	0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18,
+	0x2e, 0x64, 0x69, 0x73, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x2e, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x75,
+	0x6e, 0x74, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x22, 0x00, 0x30, 0x01, 0x42, 0x27, 0x5a, 0x25,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x75, 0x73, 0x73, 0x66,
+	0x2f, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x2d, 0x63, 0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e,
+	0x67, 0x65, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -151,19 +801,33 @@ func file_src_pb_discount_proto_rawDescGZIP() []byte {
 	return file_src_pb_discount_proto_rawDescData
 }
 
-var file_src_pb_discount_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_src_pb_discount_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
 var file_src_pb_discount_proto_goTypes = []interface{}{
-	(*GetDiscountRequest)(nil),  // 0: discount.GetDiscountRequest
-	(*GetDiscountResponse)(nil), // 1: discount.GetDiscountResponse
+	(*GetDiscountRequest)(nil),      // 0: discount.GetDiscountRequest
+	(*GetDiscountResponse)(nil),     // 1: discount.GetDiscountResponse
+	(*ExactDiscount)(nil),           // 2: discount.ExactDiscount
+	(*CartLine)(nil),                // 3: discount.CartLine
+	(*GetCartDiscountRequest)(nil),  // 4: discount.GetCartDiscountRequest
+	(*GetCartDiscountResponse)(nil), // 5: discount.GetCartDiscountResponse
+	(*WatchDiscountsRequest)(nil),   // 6: discount.WatchDiscountsRequest
+	(*DiscountUpdate)(nil),          // 7: discount.DiscountUpdate
 }
 var file_src_pb_discount_proto_depIdxs = []int32{
-	0, // 0: discount.Discount.GetDiscount:input_type -> discount.GetDiscountRequest
-	1, // 1: discount.Discount.GetDiscount:output_type -> discount.GetDiscountResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	2, // 0: discount.GetDiscountResponse.discount:type_name -> discount.ExactDiscount
+	3, // 1: discount.GetCartDiscountRequest.lines:type_name -> discount.CartLine
+	2, // 2: discount.GetCartDiscountResponse.discount:type_name -> discount.ExactDiscount
+	2, // 3: discount.DiscountUpdate.discount:type_name -> discount.ExactDiscount
+	0, // 4: discount.Discount.GetDiscount:input_type -> discount.GetDiscountRequest
+	4, // 5: discount.Discount.GetCartDiscount:input_type -> discount.GetCartDiscountRequest
+	6, // 6: discount.Discount.WatchDiscounts:input_type -> discount.WatchDiscountsRequest
+	1, // 7: discount.Discount.GetDiscount:output_type -> discount.GetDiscountResponse
+	5, // 8: discount.Discount.GetCartDiscount:output_type -> discount.GetCartDiscountResponse
+	7, // 9: discount.Discount.WatchDiscounts:output_type -> discount.DiscountUpdate
+	7, // [7:10] is the sub-list for method output_type
+	4, // [4:7] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_src_pb_discount_proto_init() }
@@ -196,6 +860,78 @@ func file_src_pb_discount_proto_init() {
 				return nil
 			}
 		}
+		file_src_pb_discount_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ExactDiscount); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_pb_discount_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CartLine); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_pb_discount_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetCartDiscountRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_pb_discount_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetCartDiscountResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_pb_discount_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchDiscountsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_pb_discount_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DiscountUpdate); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -203,7 +939,7 @@ func file_src_pb_discount_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_src_pb_discount_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   8,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
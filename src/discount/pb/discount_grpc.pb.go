@@ -19,6 +19,8 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type DiscountClient interface {
 	GetDiscount(ctx context.Context, in *GetDiscountRequest, opts ...grpc.CallOption) (*GetDiscountResponse, error)
+	GetCartDiscount(ctx context.Context, in *GetCartDiscountRequest, opts ...grpc.CallOption) (*GetCartDiscountResponse, error)
+	WatchDiscounts(ctx context.Context, in *WatchDiscountsRequest, opts ...grpc.CallOption) (Discount_WatchDiscountsClient, error)
 }
 
 type discountClient struct {
@@ -38,11 +40,54 @@ func (c *discountClient) GetDiscount(ctx context.Context, in *GetDiscountRequest
 	return out, nil
 }
 
+func (c *discountClient) GetCartDiscount(ctx context.Context, in *GetCartDiscountRequest, opts ...grpc.CallOption) (*GetCartDiscountResponse, error) {
+	out := new(GetCartDiscountResponse)
+	err := c.cc.Invoke(ctx, "/discount.Discount/GetCartDiscount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *discountClient) WatchDiscounts(ctx context.Context, in *WatchDiscountsRequest, opts ...grpc.CallOption) (Discount_WatchDiscountsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Discount_ServiceDesc.Streams[0], "/discount.Discount/WatchDiscounts", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &discountWatchDiscountsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Discount_WatchDiscountsClient interface {
+	Recv() (*DiscountUpdate, error)
+	grpc.ClientStream
+}
+
+type discountWatchDiscountsClient struct {
+	grpc.ClientStream
+}
+
+func (x *discountWatchDiscountsClient) Recv() (*DiscountUpdate, error) {
+	m := new(DiscountUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // DiscountServer is the server API for Discount service.
 // All implementations must embed UnimplementedDiscountServer
 // for forward compatibility
 type DiscountServer interface {
 	GetDiscount(context.Context, *GetDiscountRequest) (*GetDiscountResponse, error)
+	GetCartDiscount(context.Context, *GetCartDiscountRequest) (*GetCartDiscountResponse, error)
+	WatchDiscounts(*WatchDiscountsRequest, Discount_WatchDiscountsServer) error
 	mustEmbedUnimplementedDiscountServer()
 }
 
@@ -53,6 +98,12 @@ type UnimplementedDiscountServer struct {
 func (UnimplementedDiscountServer) GetDiscount(context.Context, *GetDiscountRequest) (*GetDiscountResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetDiscount not implemented")
 }
+func (UnimplementedDiscountServer) GetCartDiscount(context.Context, *GetCartDiscountRequest) (*GetCartDiscountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCartDiscount not implemented")
+}
+func (UnimplementedDiscountServer) WatchDiscounts(*WatchDiscountsRequest, Discount_WatchDiscountsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchDiscounts not implemented")
+}
 func (UnimplementedDiscountServer) mustEmbedUnimplementedDiscountServer() {}
 
 // UnsafeDiscountServer may be embedded to opt out of forward compatibility for this service.
@@ -84,6 +135,45 @@ func _Discount_GetDiscount_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Discount_GetCartDiscount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCartDiscountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DiscountServer).GetCartDiscount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/discount.Discount/GetCartDiscount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DiscountServer).GetCartDiscount(ctx, req.(*GetCartDiscountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Discount_WatchDiscounts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchDiscountsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DiscountServer).WatchDiscounts(m, &discountWatchDiscountsServer{stream})
+}
+
+type Discount_WatchDiscountsServer interface {
+	Send(*DiscountUpdate) error
+	grpc.ServerStream
+}
+
+type discountWatchDiscountsServer struct {
+	grpc.ServerStream
+}
+
+func (x *discountWatchDiscountsServer) Send(m *DiscountUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // Discount_ServiceDesc is the grpc.ServiceDesc for Discount service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -95,7 +185,17 @@ var Discount_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetDiscount",
 			Handler:    _Discount_GetDiscount_Handler,
 		},
+		{
+			MethodName: "GetCartDiscount",
+			Handler:    _Discount_GetCartDiscount_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchDiscounts",
+			Handler:       _Discount_WatchDiscounts_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "src/pb/discount.proto",
 }
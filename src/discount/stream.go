@@ -0,0 +1,202 @@
+package discount
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/clock"
+	pb "github.com/gussf/backend-challenge/src/discount/pb"
+)
+
+const ProviderGRPCStream = "grpc_stream"
+
+// DefaultRetryDelay is how long the stream waits before reconnecting
+const DefaultRetryDelay = 5 * time.Second
+
+// Update is a change to the streamed discount of a product, see DiscountUpdate in the proto
+type Update struct {
+	Version   int64
+	ProductId int32
+	Discount  Discount
+	Removed   bool
+	Resync    bool
+	// Contextual discounts vary with the checkout, they are always asked for with GetDiscount
+	Contextual bool
+	// SnapshotComplete tells every discount was streamed, until then products without one are asked for with GetDiscount
+	SnapshotComplete bool
+}
+
+// IsHeartbeat tells the update only keeps the stream alive
+func (u Update) IsHeartbeat() bool {
+	return u.ProductId == 0 && !u.Resync && !u.SnapshotComplete
+}
+
+func ConvertDiscountUpdateToUpdate(u *pb.DiscountUpdate) Update {
+	update := Update{Version: u.GetVersion(), ProductId: u.GetProductID(), Removed: u.GetRemoved(), Resync: u.GetResync(), Contextual: u.GetContextual(), SnapshotComplete: u.GetSnapshotComplete()}
+	if exact := u.GetDiscount(); exact != nil {
+		update.Discount = ConvertExactDiscountToDiscount(exact)
+	}
+	return update
+}
+
+// DiscountTable keeps the discounts received from the stream, the products whose discount is
+// contextual and the last version seen. It is safe for concurrent use
+type DiscountTable struct {
+	mu         sync.RWMutex
+	discounts  map[int32]Discount
+	contextual map[int32]bool
+	version    int64
+	connected  bool
+	// complete is set once the snapshot was fully streamed, so that products without a discount can be told apart from ones not streamed yet
+	complete   bool
+	receivedAt time.Time
+}
+
+func NewDiscountTable() *DiscountTable {
+	return &DiscountTable{discounts: make(map[int32]Discount), contextual: make(map[int32]bool)}
+}
+
+// Apply records u, received at now, which also tells the stream is connected.
+// Updates at or below the current version were already applied and are only heartbeats
+func (t *DiscountTable) Apply(u Update, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.connected = true
+	t.receivedAt = now
+	if u.Resync {
+		t.discounts = make(map[int32]Discount)
+		t.contextual = make(map[int32]bool)
+		t.version = 0
+		t.complete = false
+	}
+	if u.SnapshotComplete {
+		t.complete = true
+	}
+	if u.IsHeartbeat() || u.Version <= t.version {
+		return
+	}
+
+	t.version = u.Version
+	switch {
+	case u.ProductId == 0:
+	case u.Removed:
+		delete(t.discounts, u.ProductId)
+		delete(t.contextual, u.ProductId)
+	case u.Contextual:
+		delete(t.discounts, u.ProductId)
+		t.contextual[u.ProductId] = true
+	default:
+		t.discounts[u.ProductId] = u.Discount
+		delete(t.contextual, u.ProductId)
+	}
+}
+
+// Disconnect keeps the discounts and version, to resume from them, but stops them from being used
+func (t *DiscountTable) Disconnect() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.connected = false
+}
+
+// Lookup returns the discount of productId, no discount when the stream has none for it. It is false when
+// the table can't answer: the discount is contextual, the stream is down, nothing was received from it
+// for longer than staleAfter, or the product wasn't streamed and the snapshot isn't complete yet
+func (t *DiscountTable) Lookup(productId int32, now time.Time, staleAfter time.Duration) (Discount, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if !t.connected || now.Sub(t.receivedAt) > staleAfter || t.contextual[productId] {
+		return Discount{}, false
+	}
+	if d, ok := t.discounts[productId]; ok {
+		return d, true
+	}
+	if !t.complete {
+		return Discount{}, false
+	}
+	return FromBasisPoints(0), true
+}
+
+func (t *DiscountTable) Version() int64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.version
+}
+
+func (t *DiscountTable) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.discounts)
+}
+
+// StreamingDiscountService answers from the discounts pushed by WatchDiscounts, and asks the
+// gRPC service with GetDiscount for contextual discounts or while the table is stale
+type StreamingDiscountService struct {
+	client     pb.DiscountClient
+	fallback   DiscountService
+	table      *DiscountTable
+	staleAfter time.Duration
+	retryDelay time.Duration
+	clock      clock.Clock
+}
+
+func NewStreamingDiscountService(svc DiscountService_gRPC, staleAfter time.Duration) StreamingDiscountService {
+	return StreamingDiscountService{
+		client:     svc.client,
+		fallback:   svc,
+		table:      NewDiscountTable(),
+		staleAfter: staleAfter,
+		retryDelay: DefaultRetryDelay,
+		clock:      clock.SystemClock{},
+	}
+}
+
+func (s StreamingDiscountService) GetDiscountForProduct(req Request) Discount {
+	if d, ok := s.table.Lookup(req.ProductId, s.clock.Now(), s.staleAfter); ok {
+		d.Provider = ProviderGRPCStream
+		return d
+	}
+	return s.fallback.GetDiscountForProduct(req)
+}
+
+// Watch keeps one stream open until ctx is done, reconnecting from the last version seen
+func (s StreamingDiscountService) Watch(ctx context.Context) {
+	for {
+		err := s.watch(ctx)
+		s.table.Disconnect()
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("Discount stream is down, using GetDiscount and reconnecting in %s from version=%d: %v", s.retryDelay, s.table.Version(), err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.retryDelay):
+		}
+	}
+}
+
+func (s StreamingDiscountService) watch(ctx context.Context) error {
+	stream, err := s.client.WatchDiscounts(ctx, &pb.WatchDiscountsRequest{SinceVersion: s.table.Version()})
+	if err != nil {
+		return err
+	}
+	for {
+		u, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		update := ConvertDiscountUpdateToUpdate(u)
+		if update.Resync {
+			log.Printf("Discount stream resynced at version=%d, dropping %d discount(s)", update.Version, s.table.Len())
+		}
+		s.table.Apply(update, s.clock.Now())
+		if update.SnapshotComplete {
+			log.Printf("Discount stream snapshot complete at version=%d, %d discount(s)", s.table.Version(), s.table.Len())
+		}
+	}
+}
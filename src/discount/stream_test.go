@@ -0,0 +1,138 @@
+package discount
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/clock"
+	"github.com/gussf/backend-challenge/src/discount/pb"
+	"google.golang.org/grpc"
+)
+
+type FakeDiscountStream struct {
+	grpc.ClientStream
+	updates []*pb.DiscountUpdate
+}
+
+func (s *FakeDiscountStream) Recv() (*pb.DiscountUpdate, error) {
+	if len(s.updates) == 0 {
+		return nil, io.EOF
+	}
+	u := s.updates[0]
+	s.updates = s.updates[1:]
+	return u, nil
+}
+
+// FakeDiscountClient opens streams in order, and stops the watch once it has none left
+type FakeDiscountClient struct {
+	pb.DiscountClient
+	streams       [][]*pb.DiscountUpdate
+	sinceVersions []int64
+	stop          context.CancelFunc
+}
+
+func (c *FakeDiscountClient) WatchDiscounts(ctx context.Context, in *pb.WatchDiscountsRequest, opts ...grpc.CallOption) (pb.Discount_WatchDiscountsClient, error) {
+	c.sinceVersions = append(c.sinceVersions, in.GetSinceVersion())
+	if len(c.streams) == 0 {
+		c.stop()
+		return nil, errors.New("no more streams")
+	}
+	s := &FakeDiscountStream{updates: c.streams[0]}
+	c.streams = c.streams[1:]
+	return s, nil
+}
+
+type CountingDiscountService struct {
+	calls int
+}
+
+func (s *CountingDiscountService) GetDiscountForProduct(req Request) Discount {
+	s.calls++
+	return Discount{BasisPoints: 100, Provider: ProviderGRPC}
+}
+
+func TestStreamingDiscountServiceResumesFromLastVersion(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &FakeDiscountClient{stop: cancel, streams: [][]*pb.DiscountUpdate{
+		{
+			{Version: 1, ProductID: 1, Discount: &pb.ExactDiscount{BasisPoints: 1000}, Resync: true},
+			{Version: 2, ProductID: 2, Discount: &pb.ExactDiscount{BasisPoints: 2000}},
+			{Version: 2, SnapshotComplete: true},
+		},
+		{
+			{Version: 2, ProductID: 2, Discount: &pb.ExactDiscount{BasisPoints: 9999}},
+			{Version: 3, ProductID: 1, Removed: true},
+			{Version: 3},
+		},
+	}}
+
+	s := NewStreamingDiscountService(DiscountService_gRPC{client: client}, time.Minute)
+	s.retryDelay = 0
+	s.Watch(ctx)
+
+	if len(client.sinceVersions) != 3 || client.sinceVersions[0] != 0 || client.sinceVersions[1] != 2 || client.sinceVersions[2] != 3 {
+		t.Errorf("Incorrect versions resumed from: want=[0 2 3], got=%v", client.sinceVersions)
+	}
+	if _, ok := s.table.discounts[1]; ok {
+		t.Errorf("Removed discount of product=1 is still in the table")
+	}
+	if got := s.table.discounts[2].BasisPoints; got != 2000 {
+		t.Errorf("Incorrect discount of product=2, updates already seen must be ignored: want=%d, got=%d", 2000, got)
+	}
+	if !s.table.complete {
+		t.Errorf("Snapshot should stay complete when resuming without a resync")
+	}
+}
+
+func TestStreamingDiscountServiceFallsBackToUnary(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		productId    int32
+		connected    bool
+		receivedAgo  time.Duration
+		incomplete   bool
+		wantProvider string
+		wantBasis    int
+	}{
+		{name: "Streamed discount is used", productId: 1, connected: true, wantProvider: ProviderGRPCStream, wantBasis: 1500},
+		{name: "Streamed zero discount is used", productId: 2, connected: true, wantProvider: ProviderGRPCStream, wantBasis: 0},
+		{name: "Product that wasn't streamed has no discount", productId: 3, connected: true, wantProvider: ProviderGRPCStream, wantBasis: 0},
+		{name: "Product not streamed yet during the snapshot", productId: 3, connected: true, incomplete: true, wantProvider: ProviderGRPC, wantBasis: 100},
+		{name: "Streamed discount is used during the snapshot", productId: 1, connected: true, incomplete: true, wantProvider: ProviderGRPCStream, wantBasis: 1500},
+		{name: "Contextual discount", productId: 4, connected: true, wantProvider: ProviderGRPC, wantBasis: 100},
+		{name: "Stream is down", productId: 1, connected: false, wantProvider: ProviderGRPC, wantBasis: 100},
+		{name: "Table is stale", productId: 1, connected: true, receivedAgo: 2 * time.Minute, wantProvider: ProviderGRPC, wantBasis: 100},
+	}
+
+	for _, tt := range tests {
+		unary := &CountingDiscountService{}
+		s := StreamingDiscountService{fallback: unary, table: NewDiscountTable(), staleAfter: time.Minute, clock: clock.FixedClock{T: now}}
+		s.table.Apply(Update{Version: 1, ProductId: 1, Discount: FromBasisPoints(1500), Resync: true}, now.Add(-tt.receivedAgo))
+		s.table.Apply(Update{Version: 2, ProductId: 2, Discount: FromBasisPoints(0)}, now.Add(-tt.receivedAgo))
+		s.table.Apply(Update{Version: 3, ProductId: 4, Discount: FromBasisPoints(1500)}, now.Add(-tt.receivedAgo))
+		s.table.Apply(Update{Version: 4, ProductId: 4, Contextual: true}, now.Add(-tt.receivedAgo))
+		if !tt.incomplete {
+			s.table.Apply(Update{Version: 4, SnapshotComplete: true}, now.Add(-tt.receivedAgo))
+		}
+		if !tt.connected {
+			s.table.Disconnect()
+		}
+
+		d := s.GetDiscountForProduct(Request{ProductId: tt.productId})
+		if d.Provider != tt.wantProvider || int(d.BasisPoints) != tt.wantBasis {
+			t.Errorf("%s: Incorrect discount: want=%dbp from %s, got=%dbp from %s", tt.name, tt.wantBasis, tt.wantProvider, d.BasisPoints, d.Provider)
+		}
+		wantCalls := 0
+		if tt.wantProvider == ProviderGRPC {
+			wantCalls = 1
+		}
+		if unary.calls != wantCalls {
+			t.Errorf("%s: Incorrect GetDiscount calls: want=%d, got=%d", tt.name, wantCalls, unary.calls)
+		}
+	}
+}
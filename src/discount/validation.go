@@ -0,0 +1,204 @@
+package discount
+
+import (
+	"errors"
+	"log"
+	"math"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gussf/backend-challenge/src/money"
+)
+
+var ErrUnknownAnomalyPolicy = errors.New("unknown discount anomaly policy")
+
+// Anomalies recorded in Discount.Anomaly, a discount that was both is only reported as capped
+const (
+	AnomalyInvalid = "invalid"
+	AnomalyCapped  = "capped"
+)
+
+// AnomalyPolicy decides what happens to discounts outside of [0%, 100%]
+type AnomalyPolicy int
+
+const (
+	// PolicyClamp brings the percentage back to the closest valid value
+	PolicyClamp AnomalyPolicy = iota
+	// PolicyReject discards the percentage entirely, the product gets no discount
+	PolicyReject
+)
+
+func ParseAnomalyPolicy(s string) (AnomalyPolicy, error) {
+	switch strings.ToLower(s) {
+	case "", "clamp":
+		return PolicyClamp, nil
+	case "reject":
+		return PolicyReject, nil
+	default:
+		return PolicyClamp, ErrUnknownAnomalyPolicy
+	}
+}
+
+func (p AnomalyPolicy) String() string {
+	if p == PolicyReject {
+		return "reject"
+	}
+	return "clamp"
+}
+
+// AnomalyCounters is shared between copies of ValidatingDiscountService, so it is kept behind a pointer
+type AnomalyCounters struct {
+	invalid uint64
+	capped  uint64
+}
+
+// Invalid is the amount of discounts received that were NaN, infinite, negative or above 100%
+func (c *AnomalyCounters) Invalid() uint64 {
+	return atomic.LoadUint64(&c.invalid)
+}
+
+// Capped is the amount of discounts that were above the configured maximum
+func (c *AnomalyCounters) Capped() uint64 {
+	return atomic.LoadUint64(&c.capped)
+}
+
+// ValidatingDiscountService wraps another DiscountService, making sure that
+// whatever it returns is a sane discount no greater than maxPercentage
+type ValidatingDiscountService struct {
+	next           DiscountService
+	policy         AnomalyPolicy
+	maxBasisPoints money.BasisPoints
+	counters       *AnomalyCounters
+	rates          money.RatesTable
+}
+
+// ValidationOption configures optional behavior of the ValidatingDiscountService
+type ValidationOption func(*ValidatingDiscountService)
+
+// WithRates converts amounts in another currency than the unit price, so that they are capped too
+func WithRates(rates money.RatesTable) ValidationOption {
+	return func(svc *ValidatingDiscountService) {
+		svc.rates = rates
+	}
+}
+
+func NewValidatingDiscountService(next DiscountService, policy AnomalyPolicy, maxPercentage float32, opts ...ValidationOption) ValidatingDiscountService {
+	svc := ValidatingDiscountService{
+		next:           next,
+		policy:         policy,
+		maxBasisPoints: money.BasisPointsFromPercentage(maxPercentage),
+		counters:       &AnomalyCounters{},
+	}
+	for _, opt := range opts {
+		opt(&svc)
+	}
+	return svc
+}
+
+func (svc ValidatingDiscountService) GetDiscountForProduct(req Request) Discount {
+	id := req.ProductId
+	received := svc.next.GetDiscountForProduct(req)
+
+	discount, valid := svc.Validate(received)
+	discount.Provider, discount.Error, discount.Reason = received.Provider, received.Error, received.Reason
+	if !valid {
+		discount.Anomaly = AnomalyInvalid
+		atomic.AddUint64(&svc.counters.invalid, 1)
+		log.Printf("Invalid discount=%v (%dbp, amount=%d) received for product=%d, policy=%s, using %dbp amount=%d",
+			received.Raw, received.BasisPoints, received.AmountCents, id, svc.policy, discount.BasisPoints, discount.AmountCents)
+	}
+
+	if discount.BasisPoints > svc.maxBasisPoints {
+		atomic.AddUint64(&svc.counters.capped, 1)
+		log.Printf("Discount=%dbp for product=%d is above the maximum allowed, capping to %dbp", discount.BasisPoints, id, svc.maxBasisPoints)
+		discount.BasisPoints = svc.maxBasisPoints
+		discount.Anomaly = AnomalyCapped
+	}
+
+	// Amounts are capped like percentages, in the currency of the unit price
+	if max, ok := svc.MaxAmount(req); ok && discount.AmountCents > 0 {
+		amount, err := svc.AmountIn(discount, req.Currency)
+		if err != nil {
+			// The checkout can't convert it either, and ignores it in favour of the basis points
+			log.Printf("Discount amount=%d%s for product=%d can't be compared with the maximum allowed: %v", discount.AmountCents, discount.Currency, id, err)
+		} else if amount > max {
+			atomic.AddUint64(&svc.counters.capped, 1)
+			log.Printf("Discount amount=%d%s for product=%d is above the maximum allowed, capping to %d%s", discount.AmountCents, discount.Currency, id, max, req.Currency)
+			discount.AmountCents, discount.Currency = max, req.Currency
+			discount.Anomaly = AnomalyCapped
+		}
+	}
+
+	return discount
+}
+
+// MaxAmount is the greatest amount per unit allowed for the product of req, in the currency of its
+// unit price, as long as it is known. It is never rounded up, otherwise it could be exceeded by a cent
+func (svc ValidatingDiscountService) MaxAmount(req Request) (int, bool) {
+	if req.UnitAmount <= 0 {
+		return 0, false
+	}
+	return money.Floor.ApplyBasisPoints(req.UnitAmount, svc.maxBasisPoints), true
+}
+
+// AmountIn converts the amount of d to currency, amounts without a currency already being in it
+func (svc ValidatingDiscountService) AmountIn(d Discount, currency string) (int, error) {
+	if d.Currency == "" || money.NormalizeCurrency(d.Currency) == money.NormalizeCurrency(currency) {
+		return d.AmountCents, nil
+	}
+	converted, err := svc.rates.Convert(money.New(d.AmountCents, d.Currency), currency, money.HalfUp)
+	return converted.Amount, err
+}
+
+// Validate returns the discount that should be used according to the policy, and whether d was valid in the first place
+func (svc ValidatingDiscountService) Validate(d Discount) (Discount, bool) {
+	if !d.Exact {
+		p, valid := svc.ValidatePercentage(d.Raw)
+		v := FromPercentage(p)
+		v.Raw = d.Raw
+		return v, valid
+	}
+
+	valid := true
+	switch {
+	case d.BasisPoints < 0:
+		d.BasisPoints, valid = 0, false
+	case d.BasisPoints > money.OneHundredPercent:
+		d.BasisPoints, valid = svc.clampedOrRejected(money.OneHundredPercent), false
+	}
+
+	if d.AmountCents < 0 {
+		d.AmountCents, valid = 0, false
+	}
+
+	return d, valid
+}
+
+// ValidatePercentage applies the policy to the legacy float32 percentage
+func (svc ValidatingDiscountService) ValidatePercentage(p float32) (float32, bool) {
+	f := float64(p)
+	switch {
+	case math.IsNaN(f) || math.IsInf(f, 0):
+		// There is no sensible value to clamp to
+		return 0.00, false
+	case p < 0:
+		return 0.00, false
+	case p > 1:
+		if svc.policy == PolicyClamp {
+			return 1.00, false
+		}
+		return 0.00, false
+	}
+	return p, true
+}
+
+func (svc ValidatingDiscountService) clampedOrRejected(limit money.BasisPoints) money.BasisPoints {
+	if svc.policy == PolicyClamp {
+		return limit
+	}
+	return 0
+}
+
+func (svc ValidatingDiscountService) Counters() *AnomalyCounters {
+	return svc.counters
+}
@@ -0,0 +1,155 @@
+package discount
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gussf/backend-challenge/src/discount/pb"
+	"github.com/gussf/backend-challenge/src/money"
+)
+
+type StubDiscountService struct {
+	discount Discount
+}
+
+func (s StubDiscountService) GetDiscountForProduct(req Request) Discount {
+	return s.discount
+}
+
+func TestValidatingDiscountService(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		received      float32
+		policy        AnomalyPolicy
+		maxPercentage float32
+		want          float32
+		wantInvalid   uint64
+		wantCapped    uint64
+		wantAnomaly   string
+	}{
+		{name: "Valid discount is kept", received: 0.15, policy: PolicyClamp, maxPercentage: 1, want: 0.15},
+		{name: "Above 100% is clamped", received: 1.5, policy: PolicyClamp, maxPercentage: 1, want: 1, wantInvalid: 1, wantAnomaly: AnomalyInvalid},
+		{name: "Above 100% is rejected", received: 1.5, policy: PolicyReject, maxPercentage: 1, want: 0, wantInvalid: 1, wantAnomaly: AnomalyInvalid},
+		{name: "Negative is clamped to zero", received: -0.2, policy: PolicyClamp, maxPercentage: 1, want: 0, wantInvalid: 1, wantAnomaly: AnomalyInvalid},
+		{name: "NaN is always discarded", received: float32(math.NaN()), policy: PolicyClamp, maxPercentage: 1, want: 0, wantInvalid: 1, wantAnomaly: AnomalyInvalid},
+		{name: "Infinity is always discarded", received: float32(math.Inf(1)), policy: PolicyClamp, maxPercentage: 1, want: 0, wantInvalid: 1, wantAnomaly: AnomalyInvalid},
+		{name: "Valid discount above maximum is capped", received: 0.8, policy: PolicyReject, maxPercentage: 0.5, want: 0.5, wantCapped: 1, wantAnomaly: AnomalyCapped},
+		{name: "Clamped discount is also capped", received: 2, policy: PolicyClamp, maxPercentage: 0.3, want: 0.3, wantInvalid: 1, wantCapped: 1, wantAnomaly: AnomalyCapped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewValidatingDiscountService(StubDiscountService{FromPercentage(tt.received)}, tt.policy, tt.maxPercentage)
+
+			want := money.BasisPointsFromPercentage(tt.want)
+			discount := svc.GetDiscountForProduct(Request{ProductId: 1})
+			got := discount.BasisPoints
+			if want != got {
+				t.Errorf("%s: Incorrect discount: want=%d, got=%d", tt.name, want, got)
+			}
+
+			if tt.wantAnomaly != discount.Anomaly {
+				t.Errorf("%s: Incorrect anomaly: want=%s, got=%s", tt.name, tt.wantAnomaly, discount.Anomaly)
+			}
+
+			if tt.wantInvalid != svc.Counters().Invalid() {
+				t.Errorf("%s: Incorrect invalid count: want=%d, got=%d", tt.name, tt.wantInvalid, svc.Counters().Invalid())
+			}
+
+			if tt.wantCapped != svc.Counters().Capped() {
+				t.Errorf("%s: Incorrect capped count: want=%d, got=%d", tt.name, tt.wantCapped, svc.Counters().Capped())
+			}
+		})
+	}
+}
+
+func TestValidateExactDiscount(t *testing.T) {
+
+	svc := NewValidatingDiscountService(nil, PolicyReject, 1)
+
+	got, valid := svc.Validate(Discount{BasisPoints: 12000, Exact: true})
+	if valid || got.BasisPoints != 0 {
+		t.Errorf("Incorrect rejected discount: want=0bp invalid, got=%dbp valid=%t", got.BasisPoints, valid)
+	}
+
+	got, valid = svc.Validate(Discount{BasisPoints: 1000, AmountCents: -5, Exact: true})
+	if valid || got.AmountCents != 0 || got.BasisPoints != 1000 {
+		t.Errorf("Incorrect discount with negative amount: got=%dbp amount=%d valid=%t", got.BasisPoints, got.AmountCents, valid)
+	}
+}
+
+func TestValidatingDiscountServiceAmount(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		received     Discount
+		req          Request
+		want         int
+		wantCurrency string
+		wantCapped   uint64
+		wantAnomaly  string
+	}{
+		{name: "Amount below the maximum is kept", received: Discount{AmountCents: 40, Exact: true}, req: Request{UnitAmount: 100}, want: 40},
+		{name: "Amount above the maximum is capped", received: Discount{AmountCents: 80, Exact: true}, req: Request{UnitAmount: 100}, want: 50, wantCapped: 1, wantAnomaly: AnomalyCapped},
+		{name: "Amount above the unit price is capped", received: Discount{AmountCents: 500, Currency: "usd", Exact: true}, req: Request{UnitAmount: 99, Currency: "USD"}, want: 49, wantCapped: 1, wantAnomaly: AnomalyCapped},
+		{name: "Amount without unit price is kept", received: Discount{AmountCents: 80, Exact: true}, req: Request{}, want: 80},
+		{name: "Amount in another currency below the maximum is kept", received: Discount{AmountCents: 20, Currency: "EUR", Exact: true}, req: Request{UnitAmount: 100, Currency: "USD"}, want: 20},
+		{name: "Amount in another currency is capped in the price currency", received: Discount{AmountCents: 80, Currency: "EUR", Exact: true}, req: Request{UnitAmount: 100, Currency: "USD"},
+			want: 50, wantCurrency: "USD", wantCapped: 1, wantAnomaly: AnomalyCapped},
+		{name: "Amount in an unknown currency is kept", received: Discount{AmountCents: 80, Currency: "JPY", Exact: true}, req: Request{UnitAmount: 100, Currency: "USD"}, want: 80},
+	}
+
+	rates := money.NewRatesTable("USD")
+	rates.SetRate("EUR", "0.5")
+
+	for _, tt := range tests {
+		svc := NewValidatingDiscountService(StubDiscountService{tt.received}, PolicyClamp, 0.5, WithRates(rates))
+		tt.req.ProductId = 1
+
+		discount := svc.GetDiscountForProduct(tt.req)
+		if tt.want != discount.AmountCents {
+			t.Errorf("%s: Incorrect amount: want=%d, got=%d", tt.name, tt.want, discount.AmountCents)
+		}
+		if tt.wantCurrency != "" && tt.wantCurrency != discount.Currency {
+			t.Errorf("%s: Incorrect currency: want=%s, got=%s", tt.name, tt.wantCurrency, discount.Currency)
+		}
+		if tt.wantAnomaly != discount.Anomaly {
+			t.Errorf("%s: Incorrect anomaly: want=%s, got=%s", tt.name, tt.wantAnomaly, discount.Anomaly)
+		}
+		if tt.wantCapped != svc.Counters().Capped() {
+			t.Errorf("%s: Incorrect capped count: want=%d, got=%d", tt.name, tt.wantCapped, svc.Counters().Capped())
+		}
+	}
+}
+
+func TestConvertGetDiscountResponseToDiscount(t *testing.T) {
+
+	// 0.1 can't be represented by float32, the exact field must win when present
+	legacy := &pb.GetDiscountResponse{Percentage: 0.1}
+	exact := &pb.GetDiscountResponse{Percentage: 0.1, Discount: &pb.ExactDiscount{BasisPoints: 1001, AmountCents: 50, Currency: "USD"}}
+
+	got := ConvertGetDiscountResponseToDiscount(legacy)
+	if got.Exact || got.BasisPoints != 1000 {
+		t.Errorf("Incorrect legacy discount: want=1000bp exact=false, got=%dbp exact=%t", got.BasisPoints, got.Exact)
+	}
+
+	got = ConvertGetDiscountResponseToDiscount(exact)
+	if !got.Exact || got.BasisPoints != 1001 || got.AmountCents != 50 || got.Currency != "USD" {
+		t.Errorf("Incorrect exact discount: got=%+v", got)
+	}
+}
+
+func TestParseAnomalyPolicy(t *testing.T) {
+
+	for input, want := range map[string]AnomalyPolicy{"": PolicyClamp, "clamp": PolicyClamp, "REJECT": PolicyReject} {
+		got, err := ParseAnomalyPolicy(input)
+		if err != nil || want != got {
+			t.Errorf("Incorrect policy for '%s': want=%s, got=%s (err=%v)", input, want, got, err)
+		}
+	}
+
+	if _, err := ParseAnomalyPolicy("ignore"); err != ErrUnknownAnomalyPolicy {
+		t.Errorf("Incorrect error: want=%v, got=%v", ErrUnknownAnomalyPolicy, err)
+	}
+}
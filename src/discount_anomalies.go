@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// DiscountAnomaliesJSONResponse counts the discounts received since the server started that validation had to fix
+type DiscountAnomaliesJSONResponse struct {
+	Invalid uint64 `json:"invalid"`
+	Capped  uint64 `json:"capped"`
+}
+
+// DiscountAnomalies reports the anomaly counters on GET /admin/discount-anomalies, only for admins
+func (router ECommerceRouter) DiscountAnomalies(w http.ResponseWriter, r *http.Request) {
+
+	if router.anomalies == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Discount anomalies are not enabled"))
+		return
+	}
+
+	if !router.IsAdmin(r) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("Discount anomalies are only allowed for admins"))
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte("Only GET method is allowed"))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DiscountAnomaliesJSONResponse{
+		Invalid: router.anomalies.Invalid(),
+		Capped:  router.anomalies.Capped(),
+	})
+}
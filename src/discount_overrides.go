@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/discount"
+	"github.com/gussf/backend-challenge/src/money"
+)
+
+// DiscountOverrideJSONRequest pins the discount of a product until Expires_at (RFC 3339)
+type DiscountOverrideJSONRequest struct {
+	Basis_points int    `json:"basis_points"`
+	Amount       int    `json:"amount"`
+	Reason       string `json:"reason"`
+	Expires_at   string `json:"expires_at"`
+}
+
+type DiscountOverrideJSONResponse struct {
+	Product_id   int32  `json:"product_id"`
+	Basis_points int    `json:"basis_points"`
+	Amount       int    `json:"amount,omitempty"`
+	Reason       string `json:"reason"`
+	Set_by       string `json:"set_by"`
+	Set_at       string `json:"set_at"`
+	Expires_at   string `json:"expires_at"`
+}
+
+type DiscountOverrideListJSONResponse struct {
+	Overrides []DiscountOverrideJSONResponse `json:"overrides"`
+}
+
+type DiscountOverrideAuditJSONResponse struct {
+	At       string                       `json:"at"`
+	Action   string                       `json:"action"`
+	Actor    string                       `json:"actor,omitempty"`
+	Reason   string                       `json:"reason"`
+	Override DiscountOverrideJSONResponse `json:"override"`
+}
+
+type DiscountOverrideAuditListJSONResponse struct {
+	Audit []DiscountOverrideAuditJSONResponse `json:"audit"`
+}
+
+// DiscountOverrides lists overrides on GET /admin/discount-overrides, and their audit log on
+// GET /admin/discount-overrides/audit. PUT and DELETE on /admin/discount-overrides/{product_id}
+// set and clear the override of a product. Every one of them is only allowed for admins,
+// who name themselves in the X-Admin-Actor header for the audit log
+func (router ECommerceRouter) DiscountOverrides(w http.ResponseWriter, r *http.Request) {
+
+	if router.overrides == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Discount overrides are not enabled"))
+		return
+	}
+
+	if !router.IsAdmin(r) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("Discount overrides are only allowed for admins"))
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/discount-overrides"), "/")
+	switch {
+	case path == "" && r.Method == http.MethodGet:
+		resp := DiscountOverrideListJSONResponse{Overrides: []DiscountOverrideJSONResponse{}}
+		for _, o := range router.overrides.List() {
+			resp.Overrides = append(resp.Overrides, ConvertOverrideToDiscountOverrideJSONResponse(o))
+		}
+		w.Header().Add("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+
+	case path == "audit" && r.Method == http.MethodGet:
+		resp := DiscountOverrideAuditListJSONResponse{Audit: []DiscountOverrideAuditJSONResponse{}}
+		for _, e := range router.overrides.Audit() {
+			resp.Audit = append(resp.Audit, DiscountOverrideAuditJSONResponse{
+				At:       e.At.Format(time.RFC3339),
+				Action:   e.Action,
+				Actor:    e.Actor,
+				Reason:   e.Reason,
+				Override: ConvertOverrideToDiscountOverrideJSONResponse(e.Override),
+			})
+		}
+		w.Header().Add("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+
+	case path != "" && path != "audit" && (r.Method == http.MethodPut || r.Method == http.MethodDelete):
+		id, err := strconv.ParseInt(path, 10, 32)
+		if err != nil || id <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Invalid product id: " + path))
+			return
+		}
+		if r.Method == http.MethodPut {
+			router.SetDiscountOverride(w, r, int32(id))
+		} else {
+			router.ClearDiscountOverride(w, r, int32(id))
+		}
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte("Only GET on overrides, and PUT and DELETE on the override of a product are allowed"))
+	}
+}
+
+func (router ECommerceRouter) SetDiscountOverride(w http.ResponseWriter, r *http.Request, productId int32) {
+
+	var req DiscountOverrideJSONRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to parse request: " + err.Error()))
+		return
+	}
+	expiresAt, err := time.Parse(time.RFC3339, req.Expires_at)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to parse expires_at: " + err.Error()))
+		return
+	}
+
+	o, err := router.overrides.Set(discount.Override{
+		ProductId:   productId,
+		BasisPoints: money.BasisPoints(req.Basis_points),
+		AmountCents: req.Amount,
+		Reason:      req.Reason,
+		SetBy:       AdminActor(r),
+		ExpiresAt:   expiresAt,
+	})
+	switch {
+	case errors.Is(err, discount.ErrInvalidOverride):
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	case err != nil:
+		log.Printf("Failed to set discount override for product=%d: %v", productId, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to save the override"))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ConvertOverrideToDiscountOverrideJSONResponse(o))
+}
+
+// ClearDiscountOverride requires a "reason" query parameter, it is kept in the audit log
+func (router ECommerceRouter) ClearDiscountOverride(w http.ResponseWriter, r *http.Request, productId int32) {
+
+	reason := r.URL.Query().Get("reason")
+	if reason == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("A reason is required to clear an override"))
+		return
+	}
+
+	_, err := router.overrides.Clear(productId, AdminActor(r), reason)
+	switch {
+	case errors.Is(err, discount.ErrOverrideNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+		return
+	case err != nil:
+		log.Printf("Failed to clear discount override for product=%d: %v", productId, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to save the override"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func AdminActor(r *http.Request) string {
+	if actor := r.Header.Get("X-Admin-Actor"); actor != "" {
+		return actor
+	}
+	return "admin"
+}
+
+func ConvertOverrideToDiscountOverrideJSONResponse(o discount.Override) DiscountOverrideJSONResponse {
+	return DiscountOverrideJSONResponse{
+		Product_id:   o.ProductId,
+		Basis_points: int(o.BasisPoints),
+		Amount:       o.AmountCents,
+		Reason:       o.Reason,
+		Set_by:       o.SetBy,
+		Set_at:       o.SetAt.Format(time.RFC3339),
+		Expires_at:   o.ExpiresAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"github.com/gussf/backend-challenge/src/idempotency"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// responseRecorder sends the response to the client while keeping a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Idempotent makes POST requests with an Idempotency-Key header safe to retry: the first response is stored
+// and sent back to identical retries from the same caller, while reusing the key for another request gets 422.
+// Server errors are not stored, so that they can be retried with the same key
+func (router ECommerceRouter) Idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if router.idempotency == nil || key == "" || r.Method != http.MethodPost {
+			next(w, r)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Failed to read request: " + err.Error()))
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		// Keys are scoped to the endpoint and to the caller, the same key can be used once per path by each
+		// of them and a caller never gets the response stored for someone else
+		scopedKey := r.URL.Path + " " + Caller(r) + " " + key
+		state, stored := router.idempotency.Begin(scopedKey, idempotency.Fingerprint(r.Method, r.URL.Path, body))
+		switch state {
+		case idempotency.Replay:
+			log.Printf("Replaying response for %s=%s", IdempotencyKeyHeader, key)
+			for k, v := range stored.Header {
+				w.Header()[k] = v
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.Status)
+			w.Write(stored.Body)
+			return
+		case idempotency.Mismatch:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(IdempotencyKeyHeader + " was already used with a different request"))
+			return
+		case idempotency.InProgress:
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte("A request with this " + IdempotencyKeyHeader + " is still being processed"))
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		next(rec, r)
+
+		if rec.status >= http.StatusInternalServerError {
+			router.idempotency.Abort(scopedKey)
+			return
+		}
+		router.idempotency.Complete(scopedKey, idempotency.Response{
+			Status: rec.status,
+			Header: w.Header().Clone(),
+			Body:   rec.body.Bytes(),
+		})
+	}
+}
+
+// Caller identifies who sent the request by its credentials, which are hashed so that they are not kept.
+// Anonymous requests all share the same caller
+func Caller(r *http.Request) string {
+	h := sha256.Sum256([]byte(r.Header.Get("Authorization") + "\n" + r.Header.Get("X-Customer-Token")))
+	return hex.EncodeToString(h[:])
+}
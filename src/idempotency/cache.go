@@ -0,0 +1,113 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/clock"
+)
+
+// State is what should be done with a request carrying an idempotency key
+type State int
+
+const (
+	// New requests are processed, and their response stored with Complete
+	New State = iota
+	// Replay requests get the stored response back
+	Replay
+	// Mismatch requests reused a key with a different request
+	Mismatch
+	// InProgress requests came while the first one with their key is still being processed
+	InProgress
+)
+
+// Response is what is sent back to retries
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+type record struct {
+	fingerprint string
+	response    *Response
+	expiresAt   time.Time
+}
+
+// Cache keeps the responses of requests with an idempotency key for ttl, it is safe for concurrent use
+type Cache struct {
+	mu        *sync.Mutex
+	records   map[string]*record
+	ttl       time.Duration
+	clock     clock.Clock
+	lastSweep time.Time
+}
+
+func NewCache(ttl time.Duration, cl clock.Clock) *Cache {
+	return &Cache{mu: &sync.Mutex{}, records: make(map[string]*record), ttl: ttl, clock: cl}
+}
+
+// Fingerprint identifies a request by everything that changes its outcome
+func Fingerprint(method string, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Begin reserves key for a New request, or tells how a retry should be answered
+func (c *Cache) Begin(key string, fingerprint string) (State, *Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	c.sweep(now)
+
+	r, ok := c.records[key]
+	if !ok || !now.Before(r.expiresAt) {
+		c.records[key] = &record{fingerprint: fingerprint, expiresAt: now.Add(c.ttl)}
+		return New, nil
+	}
+
+	switch {
+	case r.fingerprint != fingerprint:
+		return Mismatch, nil
+	case r.response == nil:
+		return InProgress, nil
+	default:
+		return Replay, r.response
+	}
+}
+
+// Complete stores the response of the request that began with key
+func (c *Cache) Complete(key string, response Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if r, ok := c.records[key]; ok {
+		r.response = &response
+	}
+}
+
+// Abort releases key without storing anything, so that the request can be retried
+func (c *Cache) Abort(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.records, key)
+}
+
+// sweep drops expired records, at most once per ttl
+func (c *Cache) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < c.ttl {
+		return
+	}
+	for key, r := range c.records {
+		if !now.Before(r.expiresAt) {
+			delete(c.records, key)
+		}
+	}
+	c.lastSweep = now
+}
@@ -0,0 +1,42 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/clock"
+)
+
+func TestCacheBegin(t *testing.T) {
+	cl := &clock.MovableClock{T: time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)}
+	cache := NewCache(time.Hour, cl)
+	fingerprint := Fingerprint("POST", "/checkout", []byte(`{"products":[]}`))
+
+	if state, _ := cache.Begin("k", fingerprint); state != New {
+		t.Errorf("Incorrect state for first request: want=%d, got=%d", New, state)
+	}
+	if state, _ := cache.Begin("k", fingerprint); state != InProgress {
+		t.Errorf("Incorrect state while processing: want=%d, got=%d", InProgress, state)
+	}
+
+	cache.Complete("k", Response{Status: 200, Body: []byte("ok")})
+	state, stored := cache.Begin("k", fingerprint)
+	if state != Replay || string(stored.Body) != "ok" {
+		t.Errorf("Incorrect replay: state=%d, response=%+v", state, stored)
+	}
+
+	other := Fingerprint("POST", "/checkout", []byte(`{"products":[{"id":1}]}`))
+	if state, _ := cache.Begin("k", other); state != Mismatch {
+		t.Errorf("Incorrect state for a different request: want=%d, got=%d", Mismatch, state)
+	}
+
+	cl.T = cl.T.Add(time.Hour)
+	if state, _ := cache.Begin("k", other); state != New {
+		t.Errorf("Incorrect state after the ttl: want=%d, got=%d", New, state)
+	}
+
+	cache.Abort("k")
+	if state, _ := cache.Begin("k", fingerprint); state != New {
+		t.Errorf("Incorrect state after abort: want=%d, got=%d", New, state)
+	}
+}
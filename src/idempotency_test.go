@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/checkout"
+	"github.com/gussf/backend-challenge/src/clock"
+	"github.com/gussf/backend-challenge/src/idempotency"
+)
+
+func TestIdempotent(t *testing.T) {
+
+	now := time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)
+	router := NewECommerceRouter(checkout.CheckoutService{}, WithIdempotency(idempotency.NewCache(time.Hour, clock.FixedClock{T: now})))
+
+	calls := 0
+	started, release := make(chan bool), make(chan bool)
+	handler := router.Idempotent(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/fail":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/slow":
+			started <- true
+			<-release
+		}
+		w.Write([]byte("order " + r.Header.Get("X-Customer-Token")))
+	})
+
+	send := func(path string, key string, token string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, key)
+		if token != "" {
+			req.Header.Set("X-Customer-Token", token)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		key        string
+		token      string
+		body       string
+		wantStatus int
+		wantBody   string
+		wantCalls  int
+		wantReplay bool
+	}{
+		{name: "Should process the first request", path: "/orders", key: "k1", token: "jane", body: "a", wantStatus: 200, wantBody: "order jane", wantCalls: 1},
+		{name: "Should replay a retry", path: "/orders", key: "k1", token: "jane", body: "a", wantStatus: 200, wantBody: "order jane", wantCalls: 1, wantReplay: true},
+		{name: "Should reject the key with another body", path: "/orders", key: "k1", token: "jane", body: "b", wantStatus: 422, wantCalls: 1},
+		{name: "Should not replay to another caller", path: "/orders", key: "k1", token: "john", body: "a", wantStatus: 200, wantBody: "order john", wantCalls: 2},
+		{name: "Should scope keys to the path", path: "/checkout", key: "k1", token: "jane", body: "a", wantStatus: 200, wantCalls: 3},
+		{name: "Should not store server errors", path: "/fail", key: "k2", body: "a", wantStatus: 500, wantCalls: 4},
+		{name: "Should process server errors again", path: "/fail", key: "k2", body: "a", wantStatus: 500, wantCalls: 5},
+	}
+
+	for _, tt := range tests {
+		rec := send(tt.path, tt.key, tt.token, tt.body)
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: Incorrect status: want=%d, got=%d", tt.name, tt.wantStatus, rec.Code)
+		}
+		if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+			t.Errorf("%s: Incorrect body: want=%s, got=%s", tt.name, tt.wantBody, rec.Body.String())
+		}
+		if calls != tt.wantCalls {
+			t.Errorf("%s: Incorrect handler calls: want=%d, got=%d", tt.name, tt.wantCalls, calls)
+		}
+		if replayed := rec.Header().Get("Idempotent-Replayed") == "true"; replayed != tt.wantReplay {
+			t.Errorf("%s: Incorrect replay header: want=%t, got=%t", tt.name, tt.wantReplay, replayed)
+		}
+	}
+
+	// Retries while the first request is still being processed are turned away
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- send("/slow", "k3", "", "a") }()
+	<-started
+	if rec := send("/slow", "k3", "", "a"); rec.Code != http.StatusConflict {
+		t.Errorf("Incorrect status while in progress: want=%d, got=%d", http.StatusConflict, rec.Code)
+	}
+	release <- true
+	if rec := <-done; rec.Code != http.StatusOK {
+		t.Errorf("Incorrect status of the first request: want=%d, got=%d", http.StatusOK, rec.Code)
+	}
+}
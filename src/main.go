@@ -1,15 +1,34 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+	// The alpine image has no zoneinfo, promotions need it to load their time zones
+	_ "time/tzdata"
 
+	"github.com/gussf/backend-challenge/src/cart"
 	"github.com/gussf/backend-challenge/src/checkout"
+	"github.com/gussf/backend-challenge/src/clock"
+	"github.com/gussf/backend-challenge/src/coupon"
+	"github.com/gussf/backend-challenge/src/customer"
 	"github.com/gussf/backend-challenge/src/discount"
+	"github.com/gussf/backend-challenge/src/idempotency"
+	"github.com/gussf/backend-challenge/src/money"
+	"github.com/gussf/backend-challenge/src/order"
+	"github.com/gussf/backend-challenge/src/pricing"
+	"github.com/gussf/backend-challenge/src/promotion"
+	"github.com/gussf/backend-challenge/src/purchase"
+	"github.com/gussf/backend-challenge/src/quote"
 	"github.com/gussf/backend-challenge/src/repository"
+	"github.com/gussf/backend-challenge/src/shipping"
+	"github.com/gussf/backend-challenge/src/tax"
+	_ "github.com/lib/pq"
 )
 
 func main() {
@@ -18,27 +37,319 @@ func main() {
 	discountGRPCAddress := os.Getenv("DISCOUNT_GRPC_ADDRESS")
 	grpcDeadlineEnvvar, _ := strconv.Atoi(os.Getenv("GRPC_DEADLINE_MS"))
 	blackFridayDateEnvvar := os.Getenv("BLACK_FRIDAY_DATE_MMDD")
+	anomalyPolicyEnvvar := os.Getenv("DISCOUNT_ANOMALY_POLICY")
+	maxProductDiscountEnvvar := os.Getenv("DISCOUNT_MAX_PRODUCT_PERCENTAGE")
+	maxOrderDiscountEnvvar := os.Getenv("DISCOUNT_MAX_ORDER_PERCENTAGE")
+	roundingModeEnvvar := os.Getenv("ROUNDING_MODE")
+	roundingLevelEnvvar := os.Getenv("ROUNDING_LEVEL")
+	catalogCurrencyEnvvar := os.Getenv("CATALOG_CURRENCY")
+	currencyRatesFileEnvvar := os.Getenv("CURRENCY_RATES_FILE")
+	couponsFileEnvvar := os.Getenv("COUPONS_FILE")
+	pricingRulesFileEnvvar := os.Getenv("PRICING_RULES_FILE")
+	promotionsFileEnvvar := os.Getenv("PROMOTIONS_FILE")
+	defaultTimeZoneEnvvar := os.Getenv("DEFAULT_TIME_ZONE")
+	storefrontTimeZonesEnvvar := os.Getenv("STOREFRONT_TIME_ZONES")
+	adminTokenEnvvar := os.Getenv("ADMIN_TOKEN")
+	whatIfPricingEnvvar, _ := strconv.ParseBool(os.Getenv("WHAT_IF_PRICING_ENABLED"))
+	orderStoreEnvvar := os.Getenv("ORDER_STORE")
+	orderStoreDSNEnvvar := os.Getenv("ORDER_STORE_DSN")
+	idempotencyTTLEnvvar := os.Getenv("IDEMPOTENCY_TTL")
+	cartTTLEnvvar := os.Getenv("CART_TTL")
+	quoteSigningKeyEnvvar := os.Getenv("QUOTE_SIGNING_KEY")
+	quoteTTLEnvvar := os.Getenv("QUOTE_TTL")
+	taxRatesFileEnvvar := os.Getenv("TAX_RATES_FILE")
+	taxModeEnvvar := os.Getenv("TAX_MODE")
+	shippingRatesFileEnvvar := os.Getenv("SHIPPING_RATES_FILE")
+	customersFileEnvvar := os.Getenv("CUSTOMERS_FILE")
+	priceListsFileEnvvar := os.Getenv("PRICE_LISTS_FILE")
+	customerSigningKeyEnvvar := os.Getenv("CUSTOMER_SIGNING_KEY")
+	cartDiscountEnvvar, _ := strconv.ParseBool(os.Getenv("CART_DISCOUNT_ENABLED"))
+	discountStreamEnvvar, _ := strconv.ParseBool(os.Getenv("DISCOUNT_STREAM_ENABLED"))
+	discountStreamStaleAfterEnvvar := os.Getenv("DISCOUNT_STREAM_STALE_AFTER")
+	purchaseLimitsFileEnvvar := os.Getenv("PURCHASE_LIMITS_FILE")
+	discountOverridesFileEnvvar := os.Getenv("DISCOUNT_OVERRIDES_FILE")
 
 	gRPC_Deadline := time.Duration(grpcDeadlineEnvvar * int(time.Millisecond))
 
-	blackFridayDate := Parse_MMDD_DateFromString(blackFridayDateEnvvar)
+	maxProductDiscount := ParsePercentageFromString(maxProductDiscountEnvvar)
+	maxOrderDiscount := ParsePercentageFromString(maxOrderDiscountEnvvar)
 
-	imr, err := repository.NewInMemoryRepository("data/products.json")
+	anomalyPolicy, err := discount.ParseAnomalyPolicy(anomalyPolicyEnvvar)
+	if err != nil {
+		log.Fatalf("Failed to parse discount anomaly policy (%s): %v", anomalyPolicyEnvvar, err)
+	}
+
+	rounding := ParseRoundingFromStrings(roundingModeEnvvar, roundingLevelEnvvar)
+
+	catalogCurrency := catalogCurrencyEnvvar
+	if catalogCurrency == "" {
+		catalogCurrency = money.DefaultCurrency
+	}
+
+	imr, err := repository.NewInMemoryRepository("data/products.json", catalogCurrency)
 	if err != nil {
 		log.Fatal(err.Error())
 	}
 
-	dSvc := discount.NewDiscountService_gRPC(discountGRPCAddress, gRPC_Deadline)
-	cSvc := checkout.NewCheckoutService(imr, dSvc, blackFridayDate)
-	r := NewECommerceRouter(cSvc)
+	rates := money.NewRatesTable(catalogCurrency)
+	if currencyRatesFileEnvvar != "" {
+		rates, err = money.NewRatesTableFromFile(currencyRatesFileEnvvar)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		if !rates.Supports(catalogCurrency) {
+			log.Fatalf("Currency rates file (%s) has no rate for the catalog currency %s", currencyRatesFileEnvvar, catalogCurrency)
+		}
+	}
 
-	http.HandleFunc("/checkout", r.Checkout)
+	coupons := coupon.NewCouponService(nil)
+	if couponsFileEnvvar != "" {
+		coupons, err = coupon.NewCouponServiceFromFile(couponsFileEnvvar)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	var rules []pricing.Rule
+	if pricingRulesFileEnvvar != "" {
+		rules, err = pricing.LoadRulesFromFile(pricingRulesFileEnvvar)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	taxMode, err := tax.ParseMode(taxModeEnvvar)
+	if err != nil {
+		log.Fatalf("Failed to parse tax mode (%s): %v", taxModeEnvvar, err)
+	}
+
+	var taxes tax.Table
+	if taxRatesFileEnvvar != "" {
+		taxes, err = tax.NewTableFromFile(taxRatesFileEnvvar)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	var shippingProviders []shipping.ShippingRateProvider
+	if shippingRatesFileEnvvar != "" {
+		shippingProviders, err = shipping.LoadProvidersFromFile(shippingRatesFileEnvvar)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	customers, priceLists := LoadCustomers(customersFileEnvvar, priceListsFileEnvvar)
+
+	purchaseLimits, _ := purchase.NewLimits()
+	if purchaseLimitsFileEnvvar != "" {
+		purchaseLimits, err = purchase.LoadLimitsFromFile(purchaseLimitsFileEnvvar)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	calendar := LoadPromotionCalendar(promotionsFileEnvvar, blackFridayDateEnvvar)
+	timeZones := ParseTimeZonesFromStrings(defaultTimeZoneEnvvar, storefrontTimeZonesEnvvar)
+
+	grpcSvc := discount.NewDiscountService_gRPC(discountGRPCAddress, gRPC_Deadline)
+	var productDiscounts discount.DiscountService = grpcSvc
+	discountStreamStaleAfter := ParseDurationFromString(discountStreamStaleAfterEnvvar, time.Minute)
+	if discountStreamEnvvar {
+		streamSvc := discount.NewStreamingDiscountService(grpcSvc, discountStreamStaleAfter)
+		go streamSvc.Watch(context.Background())
+		productDiscounts = streamSvc
+	}
+	overrides := OpenOverrideStore(discountOverridesFileEnvvar)
+	dSvc := discount.NewValidatingDiscountService(
+		discount.NewOverridingDiscountService(productDiscounts, overrides),
+		anomalyPolicy,
+		maxProductDiscount,
+		discount.WithRates(rates),
+	)
+	checkoutOpts := []checkout.Option{
+		checkout.WithMaxOrderDiscount(maxOrderDiscount),
+		checkout.WithRounding(rounding),
+		checkout.WithCurrency(catalogCurrency, rates),
+		checkout.WithCoupons(coupons),
+		checkout.WithPricingRules(pricing.NewEngine(rules, rounding.Mode)),
+		checkout.WithTimeZones(timeZones),
+		checkout.WithClock(clock.SystemClock{}),
+		checkout.WithTaxes(taxes, taxMode),
+		checkout.WithShipping(shippingProviders...),
+		checkout.WithCustomers(customers, priceLists...),
+		checkout.WithPurchaseLimits(purchaseLimits),
+	}
+	if cartDiscountEnvvar {
+		checkoutOpts = append(checkoutOpts, checkout.WithCartDiscounts(grpcSvc))
+	}
+	cSvc := checkout.NewCheckoutService(imr, dSvc, calendar, checkoutOpts...)
+	orderStore := OpenOrderStore(orderStoreEnvvar, orderStoreDSNEnvvar)
+	idempotencyTTL := ParseDurationFromString(idempotencyTTLEnvvar, 24*time.Hour)
+	cartTTL := ParseDurationFromString(cartTTLEnvvar, 24*time.Hour)
+	orderSvc := order.NewOrderService(cSvc, orderStore)
+	cartSvc := cart.NewCartService(imr, cSvc, orderSvc, cart.NewMemoryStore(clock.SystemClock{}), cart.WithTTL(cartTTL))
+	routerOpts := []RouterOption{
+		WithAdminToken(adminTokenEnvvar),
+		WithWhatIfPricing(whatIfPricingEnvvar),
+		WithOrders(orderSvc),
+		WithCarts(cartSvc),
+		WithDiscountOverrides(overrides),
+		WithDiscountAnomalies(dSvc.Counters()),
+		WithIdempotency(idempotency.NewCache(idempotencyTTL, clock.SystemClock{})),
+	}
+	quoteTTL := ParseDurationFromString(quoteTTLEnvvar, 15*time.Minute)
+	if quoteSigningKeyEnvvar != "" {
+		routerOpts = append(routerOpts, WithQuotes(quote.NewSigner([]byte(quoteSigningKeyEnvvar), quoteTTL, clock.SystemClock{})))
+	}
+	if customerSigningKeyEnvvar != "" {
+		routerOpts = append(routerOpts, WithCustomers(customer.NewAuthenticator([]byte(customerSigningKeyEnvvar), clock.SystemClock{})))
+	}
+	r := NewECommerceRouter(cSvc, routerOpts...)
+
+	http.HandleFunc("/checkout", r.Idempotent(r.Checkout))
+	http.HandleFunc("/orders", r.Idempotent(r.Orders))
+	http.HandleFunc("/orders/", r.Order)
+	http.HandleFunc("/carts", r.Idempotent(r.Carts))
+	http.HandleFunc("/carts/", r.Idempotent(r.Cart))
+	http.HandleFunc("/admin/discount-overrides", r.DiscountOverrides)
+	http.HandleFunc("/admin/discount-overrides/", r.DiscountOverrides)
+	http.HandleFunc("/admin/discount-anomalies", r.DiscountAnomalies)
 
 	log.Println("Starting ecommerce server on", ecommerceAddress)
-	log.Println("Black friday:", blackFridayDate.Month(), blackFridayDate.Day())
+	for _, e := range calendar.Events() {
+		log.Printf("Promotion: %s (%s) effect=%s", e.Id, e.Name, e.Effect.Type)
+	}
+	log.Printf("Default time zone: %s, storefronts: %d", timeZones.Default, len(timeZones.Storefronts))
+	log.Printf("What-if pricing enabled: %t", whatIfPricingEnvvar && adminTokenEnvvar != "")
+	log.Printf("Discount limits: policy=%s product=%.2f order=%.2f", anomalyPolicy, maxProductDiscount, maxOrderDiscount)
+	log.Printf("Cart discounts enabled: %t", cartDiscountEnvvar)
+	log.Printf("Discount stream enabled: %t, stale after %s", discountStreamEnvvar, discountStreamStaleAfter)
+	log.Printf("Discount overrides in place: %d, file: %s", len(overrides.List()), discountOverridesFileEnvvar)
+	log.Printf("Discount rounding: mode=%s level=%s", rounding.Mode, rounding.Level)
+	log.Printf("Catalog currency: %s", catalogCurrency)
+	log.Printf("Pricing rules loaded: %d", len(rules))
+	log.Printf("Tax mode: %s, regions: %d", taxMode, taxes.Regions())
+	log.Printf("Shipping options loaded: %d", len(shippingProviders))
+	log.Printf("Customers loaded: %d, price lists: %d, authentication enabled: %t", customers.Len(), len(priceLists), customerSigningKeyEnvvar != "")
+	log.Printf("Purchase limits loaded: %d", purchaseLimits.Len())
+	log.Printf("Order store: %s", orderStoreEnvvar)
+	log.Printf("Idempotency keys kept for: %s", idempotencyTTL)
+	log.Printf("Carts expire after: %s", cartTTL)
+	log.Printf("Quotes enabled: %t, expire after: %s", quoteSigningKeyEnvvar != "", quoteTTL)
 	log.Fatal(http.ListenAndServe(ecommerceAddress, nil))
 }
 
+// LoadPromotionCalendar falls back to a single Black Friday when there is no promotions file,
+// which lasts the whole day in the zone of each request. The file wins when both are set
+func LoadPromotionCalendar(promotionsFile string, blackFridayDate string) promotion.Calendar {
+	if promotionsFile == "" {
+		bf := Parse_MMDD_DateFromString(blackFridayDate)
+		return promotion.NewCalendar(promotion.BlackFriday(bf.Month(), bf.Day(), nil))
+	}
+	if blackFridayDate != "" {
+		log.Printf("Warning: BLACK_FRIDAY_DATE_MMDD=%s is ignored, Black Friday is the one in %s", blackFridayDate, promotionsFile)
+	}
+
+	calendar, err := promotion.NewCalendarFromFile(promotionsFile)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	return calendar
+}
+
+// LoadCustomers reads the customer store and their price lists, both being empty when there is no file
+func LoadCustomers(customersFile string, priceListsFile string) (customer.MemoryStore, []customer.PriceList) {
+	customers := customer.NewMemoryStore()
+	if customersFile != "" {
+		var err error
+		customers, err = customer.NewMemoryStoreFromFile(customersFile)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	var priceLists []customer.PriceList
+	if priceListsFile != "" {
+		var err error
+		priceLists, err = customer.LoadPriceListsFromFile(priceListsFile)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+	return customers, priceLists
+}
+
+// OpenOverrideStore keeps discount overrides in memory, and in the JSON lines file at path when it is set
+func OpenOverrideStore(path string) *discount.OverrideStore {
+	if path == "" {
+		return discount.NewOverrideStore(clock.SystemClock{})
+	}
+	store, err := discount.NewFileOverrideStore(path, clock.SystemClock{})
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	return store
+}
+
+// OpenOrderStore opens "memory" (default), "file" or "postgres" stores, dsn being the file path or the connection string
+func OpenOrderStore(kind string, dsn string) order.Store {
+	switch strings.ToLower(kind) {
+	case "", "memory":
+		return order.NewMemoryStore()
+	case "file":
+		store, err := order.NewFileStore(dsn)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		return store
+	case "postgres":
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			log.Fatalf("Failed to open order database: %v", err)
+		}
+		store, err := order.NewSQLStore(db)
+		if err != nil {
+			log.Fatalf("Failed to prepare order database: %v", err)
+		}
+		return store
+	default:
+		log.Fatalf("Failed to open order store (%s): %v", kind, order.ErrUnknownStore)
+		return nil
+	}
+}
+
+// ParseTimeZonesFromStrings reads storefront zones as "storefront=Zone" pairs separated by commas,
+// an empty default zone meaning the server one
+func ParseTimeZonesFromStrings(defaultZone string, storefronts string) checkout.TimeZones {
+	tz := checkout.TimeZones{Default: time.Local, Storefronts: make(map[string]*time.Location)}
+
+	if defaultZone != "" {
+		loc, err := time.LoadLocation(defaultZone)
+		if err != nil {
+			log.Fatalf("Failed to parse default time zone (%s): %v", defaultZone, err)
+		}
+		tz.Default = loc
+	}
+
+	for _, pair := range strings.Split(storefronts, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			log.Fatalf("Failed to parse storefront time zone (%s), expected storefront=Zone", pair)
+		}
+		loc, err := time.LoadLocation(strings.TrimSpace(kv[1]))
+		if err != nil {
+			log.Fatalf("Failed to parse storefront time zone (%s): %v", pair, err)
+		}
+		tz.Storefronts[strings.TrimSpace(kv[0])] = loc
+	}
+
+	return tz
+}
+
 func Parse_MMDD_DateFromString(date string) time.Time {
 	layout := "0102"
 	blackFridayDate, err := time.Parse(layout, date)
@@ -47,3 +358,39 @@ func Parse_MMDD_DateFromString(date string) time.Time {
 	}
 	return blackFridayDate
 }
+
+// ParsePercentageFromString parses a percentage between 0 and 1, an empty string means no limit (1.00)
+func ParsePercentageFromString(percentage string) float32 {
+	if percentage == "" {
+		return 1.00
+	}
+	p, err := strconv.ParseFloat(percentage, 32)
+	if err != nil || p < 0 || p > 1 {
+		log.Fatalf("Failed to parse percentage (%s), expected a value between 0 and 1", percentage)
+	}
+	return float32(p)
+}
+
+// ParseDurationFromString parses durations such as "24h", an empty string meaning fallback
+func ParseDurationFromString(duration string, fallback time.Duration) time.Duration {
+	if duration == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(duration)
+	if err != nil || d <= 0 {
+		log.Fatalf("Failed to parse duration (%s), expected a positive value such as 24h", duration)
+	}
+	return d
+}
+
+func ParseRoundingFromStrings(mode string, level string) money.Rounding {
+	m, err := money.ParseRoundingMode(mode)
+	if err != nil {
+		log.Fatalf("Failed to parse rounding mode (%s): %v", mode, err)
+	}
+	l, err := money.ParseRoundingLevel(level)
+	if err != nil {
+		log.Fatalf("Failed to parse rounding level (%s): %v", level, err)
+	}
+	return money.Rounding{Mode: m, Level: l}
+}
@@ -0,0 +1,135 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// DefaultCurrency is the catalog currency when none is configured
+const DefaultCurrency = "USD"
+
+var (
+	ErrUnknownCurrency = errors.New("currency not found in rates table")
+	ErrInvalidRate     = errors.New("currency rate must be a positive decimal")
+	ErrAmountOverflow  = errors.New("converted amount is too large")
+)
+
+// Money is an amount in the minor unit (cents) of an ISO 4217 currency.
+// Every currency is assumed to have two decimal places
+type Money struct {
+	Amount   int
+	Currency string
+}
+
+func New(amount int, currency string) Money {
+	return Money{Amount: amount, Currency: NormalizeCurrency(currency)}
+}
+
+func (m Money) String() string {
+	return fmt.Sprintf("%d %s", m.Amount, m.Currency)
+}
+
+func NormalizeCurrency(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// RatesTable holds how much one unit of Base is worth in each currency
+type RatesTable struct {
+	Base  string
+	rates map[string]*big.Rat
+}
+
+type ratesTableJSON struct {
+	Base  string            `json:"base"`
+	Rates map[string]string `json:"rates"`
+}
+
+// NewRatesTable only has the base currency, so conversions are limited to it
+func NewRatesTable(base string) RatesTable {
+	base = NormalizeCurrency(base)
+	return RatesTable{Base: base, rates: map[string]*big.Rat{base: big.NewRat(1, 1)}}
+}
+
+// NewRatesTableFromFile reads rates written as decimal strings, so they are exact
+func NewRatesTableFromFile(jsonFilePath string) (RatesTable, error) {
+
+	var content ratesTableJSON
+
+	file, err := ioutil.ReadFile(jsonFilePath)
+	if err != nil {
+		return RatesTable{}, errors.New("error opening file " + jsonFilePath + ": " + err.Error())
+	}
+
+	err = json.Unmarshal(file, &content)
+	if err != nil {
+		return RatesTable{}, errors.New("error unmarshalling json: " + err.Error())
+	}
+
+	t := NewRatesTable(content.Base)
+	for code, rate := range content.Rates {
+		if err := t.SetRate(code, rate); err != nil {
+			return RatesTable{}, fmt.Errorf("%s: %w", code, err)
+		}
+	}
+
+	return t, nil
+}
+
+func (t RatesTable) SetRate(code string, rate string) error {
+	r, ok := new(big.Rat).SetString(rate)
+	if !ok || r.Sign() <= 0 {
+		return ErrInvalidRate
+	}
+	t.rates[NormalizeCurrency(code)] = r
+	return nil
+}
+
+func (t RatesTable) Supports(code string) bool {
+	_, ok := t.rates[NormalizeCurrency(code)]
+	return ok
+}
+
+// Convert goes through the base currency and rounds once, at the end
+func (t RatesTable) Convert(m Money, to string, mode RoundingMode) (Money, error) {
+	to = NormalizeCurrency(to)
+	if m.Currency == to {
+		return m, nil
+	}
+
+	fromRate, ok := t.rates[m.Currency]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s", ErrUnknownCurrency, m.Currency)
+	}
+	toRate, ok := t.rates[to]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s", ErrUnknownCurrency, to)
+	}
+
+	converted := new(big.Rat).SetInt64(int64(m.Amount))
+	converted.Mul(converted, toRate)
+	converted.Quo(converted, fromRate)
+
+	amount, err := roundRat(converted, mode)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %d %s to %s", err, m.Amount, m.Currency, to)
+	}
+	return Money{Amount: amount, Currency: to}, nil
+}
+
+// roundRat only deals with amounts that fit in an int64 once converted, larger ones are
+// an ErrAmountOverflow. Negative amounts are rounded by their magnitude
+func roundRat(r *big.Rat, mode RoundingMode) (int, error) {
+	if !r.Num().IsInt64() || !r.Denom().IsInt64() || r.Num().Int64() == math.MinInt64 {
+		return 0, ErrAmountOverflow
+	}
+	num, den := r.Num().Int64(), r.Denom().Int64()
+	if num < 0 {
+		return -int(mode.Divide(-num, den)), nil
+	}
+	return int(mode.Divide(num, den)), nil
+}
@@ -0,0 +1,42 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+
+	rates := NewRatesTable("USD")
+	rates.SetRate("EUR", "0.9210")
+	rates.SetRate("BRL", "5.0134")
+
+	tests := []struct {
+		name string
+		from Money
+		to   string
+		want Money
+	}{
+		{name: "Same currency is untouched", from: New(1999, "USD"), to: "usd", want: New(1999, "USD")},
+		{name: "From base currency", from: New(10000, "USD"), to: "EUR", want: New(9210, "EUR")},
+		{name: "To base currency", from: New(9210, "EUR"), to: "USD", want: New(10000, "USD")},
+		{name: "Between two quoted currencies", from: New(100, "EUR"), to: "BRL", want: New(544, "BRL")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rates.Convert(tt.from, tt.to, HalfUp)
+			if err != nil || tt.want != got {
+				t.Errorf("%s: Incorrect conversion: want=%s, got=%s (err=%v)", tt.name, tt.want, got, err)
+			}
+		})
+	}
+
+	if _, err := rates.Convert(New(100, "USD"), "JPY", HalfUp); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("Incorrect error: want=%v, got=%v", ErrUnknownCurrency, err)
+	}
+	if _, err := rates.Convert(New(math.MaxInt64, "USD"), "BRL", HalfUp); !errors.Is(err, ErrAmountOverflow) {
+		t.Errorf("Incorrect error: want=%v, got=%v", ErrAmountOverflow, err)
+	}
+}
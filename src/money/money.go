@@ -0,0 +1,18 @@
+package money
+
+import "math"
+
+// BasisPoints represents a percentage with two decimal places of precision, 10000 being 100%
+type BasisPoints int64
+
+const OneHundredPercent BasisPoints = 10000
+
+// BasisPointsFromPercentage converts a percentage such as 0.1 to basis points (1000),
+// rounding to the nearest basis point to get rid of float32 representation errors
+func BasisPointsFromPercentage(p float32) BasisPoints {
+	return BasisPoints(math.Round(float64(p) * float64(OneHundredPercent)))
+}
+
+func (bp BasisPoints) Percentage() float64 {
+	return float64(bp) / float64(OneHundredPercent)
+}
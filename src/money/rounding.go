@@ -0,0 +1,131 @@
+package money
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrUnknownRoundingMode  = errors.New("unknown rounding mode")
+	ErrUnknownRoundingLevel = errors.New("unknown rounding level")
+)
+
+type RoundingMode int
+
+const (
+	HalfUp RoundingMode = iota
+	HalfEven
+	Floor
+)
+
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	switch strings.ToLower(s) {
+	case "", "half_up":
+		return HalfUp, nil
+	case "half_even":
+		return HalfEven, nil
+	case "floor":
+		return Floor, nil
+	default:
+		return HalfUp, ErrUnknownRoundingMode
+	}
+}
+
+func (m RoundingMode) String() string {
+	switch m {
+	case HalfEven:
+		return "half_even"
+	case Floor:
+		return "floor"
+	default:
+		return "half_up"
+	}
+}
+
+// RoundingLevel decides whether discounts are rounded for each line or once for the whole order
+type RoundingLevel int
+
+const (
+	LineLevel RoundingLevel = iota
+	OrderLevel
+)
+
+func ParseRoundingLevel(s string) (RoundingLevel, error) {
+	switch strings.ToLower(s) {
+	case "", "line":
+		return LineLevel, nil
+	case "order":
+		return OrderLevel, nil
+	default:
+		return LineLevel, ErrUnknownRoundingLevel
+	}
+}
+
+func (l RoundingLevel) String() string {
+	if l == OrderLevel {
+		return "order"
+	}
+	return "line"
+}
+
+type Rounding struct {
+	Mode  RoundingMode
+	Level RoundingLevel
+}
+
+// Divide returns numerator/denominator rounded according to the mode. Both must be non-negative
+func (m RoundingMode) Divide(numerator, denominator int64) int64 {
+	q, r := numerator/denominator, numerator%denominator
+
+	switch m {
+	case Floor:
+		return q
+	case HalfEven:
+		if 2*r > denominator || (2*r == denominator && q%2 == 1) {
+			q++
+		}
+		return q
+	default:
+		if 2*r >= denominator {
+			q++
+		}
+		return q
+	}
+}
+
+// ApplyBasisPoints returns amount * bp, rounded to cents
+func (m RoundingMode) ApplyBasisPoints(amount int, bp BasisPoints) int {
+	return int(m.Divide(int64(amount)*int64(bp), int64(OneHundredPercent)))
+}
+
+// Allocate rounds the sum of numerators/denominator once, then splits the result between the parts
+// so that they always add up to it. Leftover cents go to the parts with the largest remainders
+func (m RoundingMode) Allocate(numerators []int64, denominator int64) []int64 {
+	var sum int64
+	parts := make([]int64, len(numerators))
+	for i, n := range numerators {
+		sum += n
+		parts[i] = n / denominator
+	}
+
+	leftover := m.Divide(sum, denominator)
+	for _, p := range parts {
+		leftover -= p
+	}
+
+	order := make([]int, len(numerators))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return numerators[order[a]]%denominator > numerators[order[b]]%denominator
+	})
+
+	for i := 0; leftover > 0 && i < len(order); i++ {
+		parts[order[i]]++
+		leftover--
+	}
+
+	return parts
+}
@@ -0,0 +1,62 @@
+package money
+
+import "testing"
+
+func TestDivide(t *testing.T) {
+
+	tests := []struct {
+		numerator int64
+		mode      RoundingMode
+		want      int64
+	}{
+		{numerator: 25, mode: HalfUp, want: 3},
+		{numerator: 25, mode: HalfEven, want: 2},
+		{numerator: 35, mode: HalfEven, want: 4},
+		{numerator: 29, mode: Floor, want: 2},
+		{numerator: 26, mode: HalfEven, want: 3},
+		{numerator: 24, mode: HalfUp, want: 2},
+	}
+
+	for _, tt := range tests {
+		got := tt.mode.Divide(tt.numerator, 10)
+		if tt.want != got {
+			t.Errorf("Incorrect %s rounding of %d/10: want=%d, got=%d", tt.mode, tt.numerator, tt.want, got)
+		}
+	}
+}
+
+func TestBasisPointsFromPercentage(t *testing.T) {
+
+	for p, want := range map[float32]BasisPoints{0.1: 1000, 0.07: 700, 0.3333: 3333, 1: 10000} {
+		got := BasisPointsFromPercentage(p)
+		if want != got {
+			t.Errorf("Incorrect basis points for %v: want=%d, got=%d", p, want, got)
+		}
+	}
+}
+
+func TestAllocate(t *testing.T) {
+
+	// Three lines of 0.5 cents each: line rounding would give 3 cents, order rounding gives 2 (half_even of 1.5)
+	numerators := []int64{5, 5, 5}
+	parts := HalfEven.Allocate(numerators, 10)
+
+	var sum int64
+	for _, p := range parts {
+		sum += p
+	}
+
+	var want int64 = 2
+	if want != sum {
+		t.Errorf("Incorrect allocated sum: want=%d, got=%d", want, sum)
+	}
+
+	// Largest remainders get the leftover cents first
+	parts = HalfUp.Allocate([]int64{12, 19, 14}, 10)
+	wantParts := []int64{1, 2, 2}
+	for i := range parts {
+		if wantParts[i] != parts[i] {
+			t.Errorf("Incorrect part %d: want=%d, got=%d", i, wantParts[i], parts[i])
+		}
+	}
+}
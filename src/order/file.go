@@ -0,0 +1,96 @@
+package order
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+)
+
+// FileStore appends every order to a JSON lines file, and keeps them in memory to be read.
+// Updates append the whole order again, the last line of an order being its current state.
+// The file is read once when the store is created, it must not be shared between processes
+type FileStore struct {
+	mu     *sync.Mutex
+	file   *os.File
+	memory *MemoryStore
+}
+
+func NewFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.New("error opening file " + path + ": " + err.Error())
+	}
+
+	s := &FileStore{mu: &sync.Mutex{}, file: file, memory: NewMemoryStore()}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var o Order
+		if err := json.Unmarshal(scanner.Bytes(), &o); err != nil {
+			file.Close()
+			return nil, errors.New("error unmarshalling json: " + err.Error())
+		}
+		s.memory.put(o)
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, errors.New("error reading file " + path + ": " + err.Error())
+	}
+
+	return s, nil
+}
+
+func (s *FileStore) Save(o Order) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.memory.Find(o.Id); err == nil {
+		return ErrDuplicatedOrder
+	}
+	if err := s.append(o); err != nil {
+		return err
+	}
+	return s.memory.Save(o)
+}
+
+func (s *FileStore) Update(o Order, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.memory.Find(o.Id)
+	if err != nil {
+		return err
+	}
+	if stored.Version != version {
+		return ErrVersionConflict
+	}
+	if err := s.append(o); err != nil {
+		return err
+	}
+	return s.memory.Update(o, version)
+}
+
+func (s *FileStore) append(o Order) error {
+	line, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+func (s *FileStore) Find(id string) (Order, error) {
+	return s.memory.Find(id)
+}
+
+func (s *FileStore) List(f Filter) ([]Order, error) {
+	return s.memory.List(f)
+}
+
+func (s *FileStore) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,83 @@
+package order
+
+import (
+	"sync"
+)
+
+// MemoryStore keeps orders until the process exits, it is safe for concurrent use
+type MemoryStore struct {
+	mu     *sync.RWMutex
+	orders []Order
+	byId   map[string]int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{mu: &sync.RWMutex{}, byId: make(map[string]int)}
+}
+
+func (m *MemoryStore) Save(o Order) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.byId[o.Id]; ok {
+		return ErrDuplicatedOrder
+	}
+	m.byId[o.Id] = len(m.orders)
+	m.orders = append(m.orders, o)
+	return nil
+}
+
+func (m *MemoryStore) Update(o Order, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i, ok := m.byId[o.Id]
+	if !ok {
+		return ErrOrderNotFound
+	}
+	if m.orders[i].Version != version {
+		return ErrVersionConflict
+	}
+	m.orders[i] = o
+	return nil
+}
+
+// put saves o, replacing the order with the same id if there is one
+func (m *MemoryStore) put(o Order) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if i, ok := m.byId[o.Id]; ok {
+		m.orders[i] = o
+		return
+	}
+	m.byId[o.Id] = len(m.orders)
+	m.orders = append(m.orders, o)
+}
+
+func (m *MemoryStore) Find(id string) (Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	i, ok := m.byId[id]
+	if !ok {
+		return Order{}, ErrOrderNotFound
+	}
+	return m.orders[i], nil
+}
+
+func (m *MemoryStore) List(f Filter) ([]Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ret := make([]Order, 0)
+	for i := len(m.orders) - 1; i >= 0; i-- {
+		if f.Limit > 0 && len(ret) == f.Limit {
+			break
+		}
+		if f.Matches(m.orders[i]) {
+			ret = append(ret, m.orders[i])
+		}
+	}
+	return ret, nil
+}
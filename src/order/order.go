@@ -0,0 +1,104 @@
+package order
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/checkout"
+)
+
+var (
+	ErrOrderNotFound   = errors.New("order not found")
+	ErrDuplicatedOrder = errors.New("order id already exists")
+	ErrEmptyOrder      = errors.New("order has no products that can be checked out")
+	ErrWhatIfOrder     = errors.New("what-if requests cannot be confirmed")
+	ErrUnknownStore    = errors.New("unknown order store")
+	ErrVersionConflict = errors.New("order was changed by someone else")
+	ErrQuoteUsed       = errors.New("quote was already confirmed")
+)
+
+// Order is a confirmed checkout, with the lines, discounts and gifts priced at confirmation
+type Order struct {
+	Id        string
+	CreatedAt time.Time
+	// TimeZone promotions were evaluated in, CreatedAt loses its zone name once stored
+	TimeZone    string
+	Storefront  string
+	CouponCodes []string
+	Checkout    checkout.CheckoutResponse
+	// QuoteId is the quote whose prices the order honoured, if any
+	QuoteId string
+	Status  Status
+	// Version grows with every change, updates must say which version they were made on
+	Version int
+	History []Transition
+}
+
+// HasProduct tells whether productId was ordered, gifts included
+func (o Order) HasProduct(productId int) bool {
+	for _, p := range o.Checkout.Products {
+		if p.Id == productId {
+			return true
+		}
+	}
+	return false
+}
+
+func (o Order) HasCoupon(code string) bool {
+	for _, c := range o.CouponCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter selects orders to list, every field left at its zero value is ignored
+type Filter struct {
+	Storefront string
+	ProductId  int
+	CouponCode string
+	Status     Status
+	// Orders created from From (inclusive) to To (exclusive)
+	From  time.Time
+	To    time.Time
+	Limit int
+}
+
+func (f Filter) Matches(o Order) bool {
+	switch {
+	case f.Storefront != "" && o.Storefront != f.Storefront:
+		return false
+	case f.ProductId != 0 && !o.HasProduct(f.ProductId):
+		return false
+	case f.CouponCode != "" && !o.HasCoupon(f.CouponCode):
+		return false
+	case f.Status != "" && o.Status != f.Status:
+		return false
+	case !f.From.IsZero() && o.CreatedAt.Before(f.From):
+		return false
+	case !f.To.IsZero() && !o.CreatedAt.Before(f.To):
+		return false
+	}
+	return true
+}
+
+// Store persists orders. List returns the newest orders first.
+// Update replaces an order only if the stored one is still at version, failing with ErrVersionConflict otherwise
+type Store interface {
+	Save(o Order) error
+	Update(o Order, version int) error
+	Find(id string) (Order, error)
+	List(f Filter) ([]Order, error)
+}
+
+// NewId returns a random id that can't be guessed from other orders
+func NewId() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic("failed to generate order id: " + err.Error())
+	}
+	return "ord_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,388 @@
+package order
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/checkout"
+	"github.com/gussf/backend-challenge/src/clock"
+	"github.com/gussf/backend-challenge/src/coupon"
+	"github.com/gussf/backend-challenge/src/customer"
+	"github.com/gussf/backend-challenge/src/discount"
+	"github.com/gussf/backend-challenge/src/promotion"
+	"github.com/gussf/backend-challenge/src/purchase"
+	"github.com/gussf/backend-challenge/src/quote"
+	"github.com/gussf/backend-challenge/src/repository"
+)
+
+type StubDiscountService struct{}
+
+func (s StubDiscountService) GetDiscountForProduct(req discount.Request) discount.Discount {
+	return discount.FromBasisPoints(1000)
+}
+
+// FailingStore fails to save every order
+type FailingStore struct {
+	Store
+}
+
+func (s FailingStore) Save(o Order) error {
+	return errors.New("disk full")
+}
+
+func testOrders() []Order {
+	day := time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)
+	return []Order{
+		{Id: "a", CreatedAt: day, Storefront: "br", Checkout: checkout.CheckoutResponse{Products: []checkout.ProductResponse{{Id: 1}}}},
+		{Id: "b", CreatedAt: day.Add(time.Hour), Storefront: "us", CouponCodes: []string{"WELCOME10"}, Checkout: checkout.CheckoutResponse{Products: []checkout.ProductResponse{{Id: 2}}}},
+		{Id: "c", CreatedAt: day.Add(2 * time.Hour), Storefront: "br", Checkout: checkout.CheckoutResponse{Products: []checkout.ProductResponse{{Id: 1}, {Id: 2}}}},
+	}
+}
+
+func TestStores(t *testing.T) {
+
+	fileStore, err := NewFileStore(filepath.Join(t.TempDir(), "orders.jsonl"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer fileStore.Close()
+
+	stores := map[string]Store{"memory": NewMemoryStore(), "file": fileStore}
+	day := time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{name: "Should list newest orders first", filter: Filter{}, want: []string{"c", "b", "a"}},
+		{name: "Should filter by storefront", filter: Filter{Storefront: "br"}, want: []string{"c", "a"}},
+		{name: "Should filter by product", filter: Filter{ProductId: 2}, want: []string{"c", "b"}},
+		{name: "Should filter by coupon", filter: Filter{CouponCode: "WELCOME10"}, want: []string{"b"}},
+		{name: "Should filter by creation time", filter: Filter{From: day.Add(time.Hour), To: day.Add(2 * time.Hour)}, want: []string{"b"}},
+		{name: "Should limit orders", filter: Filter{Limit: 1}, want: []string{"c"}},
+	}
+
+	for kind, store := range stores {
+		for _, o := range testOrders() {
+			if err := store.Save(o); err != nil {
+				t.Fatalf("%s: Unexpected error: %v", kind, err)
+			}
+		}
+
+		updated := testOrders()[0]
+		updated.Version = 1
+		if err := store.Update(updated, 1); err != ErrVersionConflict {
+			t.Errorf("%s: Incorrect error updating outdated order: want=%v, got=%v", kind, ErrVersionConflict, err)
+		}
+		if err := store.Update(updated, 0); err != nil {
+			t.Errorf("%s: Unexpected error: %v", kind, err)
+		}
+
+		if err := store.Save(testOrders()[0]); err != ErrDuplicatedOrder {
+			t.Errorf("%s: Incorrect error saving duplicated order: want=%v, got=%v", kind, ErrDuplicatedOrder, err)
+		}
+		if _, err := store.Find("nope"); err != ErrOrderNotFound {
+			t.Errorf("%s: Incorrect error finding missing order: want=%v, got=%v", kind, ErrOrderNotFound, err)
+		}
+
+		for _, tt := range tests {
+			orders, err := store.List(tt.filter)
+			if err != nil {
+				t.Fatalf("%s: Unexpected error: %v", kind, err)
+			}
+			var got []string
+			for _, o := range orders {
+				got = append(got, o.Id)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("%s: %s: Incorrect orders: want=%v, got=%v", kind, tt.name, tt.want, got)
+				continue
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("%s: %s: Incorrect orders: want=%v, got=%v", kind, tt.name, tt.want, got)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestFileStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.jsonl")
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, o := range testOrders() {
+		store.Save(o)
+	}
+	updated := testOrders()[1]
+	updated.Status, updated.Version = Paid, 1
+	store.Update(updated, 0)
+	store.Close()
+
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer store.Close()
+
+	o, err := store.Find("b")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if o.Storefront != "us" || len(o.CouponCodes) != 1 || len(o.Checkout.Products) != 1 || o.Status != Paid {
+		t.Errorf("Incorrect reloaded order: got=%+v", o)
+	}
+}
+
+func TestOrderServiceConfirm(t *testing.T) {
+
+	inmemoryRepo := repository.InMemoryRepository{Products: []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 100, Is_gift: false},
+	}}
+	coupons := coupon.NewCouponService([]coupon.Coupon{{Code: "TENOFF", Type: coupon.FixedAmount, Amount: 10, UsageLimit: 1}})
+	now := time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)
+	checkoutSvc := checkout.NewCheckoutService(inmemoryRepo, StubDiscountService{}, promotion.NewCalendar(),
+		checkout.WithCoupons(coupons), checkout.WithClock(clock.FixedClock{T: now}))
+	orderSvc := NewOrderService(checkoutSvc, NewMemoryStore())
+
+	o, err := orderSvc.Confirm(checkout.CheckoutRequest{
+		Products:    []checkout.ProductRequest{{Id: 1, Quantity: 1}},
+		CouponCodes: []string{"TENOFF", "NOPE"},
+		Storefront:  "br",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := 20
+	got := o.Checkout.TotalDiscount
+	if want != got {
+		t.Errorf("Incorrect TotalDiscount: want=%d, got=%d", want, got)
+	}
+	if len(o.CouponCodes) != 1 || o.CouponCodes[0] != "TENOFF" {
+		t.Errorf("Incorrect CouponCodes: got=%v", o.CouponCodes)
+	}
+	if o.Status != Created || o.Version != 1 || len(o.History) != 1 {
+		t.Errorf("Incorrect initial status: got=%s version=%d history=%+v", o.Status, o.Version, o.History)
+	}
+	if coupons.Used("TENOFF") != 1 {
+		t.Errorf("Coupon should be redeemed by the order")
+	}
+
+	found, err := orderSvc.Find(o.Id)
+	if err != nil || found.Id != o.Id {
+		t.Errorf("Order should be found by id=%s: got=%+v, err=%v", o.Id, found, err)
+	}
+
+	_, err = orderSvc.Confirm(checkout.CheckoutRequest{Products: []checkout.ProductRequest{{Id: 2, Quantity: 1}}})
+	if err != ErrEmptyOrder {
+		t.Errorf("Incorrect error for empty order: want=%v, got=%v", ErrEmptyOrder, err)
+	}
+
+	_, err = orderSvc.Confirm(checkout.CheckoutRequest{Products: []checkout.ProductRequest{{Id: 1, Quantity: 1}}, AsOf: &now})
+	if err != ErrWhatIfOrder {
+		t.Errorf("Incorrect error for what-if order: want=%v, got=%v", ErrWhatIfOrder, err)
+	}
+
+	// Orders that can't be saved give back their coupons
+	coupons.Release("TENOFF")
+	failing := NewOrderService(checkoutSvc, FailingStore{NewMemoryStore()})
+	if _, err := failing.Confirm(checkout.CheckoutRequest{Products: []checkout.ProductRequest{{Id: 1, Quantity: 1}}, CouponCodes: []string{"TENOFF"}}); err == nil {
+		t.Errorf("Confirm should fail when the order can't be saved")
+	}
+	if coupons.Used("TENOFF") != 0 {
+		t.Errorf("Coupon should be released when the order can't be saved: used=%d", coupons.Used("TENOFF"))
+	}
+}
+
+func TestOrderServiceTransition(t *testing.T) {
+
+	now := time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.Save(Order{Id: "a", Status: Created, Version: 1})
+	orderSvc := NewOrderService(checkout.CheckoutService{}, store, WithClock(clock.FixedClock{T: now}))
+
+	o, err := orderSvc.Transition("a", PaymentPending, "checkout", 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if o.Status != PaymentPending || o.Version != 2 || !o.History[0].At.Equal(now) {
+		t.Errorf("Incorrect order: got=%+v", o)
+	}
+
+	if _, err := orderSvc.Transition("a", Paid, "stale", 1); err != ErrVersionConflict {
+		t.Errorf("Incorrect error for outdated version: want=%v, got=%v", ErrVersionConflict, err)
+	}
+	if _, err := orderSvc.Transition("a", Fulfilled, "skip", 2); !errors.Is(err, ErrIllegalTransition) {
+		t.Errorf("Incorrect error for illegal transition: want=%v, got=%v", ErrIllegalTransition, err)
+	}
+	if _, err := orderSvc.Transition("b", Paid, "missing", 1); err != ErrOrderNotFound {
+		t.Errorf("Incorrect error for missing order: want=%v, got=%v", ErrOrderNotFound, err)
+	}
+}
+
+func TestOrderServiceTransitionReleases(t *testing.T) {
+
+	inmemoryRepo := repository.InMemoryRepository{Products: []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 100, Is_gift: false},
+	}}
+	coupons := coupon.NewCouponService([]coupon.Coupon{{Code: "TENOFF", Type: coupon.FixedAmount, Amount: 10, UsageLimit: 1}})
+	limits, _ := purchase.NewLimits(purchase.Limit{ProductId: 1, MaxPerCustomer: 2, Action: purchase.Cap})
+	customers := customer.NewMemoryStore(customer.Customer{Id: "jane"})
+	now := time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)
+	checkoutSvc := checkout.NewCheckoutService(inmemoryRepo, StubDiscountService{}, promotion.NewCalendar(), checkout.WithCoupons(coupons),
+		checkout.WithCustomers(customers), checkout.WithPurchaseLimits(limits), checkout.WithClock(clock.FixedClock{T: now}))
+	orderSvc := NewOrderService(checkoutSvc, NewMemoryStore(), WithClock(clock.FixedClock{T: now}))
+
+	req := checkout.CheckoutRequest{Products: []checkout.ProductRequest{{Id: 1, Quantity: 2}}, CouponCodes: []string{"TENOFF"}, CustomerId: "jane"}
+	o, err := orderSvc.Confirm(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := orderSvc.Transition(o.Id, PaymentPending, "checkout", 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if coupons.Used("TENOFF") != 1 {
+		t.Errorf("Incorrect coupon usage of a pending order: want=1, got=%d", coupons.Used("TENOFF"))
+	}
+
+	if _, err := orderSvc.Transition(o.Id, Cancelled, "payment failed", 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if coupons.Used("TENOFF") != 0 {
+		t.Errorf("Incorrect coupon usage of a cancelled order: want=0, got=%d", coupons.Used("TENOFF"))
+	}
+
+	// The customer can buy the same again, with the same coupon
+	again, err := orderSvc.Confirm(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if again.Checkout.Products[0].Quantity != 2 || len(again.CouponCodes) != 1 {
+		t.Errorf("Incorrect order after a cancellation: quantity=%d coupons=%v", again.Checkout.Products[0].Quantity, again.CouponCodes)
+	}
+}
+
+func TestOrderServiceConfirmQuote(t *testing.T) {
+
+	inmemoryRepo := repository.InMemoryRepository{Products: []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 100, Is_gift: false},
+	}}
+	coupons := coupon.NewCouponService([]coupon.Coupon{{Code: "TENOFF", Type: coupon.FixedAmount, Amount: 10, UsageLimit: 1}})
+	now := time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)
+	checkoutSvc := checkout.NewCheckoutService(inmemoryRepo, StubDiscountService{}, promotion.NewCalendar(),
+		checkout.WithCoupons(coupons), checkout.WithClock(clock.FixedClock{T: now}))
+	orderSvc := NewOrderService(checkoutSvc, NewMemoryStore(), WithClock(clock.FixedClock{T: now}))
+	signer := quote.NewSigner([]byte("secret"), time.Hour, clock.FixedClock{T: now})
+
+	req := checkout.CheckoutRequest{Products: []checkout.ProductRequest{{Id: 1, Quantity: 1}}, CouponCodes: []string{"TENOFF"}}
+	resp := checkoutSvc.Quote(req)
+	if coupons.Used("TENOFF") != 0 {
+		t.Errorf("Coupon should not be redeemed by quotes")
+	}
+	q, _, _ := signer.Sign(req, resp)
+	other, _, _ := signer.Sign(req, checkoutSvc.Quote(req))
+
+	// The quoted prices are honoured, whatever the catalog says now
+	q.Checkout.Products[0].UnitAmount = 1
+	o, err := orderSvc.ConfirmQuote(q)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if o.QuoteId != q.Id || o.Checkout.Products[0].UnitAmount != 1 || coupons.Used("TENOFF") != 1 {
+		t.Errorf("Incorrect order: got=%+v", o)
+	}
+
+	if _, err := orderSvc.ConfirmQuote(q); err != ErrQuoteUsed {
+		t.Errorf("Incorrect error confirming a quote twice: want=%v, got=%v", ErrQuoteUsed, err)
+	}
+
+	// Both quotes had the coupon, only the first one confirmed could use it
+	if _, err := orderSvc.ConfirmQuote(other); !errors.Is(err, coupon.ErrUsageLimitReached) {
+		t.Errorf("Incorrect error for a coupon that ran out: want=%v, got=%v", coupon.ErrUsageLimitReached, err)
+	}
+}
+
+func TestOrderServiceConfirmQuoteGifts(t *testing.T) {
+
+	inmemoryRepo := repository.InMemoryRepository{Products: []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 100, Is_gift: false},
+		{Id: 2, Title: "b", Description: "b", Amount: 50, Is_gift: true},
+	}}
+	calendar := promotion.NewCalendar(promotion.Event{Id: "gwp", Effect: promotion.Effect{Type: promotion.AddGift, GiftProductId: 2,
+		GiftConditions: promotion.GiftConditions{DailyCap: 1}}})
+	coupons := coupon.NewCouponService([]coupon.Coupon{{Code: "TENOFF", Type: coupon.FixedAmount, Amount: 10}})
+	now := time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)
+	checkoutSvc := checkout.NewCheckoutService(inmemoryRepo, StubDiscountService{}, calendar,
+		checkout.WithCoupons(coupons), checkout.WithClock(clock.FixedClock{T: now}))
+	orderSvc := NewOrderService(checkoutSvc, NewMemoryStore(), WithClock(clock.FixedClock{T: now}))
+	signer := quote.NewSigner([]byte("secret"), time.Hour, clock.FixedClock{T: now})
+
+	// Both quotes get the only gift of the day, nothing is used up until one is confirmed
+	req := checkout.CheckoutRequest{Products: []checkout.ProductRequest{{Id: 1, Quantity: 1}}, CouponCodes: []string{"TENOFF"}}
+	first, _, _ := signer.Sign(req, checkoutSvc.Quote(req))
+	second, _, _ := signer.Sign(req, checkoutSvc.Quote(req))
+	if len(second.Checkout.Products) != 2 {
+		t.Fatalf("Both quotes should have the gift: got=%+v", second.Checkout.Products)
+	}
+
+	// A quote that can't be saved gives its coupon and gift back
+	failing := NewOrderService(checkoutSvc, FailingStore{NewMemoryStore()}, WithClock(clock.FixedClock{T: now}))
+	if _, err := failing.ConfirmQuote(first); err == nil {
+		t.Errorf("ConfirmQuote should fail when the order can't be saved")
+	}
+	if coupons.Used("TENOFF") != 0 {
+		t.Errorf("Coupon should be released when the order can't be saved: used=%d", coupons.Used("TENOFF"))
+	}
+
+	if _, err := orderSvc.ConfirmQuote(first); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := orderSvc.ConfirmQuote(second); !errors.Is(err, promotion.ErrGiftLimitReached) {
+		t.Errorf("Incorrect error for a gift that ran out: want=%v, got=%v", promotion.ErrGiftLimitReached, err)
+	}
+	if coupons.Used("TENOFF") != 1 {
+		t.Errorf("Coupon of the rejected quote should be released: used=%d", coupons.Used("TENOFF"))
+	}
+}
+
+func TestOrderServiceConfirmQuotePurchaseLimits(t *testing.T) {
+
+	inmemoryRepo := repository.InMemoryRepository{Products: []repository.ProductDAO{
+		{Id: 1, Title: "a", Description: "a", Amount: 100, Is_gift: false},
+	}}
+	limits, _ := purchase.NewLimits(purchase.Limit{ProductId: 1, MaxPerCustomer: 2, Action: purchase.Cap})
+	customers := customer.NewMemoryStore(customer.Customer{Id: "jane"})
+	now := time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)
+	checkoutSvc := checkout.NewCheckoutService(inmemoryRepo, StubDiscountService{}, promotion.NewCalendar(),
+		checkout.WithCustomers(customers), checkout.WithPurchaseLimits(limits), checkout.WithClock(clock.FixedClock{T: now}))
+	orderSvc := NewOrderService(checkoutSvc, NewMemoryStore(), WithClock(clock.FixedClock{T: now}))
+	failing := NewOrderService(checkoutSvc, FailingStore{NewMemoryStore()}, WithClock(clock.FixedClock{T: now}))
+	signer := quote.NewSigner([]byte("secret"), time.Hour, clock.FixedClock{T: now})
+
+	req := checkout.CheckoutRequest{Products: []checkout.ProductRequest{{Id: 1, Quantity: 2}}, CustomerId: "jane"}
+	first, _, _ := signer.Sign(req, checkoutSvc.Quote(req))
+	second, _, _ := signer.Sign(req, checkoutSvc.Quote(req))
+
+	// Orders that can't be saved don't count towards the limit
+	if _, err := failing.Confirm(req); err == nil {
+		t.Errorf("Confirm should fail when the order can't be saved")
+	}
+	if _, err := failing.ConfirmQuote(first); err == nil {
+		t.Errorf("ConfirmQuote should fail when the order can't be saved")
+	}
+
+	if _, err := orderSvc.ConfirmQuote(first); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := orderSvc.ConfirmQuote(second); !errors.Is(err, purchase.ErrLimitReached) {
+		t.Errorf("Incorrect error for a purchase limit that ran out: want=%v, got=%v", purchase.ErrLimitReached, err)
+	}
+}
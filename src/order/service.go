@@ -0,0 +1,146 @@
+package order
+
+import (
+	"log"
+
+	"github.com/gussf/backend-challenge/src/checkout"
+	"github.com/gussf/backend-challenge/src/clock"
+	"github.com/gussf/backend-challenge/src/quote"
+)
+
+type OrderService struct {
+	checkoutSvc checkout.CheckoutService
+	store       Store
+	clock       clock.Clock
+}
+
+// Option configures optional behavior of the OrderService
+type Option func(*OrderService)
+
+// WithClock sets the clock status transitions are timestamped with
+func WithClock(cl clock.Clock) Option {
+	return func(s *OrderService) {
+		s.clock = cl
+	}
+}
+
+func NewOrderService(cs checkout.CheckoutService, store Store, opts ...Option) OrderService {
+	s := OrderService{checkoutSvc: cs, store: store, clock: clock.SystemClock{}}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
+
+// Confirm prices the request like a checkout, redeeming its coupons, and persists the result.
+// What was redeemed is released when the order can't be persisted
+func (s OrderService) Confirm(req checkout.CheckoutRequest) (Order, error) {
+	if req.IsWhatIf() {
+		return Order{}, ErrWhatIfOrder
+	}
+
+	// Traces are for support looking at a price, they are not kept with orders
+	req.Explain = false
+	response := s.checkoutSvc.ProcessRequest(req)
+	if len(response.Products) == 0 {
+		s.checkoutSvc.Release(response)
+		return Order{}, ErrEmptyOrder
+	}
+
+	o := Order{
+		Id:          NewId(),
+		CreatedAt:   response.EvaluatedAt,
+		TimeZone:    response.EvaluatedAt.Location().String(),
+		Storefront:  req.Storefront,
+		CouponCodes: AppliedCoupons(response),
+		Checkout:    *response,
+		Status:      Created,
+		Version:     1,
+		History:     []Transition{{To: Created, At: response.EvaluatedAt, Reason: "confirmed"}},
+	}
+	if err := s.store.Save(o); err != nil {
+		s.checkoutSvc.Release(response)
+		return Order{}, err
+	}
+
+	log.Printf("Order=%s confirmed, total=%d discount=%d", o.Id, o.Checkout.TotalAmount, o.Checkout.TotalDiscount)
+	return o, nil
+}
+
+// ConfirmQuote persists the order exactly as it was quoted, which can only be done once per quote.
+// Coupons and gifts the quote applied are redeemed now, the quote is rejected if one of them ran out in between
+func (s OrderService) ConfirmQuote(q quote.Quote) (Order, error) {
+	if len(q.Checkout.Products) == 0 {
+		return Order{}, ErrEmptyOrder
+	}
+
+	o := Order{
+		Id:          "ord_" + q.Id,
+		CreatedAt:   s.clock.Now(),
+		TimeZone:    q.TimeZone,
+		Storefront:  q.Request.Storefront,
+		CouponCodes: AppliedCoupons(&q.Checkout),
+		Checkout:    q.Checkout,
+		QuoteId:     q.Id,
+		Status:      Created,
+		Version:     1,
+	}
+	o.History = []Transition{{To: Created, At: o.CreatedAt, Reason: "quote confirmed"}}
+
+	if _, err := s.store.Find(o.Id); err == nil {
+		return Order{}, ErrQuoteUsed
+	}
+	if err := s.checkoutSvc.Redeem(&o.Checkout); err != nil {
+		return Order{}, err
+	}
+	if err := s.store.Save(o); err != nil {
+		s.checkoutSvc.Release(&o.Checkout)
+		if err == ErrDuplicatedOrder {
+			return Order{}, ErrQuoteUsed
+		}
+		return Order{}, err
+	}
+
+	log.Printf("Order=%s confirmed from quote=%s, total=%d discount=%d", o.Id, q.Id, o.Checkout.TotalAmount, o.Checkout.TotalDiscount)
+	return o, nil
+}
+
+// Transition moves the order to status to. version must be the one the caller last read,
+// so that a change made in between is not silently overwritten. Cancelled and refunded orders
+// release their coupons, gifts and purchases, which can only happen once since both are final
+func (s OrderService) Transition(id string, to Status, reason string, version int) (Order, error) {
+	o, err := s.store.Find(id)
+	if err != nil {
+		return o, err
+	}
+	if o.Version != version {
+		return o, ErrVersionConflict
+	}
+
+	updated, err := o.MoveTo(to, reason, s.clock.Now())
+	if err != nil {
+		return o, err
+	}
+	if err := s.store.Update(updated, version); err != nil {
+		return o, err
+	}
+	if to.Releases() {
+		s.checkoutSvc.Release(&updated.Checkout)
+	}
+
+	log.Printf("Order=%s moved from %s to %s: %s", id, o.Status, to, reason)
+	return updated, nil
+}
+
+func (s OrderService) Find(id string) (Order, error) {
+	return s.store.Find(id)
+}
+
+func (s OrderService) List(f Filter) ([]Order, error) {
+	return s.store.List(f)
+}
+
+// AppliedCoupons returns the codes that made it into the checkout, rejected ones are only warnings
+func AppliedCoupons(r *checkout.CheckoutResponse) []string {
+	return r.CouponCodes()
+}
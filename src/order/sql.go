@@ -0,0 +1,134 @@
+package order
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// SQLStore keeps orders in the "orders" table, written for PostgreSQL. The order itself is
+// stored as JSON, only the columns orders are filtered by have their own
+type SQLStore struct {
+	db *sql.DB
+}
+
+const createOrdersTable = `CREATE TABLE IF NOT EXISTS orders (
+	id TEXT PRIMARY KEY,
+	created_at TIMESTAMPTZ NOT NULL,
+	storefront TEXT NOT NULL,
+	body TEXT NOT NULL
+)`
+
+// Tables created before orders had a status
+const addVersionColumn = `ALTER TABLE orders ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 0`
+
+// NewSQLStore creates the orders table when it doesn't exist yet
+func NewSQLStore(db *sql.DB) (SQLStore, error) {
+	for _, stmt := range []string{createOrdersTable, addVersionColumn} {
+		if _, err := db.Exec(stmt); err != nil {
+			return SQLStore{}, err
+		}
+	}
+	return SQLStore{db: db}, nil
+}
+
+func (s SQLStore) Save(o Order) error {
+	body, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.db.Exec(`INSERT INTO orders (id, created_at, storefront, body, version) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (id) DO NOTHING`,
+		o.Id, o.CreatedAt, o.Storefront, string(body), o.Version)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrDuplicatedOrder
+	}
+	return nil
+}
+
+// Update relies on the version column, so that two updates made on the same version can't both succeed
+func (s SQLStore) Update(o Order, version int) error {
+	body, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.db.Exec(`UPDATE orders SET body = $1, version = $2 WHERE id = $3 AND version = $4`,
+		string(body), o.Version, o.Id, version)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+
+	if _, err := s.Find(o.Id); err != nil {
+		return err
+	}
+	return ErrVersionConflict
+}
+
+func (s SQLStore) Find(id string) (Order, error) {
+	var body string
+	err := s.db.QueryRow(`SELECT body FROM orders WHERE id = $1`, id).Scan(&body)
+	if err == sql.ErrNoRows {
+		return Order{}, ErrOrderNotFound
+	}
+	if err != nil {
+		return Order{}, err
+	}
+
+	var o Order
+	err = json.Unmarshal([]byte(body), &o)
+	return o, err
+}
+
+// List narrows the query down by storefront and creation time, the other filters are applied to the rows read
+func (s SQLStore) List(f Filter) ([]Order, error) {
+	var where []string
+	var args []interface{}
+	if f.Storefront != "" {
+		args = append(args, f.Storefront)
+		where = append(where, "storefront = $"+strconv.Itoa(len(args)))
+	}
+	if !f.From.IsZero() {
+		args = append(args, f.From)
+		where = append(where, "created_at >= $"+strconv.Itoa(len(args)))
+	}
+	if !f.To.IsZero() {
+		args = append(args, f.To)
+		where = append(where, "created_at < $"+strconv.Itoa(len(args)))
+	}
+
+	query := `SELECT body FROM orders`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := make([]Order, 0)
+	for rows.Next() && (f.Limit == 0 || len(ret) < f.Limit) {
+		var body string
+		if err := rows.Scan(&body); err != nil {
+			return nil, err
+		}
+		var o Order
+		if err := json.Unmarshal([]byte(body), &o); err != nil {
+			return nil, err
+		}
+		if f.Matches(o) {
+			ret = append(ret, o)
+		}
+	}
+	return ret, rows.Err()
+}
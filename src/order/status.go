@@ -0,0 +1,93 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrUnknownStatus     = errors.New("unknown order status")
+	ErrIllegalTransition = errors.New("illegal order status transition")
+)
+
+type Status string
+
+const (
+	Created        Status = "created"
+	PaymentPending Status = "payment_pending"
+	Paid           Status = "paid"
+	Fulfilled      Status = "fulfilled"
+	Cancelled      Status = "cancelled"
+	Refunded       Status = "refunded"
+)
+
+// transitions lists the statuses each status can move to, cancelled and refunded are final
+var transitions = map[Status][]Status{
+	Created:        {PaymentPending, Cancelled},
+	PaymentPending: {Paid, Cancelled},
+	Paid:           {Fulfilled, Refunded},
+	Fulfilled:      {Refunded},
+}
+
+func ParseStatus(s string) (Status, error) {
+	status := Status(s)
+	switch status {
+	case Created, PaymentPending, Paid, Fulfilled, Cancelled, Refunded:
+		return status, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnknownStatus, s)
+	}
+}
+
+// Releases tells whether orders moving to s give back what was redeemed for them, such as coupons,
+// so that orders that won't be paid for or were refunded don't use them up
+func (s Status) Releases() bool {
+	return s == Cancelled || s == Refunded
+}
+
+func (s Status) CanMoveTo(to Status) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition records a status change. Amount is what was charged or given back,
+// for transitions to paid and refunded
+type Transition struct {
+	From   Status
+	To     Status
+	At     time.Time
+	Reason string
+	Amount int
+}
+
+// AmountDue is what the customer pays for the order, gifts and discounts already taken out and tax included
+func (o Order) AmountDue() int {
+	return o.Checkout.AmountDue()
+}
+
+// MoveTo returns the order in status to, with the transition added to its history and a new version
+func (o Order) MoveTo(to Status, reason string, at time.Time) (Order, error) {
+	from := o.Status
+	if from == "" {
+		// Orders stored before statuses existed
+		from = Created
+	}
+	if !from.CanMoveTo(to) {
+		return o, fmt.Errorf("%w: %s to %s", ErrIllegalTransition, from, to)
+	}
+
+	t := Transition{From: from, To: to, At: at, Reason: reason}
+	if to == Paid || to == Refunded {
+		t.Amount = o.AmountDue()
+	}
+
+	o.History = append(append([]Transition(nil), o.History...), t)
+	o.Status = to
+	o.Version++
+	return o, nil
+}
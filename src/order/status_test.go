@@ -0,0 +1,51 @@
+package order
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/checkout"
+)
+
+func TestOrderMoveTo(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		from       Status
+		to         Status
+		wantErr    error
+		wantAmount int
+	}{
+		{name: "Should wait for payment", from: Created, to: PaymentPending},
+		{name: "Should cancel created orders", from: Created, to: Cancelled},
+		{name: "Should not pay before payment is pending", from: Created, to: Paid, wantErr: ErrIllegalTransition},
+		{name: "Should record the amount paid", from: PaymentPending, to: Paid, wantAmount: 90},
+		{name: "Should not cancel paid orders", from: Paid, to: Cancelled, wantErr: ErrIllegalTransition},
+		{name: "Should record the amount refunded", from: Fulfilled, to: Refunded, wantAmount: 90},
+		{name: "Should not leave refunded orders", from: Refunded, to: Paid, wantErr: ErrIllegalTransition},
+		{name: "Should treat orders without status as created", from: "", to: PaymentPending},
+	}
+
+	at := time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := Order{Status: tt.from, Version: 3, Checkout: checkout.CheckoutResponse{TotalAmount: 100, TotalDiscount: 10}}
+
+			got, err := o.MoveTo(tt.to, "test", at)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("%s: Incorrect error: want=%v, got=%v", tt.name, tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if got.Status != tt.to || got.Version != 4 || len(got.History) != 1 {
+				t.Errorf("%s: Incorrect order: got=%+v", tt.name, got)
+			}
+			if got.History[0].Amount != tt.wantAmount {
+				t.Errorf("%s: Incorrect Amount: want=%d, got=%d", tt.name, tt.wantAmount, got.History[0].Amount)
+			}
+		})
+	}
+}
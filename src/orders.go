@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/coupon"
+	"github.com/gussf/backend-challenge/src/order"
+	"github.com/gussf/backend-challenge/src/promotion"
+	"github.com/gussf/backend-challenge/src/purchase"
+	"github.com/gussf/backend-challenge/src/quote"
+)
+
+type OrderJSONResponse struct {
+	Id           string                   `json:"id"`
+	Created_at   string                   `json:"created_at"`
+	Storefront   string                   `json:"storefront,omitempty"`
+	Coupon_codes []string                 `json:"coupon_codes,omitempty"`
+	Quote_id     string                   `json:"quote_id,omitempty"`
+	Status       string                   `json:"status"`
+	Version      int                      `json:"version"`
+	History      []TransitionJSONResponse `json:"history"`
+	CheckoutJSONResponse
+}
+
+type TransitionJSONResponse struct {
+	From   string `json:"from,omitempty"`
+	To     string `json:"to"`
+	At     string `json:"at"`
+	Reason string `json:"reason"`
+	Amount int    `json:"amount,omitempty"`
+}
+
+// QuoteConfirmationJSONRequest confirms a quote instead of pricing the order again
+type QuoteConfirmationJSONRequest struct {
+	Quote_token string `json:"quote_token"`
+}
+
+// TransitionJSONRequest moves an order to Status, Version being the one last read by the caller
+type TransitionJSONRequest struct {
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	Version int    `json:"version"`
+}
+
+type OrderListJSONResponse struct {
+	Orders []OrderJSONResponse `json:"orders"`
+}
+
+// Orders confirms a checkout request into an order on POST, and lists orders on GET (admins only)
+func (router ECommerceRouter) Orders(w http.ResponseWriter, r *http.Request) {
+
+	if router.orderSvc == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Orders are not enabled"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		router.ConfirmOrder(w, r)
+	case http.MethodGet:
+		router.ListOrders(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte("Only GET and POST methods are allowed"))
+	}
+}
+
+func (router ECommerceRouter) ConfirmOrder(w http.ResponseWriter, r *http.Request) {
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to read request: " + err.Error()))
+		return
+	}
+	var quoteReq QuoteConfirmationJSONRequest
+	if json.Unmarshal(body, &quoteReq) == nil && quoteReq.Quote_token != "" {
+		router.ConfirmQuote(w, r, quoteReq.Quote_token)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	checkoutReq, ok := router.ParseAndValidateCheckoutRequest(w, r)
+	if !ok {
+		return
+	}
+
+	o, err := router.orderSvc.Confirm(checkoutReq)
+	switch err {
+	case nil:
+	case order.ErrEmptyOrder, order.ErrWhatIfOrder:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to confirm order: " + err.Error()))
+		return
+	default:
+		log.Printf("Failed to save order: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to save order"))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.Header().Add("Location", "/orders/"+o.Id)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ConvertOrderToOrderJSONResponse(o))
+}
+
+// ConfirmQuote honours the prices of the quote. Tampered quotes get 400, expired ones 410, quotes priced
+// for another customer than the caller 403, and quotes that were already confirmed or whose coupons,
+// gifts or purchase limits ran out in the meantime get 409
+func (router ECommerceRouter) ConfirmQuote(w http.ResponseWriter, r *http.Request, token string) {
+
+	if router.quotes == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Quotes are not enabled"))
+		return
+	}
+
+	q, err := router.quotes.Verify(token)
+	switch err {
+	case nil:
+	case quote.ErrQuoteExpired:
+		w.WriteHeader(http.StatusGone)
+		w.Write([]byte(err.Error()))
+		return
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	// The quote has the prices of the customer it was asked by, such as their price list
+	customerId, err := router.CustomerId(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Failed to authenticate customer: " + err.Error()))
+		return
+	}
+	if customerId != q.Request.CustomerId {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("Quote was priced for another customer"))
+		return
+	}
+
+	o, err := router.orderSvc.ConfirmQuote(q)
+	switch {
+	case err == nil:
+	case errors.Is(err, order.ErrEmptyOrder):
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to confirm order: " + err.Error()))
+		return
+	case errors.Is(err, order.ErrQuoteUsed), errors.Is(err, coupon.ErrUsageLimitReached), errors.Is(err, coupon.ErrCouponNotFound),
+		errors.Is(err, promotion.ErrGiftLimitReached), errors.Is(err, purchase.ErrLimitReached):
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("Failed to confirm quote: " + err.Error()))
+		return
+	default:
+		log.Printf("Failed to save order from quote=%s: %v", q.Id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to save order"))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.Header().Add("Location", "/orders/"+o.Id)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ConvertOrderToOrderJSONResponse(o))
+}
+
+func (router ECommerceRouter) ListOrders(w http.ResponseWriter, r *http.Request) {
+
+	if !router.IsAdmin(r) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("Listing orders is only allowed for admins"))
+		return
+	}
+
+	filter, err := ParseOrderFilterFromQuery(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to parse filters: " + err.Error()))
+		return
+	}
+
+	orders, err := router.orderSvc.List(filter)
+	if err != nil {
+		log.Printf("Failed to list orders: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to list orders"))
+		return
+	}
+
+	resp := OrderListJSONResponse{Orders: make([]OrderJSONResponse, 0, len(orders))}
+	for _, o := range orders {
+		resp.Orders = append(resp.Orders, ConvertOrderToOrderJSONResponse(o))
+	}
+	w.Header().Add("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Order returns the order at /orders/{id}, and changes its status through /orders/{id}/transitions
+func (router ECommerceRouter) Order(w http.ResponseWriter, r *http.Request) {
+
+	if router.orderSvc == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Orders are not enabled"))
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/orders/")
+	if strings.HasSuffix(id, "/transitions") {
+		router.TransitionOrder(w, r, strings.TrimSuffix(id, "/transitions"))
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte("Only GET method is allowed"))
+		return
+	}
+
+	o, err := router.orderSvc.Find(id)
+	switch err {
+	case nil:
+	case order.ErrOrderNotFound:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Order not found: " + id))
+		return
+	default:
+		log.Printf("Failed to find order=%s: %v", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to find order"))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ConvertOrderToOrderJSONResponse(o))
+}
+
+// TransitionOrder is only allowed for admins. Illegal transitions get 422, and transitions
+// made on an outdated version get 409 so that the caller reads the order again
+func (router ECommerceRouter) TransitionOrder(w http.ResponseWriter, r *http.Request, id string) {
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte("Only POST method is allowed"))
+		return
+	}
+
+	if !router.IsAdmin(r) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("Changing orders is only allowed for admins"))
+		return
+	}
+
+	var req TransitionJSONRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to parse request: " + err.Error()))
+		return
+	}
+
+	status, err := order.ParseStatus(req.Status)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	o, err := router.orderSvc.Transition(id, status, req.Reason, req.Version)
+	switch {
+	case err == nil:
+	case errors.Is(err, order.ErrOrderNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Order not found: " + id))
+		return
+	case errors.Is(err, order.ErrVersionConflict):
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(err.Error()))
+		return
+	case errors.Is(err, order.ErrIllegalTransition):
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(err.Error()))
+		return
+	default:
+		log.Printf("Failed to change order=%s: %v", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to change order"))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ConvertOrderToOrderJSONResponse(o))
+}
+
+// ParseOrderFilterFromQuery reads storefront, product_id, coupon_code, status, from, to (RFC 3339) and limit
+func ParseOrderFilterFromQuery(r *http.Request) (order.Filter, error) {
+	var f order.Filter
+	var err error
+	q := r.URL.Query()
+
+	f.Storefront = q.Get("storefront")
+	f.CouponCode = q.Get("coupon_code")
+	if v := q.Get("status"); v != "" {
+		if f.Status, err = order.ParseStatus(v); err != nil {
+			return f, err
+		}
+	}
+	if v := q.Get("product_id"); v != "" {
+		if f.ProductId, err = strconv.Atoi(v); err != nil {
+			return f, err
+		}
+	}
+	if v := q.Get("from"); v != "" {
+		if f.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return f, err
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if f.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return f, err
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+func ConvertOrderToOrderJSONResponse(o order.Order) OrderJSONResponse {
+	resp := OrderJSONResponse{
+		Id:                   o.Id,
+		Created_at:           o.CreatedAt.Format(time.RFC3339),
+		Storefront:           o.Storefront,
+		Coupon_codes:         o.CouponCodes,
+		Quote_id:             o.QuoteId,
+		Status:               string(o.Status),
+		Version:              o.Version,
+		History:              make([]TransitionJSONResponse, 0, len(o.History)),
+		CheckoutJSONResponse: ConvertCheckoutResponseToCheckoutJSONResponse(&o.Checkout),
+	}
+	for _, t := range o.History {
+		resp.History = append(resp.History, TransitionJSONResponse{
+			From:   string(t.From),
+			To:     string(t.To),
+			At:     t.At.Format(time.RFC3339),
+			Reason: t.Reason,
+			Amount: t.Amount,
+		})
+	}
+	// The zone name doesn't survive storage, only its offset does
+	resp.Time_zone = o.TimeZone
+	return resp
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/checkout"
+	"github.com/gussf/backend-challenge/src/clock"
+	"github.com/gussf/backend-challenge/src/customer"
+	"github.com/gussf/backend-challenge/src/order"
+	"github.com/gussf/backend-challenge/src/promotion"
+	"github.com/gussf/backend-challenge/src/quote"
+	"github.com/gussf/backend-challenge/src/repository"
+)
+
+func TestConfirmQuoteCustomer(t *testing.T) {
+
+	now := time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)
+	cl := clock.FixedClock{T: now}
+	signer := quote.NewSigner([]byte("quote-key"), time.Hour, cl)
+	customers := customer.NewAuthenticator([]byte("customer-key"), cl)
+	checkoutSvc := checkout.NewCheckoutService(repository.InMemoryRepository{}, nil, promotion.NewCalendar())
+	orderSvc := order.NewOrderService(checkoutSvc, order.NewMemoryStore(), order.WithClock(cl))
+	router := NewECommerceRouter(checkoutSvc, WithOrders(orderSvc), WithQuotes(signer), WithCustomers(customers))
+
+	priced := &checkout.CheckoutResponse{
+		Products:    []checkout.ProductResponse{{Id: 1, Quantity: 1, UnitAmount: 100, TotalAmount: 100}},
+		TotalAmount: 100,
+		EvaluatedAt: now,
+	}
+	_, janeQuote, _ := signer.Sign(checkout.CheckoutRequest{CustomerId: "jane"}, priced)
+	_, anonymousQuote, _ := signer.Sign(checkout.CheckoutRequest{}, priced)
+	jane, _ := customers.Sign("jane", time.Hour)
+	john, _ := customers.Sign("john", time.Hour)
+
+	tests := []struct {
+		name          string
+		quote         string
+		customerToken string
+		wantStatus    int
+	}{
+		{name: "Anonymous caller can't confirm a customer quote", quote: janeQuote, wantStatus: http.StatusForbidden},
+		{name: "Another customer can't confirm it", quote: janeQuote, customerToken: john, wantStatus: http.StatusForbidden},
+		{name: "Invalid customer token", quote: janeQuote, customerToken: "forged", wantStatus: http.StatusUnauthorized},
+		{name: "Customer of the quote confirms it", quote: janeQuote, customerToken: jane, wantStatus: http.StatusCreated},
+		{name: "Customer can't confirm an anonymous quote", quote: anonymousQuote, customerToken: jane, wantStatus: http.StatusForbidden},
+		{name: "Anonymous quote is confirmed anonymously", quote: anonymousQuote, wantStatus: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"quote_token": "`+tt.quote+`"}`))
+		if tt.customerToken != "" {
+			req.Header.Set("X-Customer-Token", tt.customerToken)
+		}
+		rec := httptest.NewRecorder()
+		router.Orders(rec, req)
+
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: Incorrect status: want=%d, got=%d (%s)", tt.name, tt.wantStatus, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package pricing
+
+import "github.com/gussf/backend-challenge/src/money"
+
+// Line is what the engine needs to know about a cart line
+type Line struct {
+	ProductId  int
+	Quantity   int
+	UnitAmount int
+	// Remaining is what is left to pay for the line after previous discounts
+	Remaining int
+}
+
+// Result is a discount one rule gives to one line, Line being its index in the cart
+type Result struct {
+	Line   int
+	RuleId string
+	Amount int
+}
+
+// Engine evaluates rules in the order they were defined, each one on what previous rules left to pay
+type Engine struct {
+	rules []Rule
+	mode  money.RoundingMode
+}
+
+func NewEngine(rules []Rule, mode money.RoundingMode) Engine {
+	return Engine{rules: rules, mode: mode}
+}
+
+func (e Engine) Rules() []Rule {
+	return e.rules
+}
+
+// Evaluate doesn't modify lines, it returns every discount that should be given to them
+// by the rules without a set and the rules of the enabled sets
+func (e Engine) Evaluate(lines []Line, enabledSets map[string]bool) []Result {
+	remaining := make([]Line, len(lines))
+	copy(remaining, lines)
+
+	var results []Result
+	for _, rule := range e.rules {
+		if rule.Set != "" && !enabledSets[rule.Set] {
+			continue
+		}
+		for _, r := range e.evaluateRule(rule, remaining) {
+			if r.Amount > remaining[r.Line].Remaining {
+				r.Amount = remaining[r.Line].Remaining
+			}
+			if r.Amount <= 0 {
+				continue
+			}
+			remaining[r.Line].Remaining -= r.Amount
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+func (e Engine) evaluateRule(rule Rule, lines []Line) []Result {
+	switch rule.Type {
+	case BuyXPayY:
+		return evaluateBuyXPayY(rule, lines)
+	case BuyXGetY:
+		return evaluateBuyXGetY(rule, lines)
+	case Tiered:
+		return e.evaluateTiered(rule, lines)
+	case Bundle:
+		return evaluateBundle(rule, lines)
+	}
+	return nil
+}
+
+// Free units are valued at what is left to pay per unit, so they stack with previous discounts
+func freeUnitsAmount(l Line, free int) int {
+	if free > l.Quantity {
+		free = l.Quantity
+	}
+	return l.Remaining * free / l.Quantity
+}
+
+// productLines are the lines of one product, taken together so that splitting
+// a product over several lines doesn't change what rules give it
+type productLines struct {
+	Line
+	lines []int
+}
+
+// groupByProduct merges the lines of each product the rule applies to, in the order products first appear
+func groupByProduct(rule Rule, lines []Line) []productLines {
+	var groups []productLines
+	index := make(map[int]int)
+	for i, l := range lines {
+		if !rule.AppliesTo(l.ProductId) || l.Quantity == 0 {
+			continue
+		}
+		g, ok := index[l.ProductId]
+		if !ok {
+			g = len(groups)
+			index[l.ProductId] = g
+			groups = append(groups, productLines{Line: Line{ProductId: l.ProductId, UnitAmount: l.UnitAmount}})
+		}
+		groups[g].Quantity += l.Quantity
+		groups[g].Remaining += l.Remaining
+		groups[g].lines = append(groups[g].lines, i)
+	}
+	return groups
+}
+
+// split gives amount back to the lines of the product proportionally to what is left to pay for them
+func (g productLines) split(rule Rule, lines []Line, amount int) []Result {
+	if amount <= 0 || g.Remaining <= 0 {
+		return nil
+	}
+
+	numerators := make([]int64, len(g.lines))
+	for j, i := range g.lines {
+		numerators[j] = int64(amount) * int64(lines[i].Remaining)
+	}
+	parts := money.Floor.Allocate(numerators, int64(g.Remaining))
+
+	var results []Result
+	for j, i := range g.lines {
+		results = append(results, Result{Line: i, RuleId: rule.Id, Amount: int(parts[j])})
+	}
+	return results
+}
+
+func evaluateBuyXPayY(rule Rule, lines []Line) []Result {
+	var results []Result
+	for _, g := range groupByProduct(rule, lines) {
+		free := g.Quantity / rule.Buy * (rule.Buy - rule.Pay)
+		results = append(results, g.split(rule, lines, freeUnitsAmount(g.Line, free))...)
+	}
+	return results
+}
+
+func evaluateBuyXGetY(rule Rule, lines []Line) []Result {
+	bought := 0
+	for _, l := range lines {
+		if rule.AppliesTo(l.ProductId) {
+			bought += l.Quantity
+		}
+	}
+
+	free := bought / rule.Buy * rule.Get
+	var results []Result
+	for i, l := range lines {
+		if free == 0 {
+			break
+		}
+		if l.ProductId != rule.GetProductId || l.Quantity == 0 {
+			continue
+		}
+		units := free
+		if units > l.Quantity {
+			units = l.Quantity
+		}
+		free -= units
+		results = append(results, Result{Line: i, RuleId: rule.Id, Amount: freeUnitsAmount(l, units)})
+	}
+	return results
+}
+
+// Tiers are reached by the quantity of the product across every line of it
+func (e Engine) evaluateTiered(rule Rule, lines []Line) []Result {
+	var results []Result
+	for _, g := range groupByProduct(rule, lines) {
+		var best *Tier
+		for t := range rule.Tiers {
+			if g.Quantity >= rule.Tiers[t].MinQuantity && (best == nil || rule.Tiers[t].MinQuantity > best.MinQuantity) {
+				best = &rule.Tiers[t]
+			}
+		}
+		if best != nil {
+			results = append(results, g.split(rule, lines, e.mode.ApplyBasisPoints(g.Remaining, best.BasisPoints))...)
+		}
+	}
+	return results
+}
+
+// evaluateBundle prices complete bundles at BundlePrice. Lines of the same product are taken together,
+// and bundles are valued at what is left to pay for their units, so they stack with previous discounts.
+// The saving is split between the lines proportionally to the value of their units in the bundles
+func evaluateBundle(rule Rule, lines []Line) []Result {
+	bundles := -1
+	quantities := make(map[int]int, len(rule.ProductIds))
+	for _, id := range rule.ProductIds {
+		for _, l := range lines {
+			if l.ProductId == id {
+				quantities[id] += l.Quantity
+			}
+		}
+		if bundles == -1 || quantities[id] < bundles {
+			bundles = quantities[id]
+		}
+	}
+	if bundles <= 0 {
+		return nil
+	}
+
+	var bundled []int
+	var values []int64
+	var total int64
+	for _, id := range rule.ProductIds {
+		for i, l := range lines {
+			if l.ProductId != id || l.Quantity == 0 {
+				continue
+			}
+			value := int64(l.Remaining) * int64(bundles) / int64(quantities[id])
+			bundled = append(bundled, i)
+			values = append(values, value)
+			total += value
+		}
+	}
+
+	saving := total - int64(bundles)*int64(rule.BundlePrice)
+	if saving <= 0 {
+		return nil
+	}
+
+	numerators := make([]int64, len(values))
+	for j, v := range values {
+		numerators[j] = saving * v
+	}
+	parts := money.Floor.Allocate(numerators, total)
+
+	var results []Result
+	for j, i := range bundled {
+		results = append(results, Result{Line: i, RuleId: rule.Id, Amount: int(parts[j])})
+	}
+	return results
+}
@@ -0,0 +1,129 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/gussf/backend-challenge/src/money"
+)
+
+func line(productId int, quantity int, unit int) Line {
+	return Line{ProductId: productId, Quantity: quantity, UnitAmount: unit, Remaining: quantity * unit}
+}
+
+func TestEvaluate(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		rules []Rule
+		lines []Line
+		sets  map[string]bool
+		want  []Result
+	}{
+		{
+			name:  "Buy 3 pay 2",
+			rules: []Rule{{Id: "3for2", Type: BuyXPayY, ProductIds: []int{1}, Buy: 3, Pay: 2}},
+			lines: []Line{line(1, 7, 100)},
+			want:  []Result{{Line: 0, RuleId: "3for2", Amount: 200}},
+		},
+		{
+			name:  "Buy 3 pay 2 merges lines of the same product",
+			rules: []Rule{{Id: "3for2", Type: BuyXPayY, ProductIds: []int{1}, Buy: 3, Pay: 2}},
+			lines: []Line{line(1, 2, 100), line(2, 2, 100), line(1, 2, 100)},
+			want:  []Result{{Line: 0, RuleId: "3for2", Amount: 50}, {Line: 2, RuleId: "3for2", Amount: 50}},
+		},
+		{
+			name:  "Buy X get Y free, only as many as in the cart",
+			rules: []Rule{{Id: "bxgy", Type: BuyXGetY, ProductIds: []int{1}, Buy: 2, Get: 1, GetProductId: 2}},
+			lines: []Line{line(1, 6, 100), line(2, 2, 50)},
+			want:  []Result{{Line: 1, RuleId: "bxgy", Amount: 100}},
+		},
+		{
+			name: "Tiered uses the highest tier reached",
+			rules: []Rule{{Id: "tiers", Type: Tiered, ProductIds: []int{1}, Tiers: []Tier{
+				{MinQuantity: 5, BasisPoints: 500}, {MinQuantity: 10, BasisPoints: 1000}, {MinQuantity: 20, BasisPoints: 2000},
+			}}},
+			lines: []Line{line(1, 12, 100)},
+			want:  []Result{{Line: 0, RuleId: "tiers", Amount: 120}},
+		},
+		{
+			name:  "Tiered is reached across lines of the same product",
+			rules: []Rule{{Id: "tiers", Type: Tiered, ProductIds: []int{1}, Tiers: []Tier{{MinQuantity: 5, BasisPoints: 1000}}}},
+			lines: []Line{line(1, 3, 100), line(1, 2, 100)},
+			want:  []Result{{Line: 0, RuleId: "tiers", Amount: 30}, {Line: 1, RuleId: "tiers", Amount: 20}},
+		},
+		{
+			name:  "Bundle is split proportionally to unit prices",
+			rules: []Rule{{Id: "bundle", Type: Bundle, ProductIds: []int{1, 2}, BundlePrice: 300}},
+			lines: []Line{line(1, 2, 300), line(2, 1, 100)},
+			want:  []Result{{Line: 0, RuleId: "bundle", Amount: 75}, {Line: 1, RuleId: "bundle", Amount: 25}},
+		},
+		{
+			name:  "Bundle merges lines of the same product",
+			rules: []Rule{{Id: "bundle", Type: Bundle, ProductIds: []int{1, 2}, BundlePrice: 300}},
+			lines: []Line{line(1, 1, 300), line(2, 2, 100), line(1, 1, 300)},
+			want:  []Result{{Line: 0, RuleId: "bundle", Amount: 75}, {Line: 2, RuleId: "bundle", Amount: 75}, {Line: 1, RuleId: "bundle", Amount: 50}},
+		},
+		{
+			name:  "Bundle is valued at what is left to pay",
+			rules: []Rule{{Id: "bundle", Type: Bundle, ProductIds: []int{1, 2}, BundlePrice: 200}},
+			lines: []Line{{ProductId: 1, Quantity: 1, UnitAmount: 300, Remaining: 150}, line(2, 1, 100)},
+			want:  []Result{{Line: 0, RuleId: "bundle", Amount: 30}, {Line: 1, RuleId: "bundle", Amount: 20}},
+		},
+		{
+			name:  "Incomplete bundle",
+			rules: []Rule{{Id: "bundle", Type: Bundle, ProductIds: []int{1, 2}, BundlePrice: 300}},
+			lines: []Line{line(1, 2, 300)},
+		},
+		{
+			name: "Rules stack on what is left to pay",
+			rules: []Rule{
+				{Id: "3for2", Type: BuyXPayY, ProductIds: []int{1}, Buy: 3, Pay: 2},
+				{Id: "tiers", Type: Tiered, ProductIds: []int{1}, Tiers: []Tier{{MinQuantity: 3, BasisPoints: 1000}}},
+			},
+			lines: []Line{line(1, 3, 100)},
+			want:  []Result{{Line: 0, RuleId: "3for2", Amount: 100}, {Line: 0, RuleId: "tiers", Amount: 20}},
+		},
+		{
+			name: "Rules of disabled sets are skipped",
+			rules: []Rule{
+				{Id: "3for2", Type: BuyXPayY, ProductIds: []int{1}, Buy: 3, Pay: 2, Set: "black-friday"},
+				{Id: "tiers", Type: Tiered, ProductIds: []int{1}, Tiers: []Tier{{MinQuantity: 3, BasisPoints: 1000}}, Set: "xmas"},
+			},
+			lines: []Line{line(1, 3, 100)},
+			sets:  map[string]bool{"xmas": true},
+			want:  []Result{{Line: 0, RuleId: "tiers", Amount: 30}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewEngine(tt.rules, money.HalfUp).Evaluate(tt.lines, tt.sets)
+
+			if len(tt.want) != len(got) {
+				t.Fatalf("%s: Incorrect results: want=%v, got=%v", tt.name, tt.want, got)
+			}
+			for i := range got {
+				if tt.want[i] != got[i] {
+					t.Errorf("%s: Incorrect result %d: want=%v, got=%v", tt.name, i, tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+
+	invalid := []Rule{
+		{Type: BuyXPayY, ProductIds: []int{1}, Buy: 3, Pay: 2},
+		{Id: "a", Type: BuyXPayY, ProductIds: []int{1}, Buy: 2, Pay: 2},
+		{Id: "b", Type: Tiered, ProductIds: []int{1}},
+		{Id: "c", Type: Bundle, ProductIds: []int{1, 1}, BundlePrice: 10},
+		{Id: "d", Type: "unknown", ProductIds: []int{1}},
+	}
+
+	for _, r := range invalid {
+		if err := r.Validate(); err == nil {
+			t.Errorf("Rule should be invalid: %+v", r)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package pricing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/gussf/backend-challenge/src/money"
+)
+
+var ErrInvalidRule = errors.New("invalid pricing rule")
+
+type RuleType string
+
+const (
+	// BuyXPayY makes Buy-Pay units free for every Buy units of the same product, "buy 3 pay 2"
+	BuyXPayY RuleType = "buy_x_pay_y"
+	// BuyXGetY makes Get units of GetProductId free for every Buy units of the rule products
+	BuyXGetY RuleType = "buy_x_get_y"
+	// Tiered discounts a line by the highest tier its quantity reaches
+	Tiered RuleType = "tiered"
+	// Bundle sells one of each rule product together for BundlePrice
+	Bundle RuleType = "bundle"
+)
+
+type Tier struct {
+	MinQuantity int               `json:"min_quantity"`
+	BasisPoints money.BasisPoints `json:"basis_points"`
+}
+
+type Rule struct {
+	Id         string   `json:"id"`
+	Name       string   `json:"name"`
+	Type       RuleType `json:"type"`
+	ProductIds []int    `json:"product_ids"`
+	// Set makes the rule only apply while a promotion enables it, rules without one always apply
+	Set string `json:"set"`
+
+	Buy          int `json:"buy"`
+	Pay          int `json:"pay"`
+	Get          int `json:"get"`
+	GetProductId int `json:"get_product_id"`
+
+	Tiers       []Tier `json:"tiers"`
+	BundlePrice int    `json:"bundle_price"`
+}
+
+func (r Rule) AppliesTo(productId int) bool {
+	for _, id := range r.ProductIds {
+		if id == productId {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) Validate() error {
+	if r.Id == "" {
+		return fmt.Errorf("%w: missing id", ErrInvalidRule)
+	}
+	if len(r.ProductIds) == 0 {
+		return fmt.Errorf("%w: %s has no products", ErrInvalidRule, r.Id)
+	}
+
+	switch r.Type {
+	case BuyXPayY:
+		if r.Buy <= 0 || r.Pay < 0 || r.Pay >= r.Buy {
+			return fmt.Errorf("%w: %s must have buy > pay >= 0", ErrInvalidRule, r.Id)
+		}
+	case BuyXGetY:
+		if r.Buy <= 0 || r.Get <= 0 || r.GetProductId == 0 {
+			return fmt.Errorf("%w: %s must have buy, get and get_product_id", ErrInvalidRule, r.Id)
+		}
+	case Tiered:
+		if len(r.Tiers) == 0 {
+			return fmt.Errorf("%w: %s has no tiers", ErrInvalidRule, r.Id)
+		}
+		for _, t := range r.Tiers {
+			if t.MinQuantity <= 0 || t.BasisPoints < 0 || t.BasisPoints > money.OneHundredPercent {
+				return fmt.Errorf("%w: %s has an invalid tier", ErrInvalidRule, r.Id)
+			}
+		}
+	case Bundle:
+		if len(r.ProductIds) < 2 || r.BundlePrice < 0 {
+			return fmt.Errorf("%w: %s must have at least 2 products and a bundle_price", ErrInvalidRule, r.Id)
+		}
+		seen := make(map[int]bool, len(r.ProductIds))
+		for _, id := range r.ProductIds {
+			if seen[id] {
+				return fmt.Errorf("%w: %s has product %d more than once", ErrInvalidRule, r.Id, id)
+			}
+			seen[id] = true
+		}
+	default:
+		return fmt.Errorf("%w: %s has unknown type %s", ErrInvalidRule, r.Id, r.Type)
+	}
+	return nil
+}
+
+func LoadRulesFromFile(jsonFilePath string) ([]Rule, error) {
+
+	var rules []Rule
+
+	content, err := ioutil.ReadFile(jsonFilePath)
+	if err != nil {
+		return nil, errors.New("error opening file " + jsonFilePath + ": " + err.Error())
+	}
+
+	err = json.Unmarshal(content, &rules)
+	if err != nil {
+		return nil, errors.New("error unmarshalling json: " + err.Error())
+	}
+
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,161 @@
+package promotion
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Timestamps without an offset are read in the event time zone
+const localTimestampLayout = "2006-01-02T15:04:05"
+
+// FloatingTimeZone makes an event follow the zone it is evaluated in, instead of a fixed one
+const FloatingTimeZone = "customer"
+
+type Calendar struct {
+	events []Event
+}
+
+func NewCalendar(events ...Event) Calendar {
+	return Calendar{events: events}
+}
+
+// BlackFriday is a gift event lasting the whole day of month/day every year, in loc,
+// or in the zone it is evaluated in when loc is nil
+func BlackFriday(month time.Month, day int, loc *time.Location) Event {
+	return Event{
+		Id:         "black-friday",
+		Name:       "Black Friday",
+		Location:   loc,
+		Recurrence: &Recurrence{Month: month, Day: day},
+		Duration:   24 * time.Hour,
+		Effect:     Effect{Type: AddGift},
+	}
+}
+
+func (c Calendar) Events() []Event {
+	return c.events
+}
+
+// ActiveAt returns the events running at t, in the order they were defined
+func (c Calendar) ActiveAt(t time.Time) []Event {
+	var active []Event
+	for _, e := range c.events {
+		if e.ActiveAt(t) {
+			active = append(active, e)
+		}
+	}
+	return active
+}
+
+type eventJSON struct {
+	Id         string          `json:"id"`
+	Name       string          `json:"name"`
+	TimeZone   string          `json:"time_zone"`
+	Start      string          `json:"start"`
+	End        string          `json:"end"`
+	Recurrence *recurrenceJSON `json:"recurrence"`
+	StartTime  string          `json:"start_time"`
+	Duration   string          `json:"duration"`
+	Effect     Effect          `json:"effect"`
+}
+
+type recurrenceJSON struct {
+	Month   int    `json:"month"`
+	Day     int    `json:"day"`
+	Weekday string `json:"weekday"`
+	Week    int    `json:"week"`
+}
+
+func NewCalendarFromFile(jsonFilePath string) (Calendar, error) {
+
+	var content []eventJSON
+
+	file, err := ioutil.ReadFile(jsonFilePath)
+	if err != nil {
+		return Calendar{}, errors.New("error opening file " + jsonFilePath + ": " + err.Error())
+	}
+
+	err = json.Unmarshal(file, &content)
+	if err != nil {
+		return Calendar{}, errors.New("error unmarshalling json: " + err.Error())
+	}
+
+	var events []Event
+	for _, ej := range content {
+		e, err := ej.toEvent()
+		if err != nil {
+			return Calendar{}, err
+		}
+		if err := e.Validate(); err != nil {
+			return Calendar{}, err
+		}
+		events = append(events, e)
+	}
+
+	return NewCalendar(events...), nil
+}
+
+func (ej eventJSON) toEvent() (Event, error) {
+	e := Event{Id: ej.Id, Name: ej.Name, Effect: ej.Effect}
+
+	// Floating events keep their wall clock in UTC until they are evaluated
+	loc := time.UTC
+	if ej.TimeZone != FloatingTimeZone {
+		var err error
+		loc, err = time.LoadLocation(ej.TimeZone)
+		if err != nil || ej.TimeZone == "" {
+			return e, fmt.Errorf("%w: %s needs a valid time_zone (%s)", ErrInvalidEvent, ej.Id, ej.TimeZone)
+		}
+		e.Location = loc
+	}
+
+	var err error
+	if ej.Recurrence == nil {
+		if e.Start, err = parseTimestamp(ej.Start, loc); err != nil {
+			return e, fmt.Errorf("%w: %s start: %v", ErrInvalidEvent, ej.Id, err)
+		}
+		if e.End, err = parseTimestamp(ej.End, loc); err != nil {
+			return e, fmt.Errorf("%w: %s end: %v", ErrInvalidEvent, ej.Id, err)
+		}
+		return e, nil
+	}
+
+	e.Recurrence = &Recurrence{Month: time.Month(ej.Recurrence.Month), Day: ej.Recurrence.Day, Week: ej.Recurrence.Week}
+	if ej.Recurrence.Weekday != "" {
+		if e.Recurrence.Weekday, err = ParseWeekday(ej.Recurrence.Weekday); err != nil {
+			return e, err
+		}
+	}
+
+	if ej.StartTime != "" {
+		t, err := time.Parse("15:04", ej.StartTime)
+		if err != nil {
+			return e, fmt.Errorf("%w: %s start_time must be HH:MM", ErrInvalidEvent, ej.Id)
+		}
+		e.StartTime = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+
+	e.Duration = 24 * time.Hour
+	if ej.Duration != "" {
+		if e.Duration, err = time.ParseDuration(ej.Duration); err != nil {
+			return e, fmt.Errorf("%w: %s duration: %v", ErrInvalidEvent, ej.Id, err)
+		}
+	}
+
+	return e, nil
+}
+
+// parseTimestamp accepts RFC 3339 timestamps, or local ones that are read in loc.
+// Empty timestamps are the zero time, leaving that side of the event open
+func parseTimestamp(s string, loc *time.Location) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(localTimestampLayout, s, loc)
+}
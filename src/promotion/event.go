@@ -0,0 +1,164 @@
+package promotion
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/money"
+)
+
+var ErrInvalidEvent = errors.New("invalid promotion event")
+
+type EffectType string
+
+const (
+	// AddGift adds GiftProductId, or any gift from the repository when 0, to non empty checkouts
+	// that meet the GiftConditions
+	AddGift EffectType = "gift"
+	// StoreWideDiscount discounts BasisPoints from every product
+	StoreWideDiscount EffectType = "store_wide_discount"
+	// EnableRuleSet turns on the pricing rules of RuleSet
+	EnableRuleSet EffectType = "rule_set"
+)
+
+type Effect struct {
+	Type          EffectType        `json:"type"`
+	GiftProductId int               `json:"gift_product_id"`
+	BasisPoints   money.BasisPoints `json:"basis_points"`
+	RuleSet       string            `json:"rule_set"`
+	GiftConditions
+}
+
+// Recurrence repeats an event every year, either on a fixed Day of Month
+// or on the Week-th Weekday of Month, Week -1 being the last one
+type Recurrence struct {
+	Month   time.Month
+	Day     int
+	Weekday time.Weekday
+	Week    int
+}
+
+// Event is active from Start (inclusive) to End (exclusive), a zero Start or End leaving
+// that side open. Recurring events start every year at StartTime on the day given by
+// Recurrence, and last Duration.
+// Events without a Location are floating: their dates are wall clock times in
+// the zone of the instant they are evaluated at, usually the customer one
+type Event struct {
+	Id         string
+	Name       string
+	Location   *time.Location
+	Start      time.Time
+	End        time.Time
+	Recurrence *Recurrence
+	StartTime  time.Duration
+	Duration   time.Duration
+	Effect     Effect
+}
+
+// Occurrence returns the window of the event that may contain t, for recurring events
+// that is the one of the year of t, or of the previous year if it is still running
+func (e Event) Occurrence(t time.Time) (time.Time, time.Time) {
+	loc := e.Location
+	if loc == nil {
+		loc = t.Location()
+	}
+
+	if e.Recurrence == nil {
+		if e.Location == nil {
+			return inLocation(e.Start, loc), inLocation(e.End, loc)
+		}
+		return e.Start, e.End
+	}
+
+	year := t.In(loc).Year()
+	start := e.Recurrence.Date(year, loc).Add(e.StartTime)
+	if t.Before(start) {
+		start = e.Recurrence.Date(year-1, loc).Add(e.StartTime)
+	}
+	return start, start.Add(e.Duration)
+}
+
+func (e Event) IsFloating() bool {
+	return e.Location == nil
+}
+
+// inLocation keeps the wall clock of t, changing the instant it represents
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+func (e Event) ActiveAt(t time.Time) bool {
+	start, end := e.Occurrence(t)
+	if e.Recurrence == nil {
+		return (e.Start.IsZero() || !t.Before(start)) && (e.End.IsZero() || t.Before(end))
+	}
+	return !t.Before(start) && t.Before(end)
+}
+
+// Date is midnight of the day the recurrence falls on in year
+func (r Recurrence) Date(year int, loc *time.Location) time.Time {
+	if r.Day > 0 {
+		return time.Date(year, r.Month, r.Day, 0, 0, 0, 0, loc)
+	}
+
+	if r.Week < 0 {
+		last := time.Date(year, r.Month+1, 0, 0, 0, 0, 0, loc)
+		offset := (int(last.Weekday()) - int(r.Weekday) + 7) % 7
+		return last.AddDate(0, 0, -offset)
+	}
+
+	first := time.Date(year, r.Month, 1, 0, 0, 0, 0, loc)
+	offset := (int(r.Weekday) - int(first.Weekday()) + 7) % 7
+	return first.AddDate(0, 0, offset+7*(r.Week-1))
+}
+
+func (e Event) Validate() error {
+	if e.Id == "" {
+		return fmt.Errorf("%w: missing id", ErrInvalidEvent)
+	}
+
+	if e.Recurrence == nil && !e.Start.IsZero() && !e.End.IsZero() && !e.Start.Before(e.End) {
+		return fmt.Errorf("%w: %s must start before it ends", ErrInvalidEvent, e.Id)
+	}
+
+	if e.Recurrence != nil {
+		r := e.Recurrence
+		if r.Month < time.January || r.Month > time.December {
+			return fmt.Errorf("%w: %s has an invalid month", ErrInvalidEvent, e.Id)
+		}
+		if r.Day == 0 && (r.Week == 0 || r.Week > 4 || r.Week < -1) {
+			return fmt.Errorf("%w: %s needs a day, or a weekday and week", ErrInvalidEvent, e.Id)
+		}
+		if e.Duration <= 0 {
+			return fmt.Errorf("%w: %s needs a positive duration", ErrInvalidEvent, e.Id)
+		}
+	}
+
+	switch e.Effect.Type {
+	case AddGift:
+		g := e.Effect.GiftConditions
+		if g.Quantity < 0 || g.MinCartAmount < 0 || g.SpendStep < 0 || g.MaxPerOrder < 0 || g.DailyCap < 0 || g.MaxPerCustomer < 0 {
+			return fmt.Errorf("%w: %s has negative gift conditions", ErrInvalidEvent, e.Id)
+		}
+	case EnableRuleSet:
+	case StoreWideDiscount:
+		if e.Effect.BasisPoints <= 0 || e.Effect.BasisPoints > money.OneHundredPercent {
+			return fmt.Errorf("%w: %s has an invalid discount", ErrInvalidEvent, e.Id)
+		}
+	default:
+		return fmt.Errorf("%w: %s has unknown effect %s", ErrInvalidEvent, e.Id, e.Effect.Type)
+	}
+
+	return nil
+}
+
+func ParseWeekday(s string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), s) {
+			return d, nil
+		}
+	}
+	return time.Sunday, fmt.Errorf("%w: unknown weekday %s", ErrInvalidEvent, s)
+}
@@ -0,0 +1,114 @@
+package promotion
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecurrenceDate(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		recurrence Recurrence
+		year       int
+		want       time.Time
+	}{
+		{name: "Fixed day", recurrence: Recurrence{Month: time.November, Day: 9}, year: 2021, want: time.Date(2021, 11, 9, 0, 0, 0, 0, time.UTC)},
+		{name: "Fourth friday of november", recurrence: Recurrence{Month: time.November, Weekday: time.Friday, Week: 4}, year: 2021, want: time.Date(2021, 11, 26, 0, 0, 0, 0, time.UTC)},
+		{name: "Fourth friday when the month starts on a friday", recurrence: Recurrence{Month: time.November, Weekday: time.Friday, Week: 4}, year: 2019, want: time.Date(2019, 11, 22, 0, 0, 0, 0, time.UTC)},
+		{name: "Last monday of may", recurrence: Recurrence{Month: time.May, Weekday: time.Monday, Week: -1}, year: 2022, want: time.Date(2022, 5, 30, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got := tt.recurrence.Date(tt.year, time.UTC)
+		if !tt.want.Equal(got) {
+			t.Errorf("%s: Incorrect date: want=%s, got=%s", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestEventActiveAt(t *testing.T) {
+	saoPaulo, _ := time.LoadLocation("America/Sao_Paulo")
+	bf := Event{
+		Id:         "black-friday",
+		Location:   saoPaulo,
+		Recurrence: &Recurrence{Month: time.November, Weekday: time.Friday, Week: 4},
+		Duration:   24 * time.Hour,
+		Effect:     Effect{Type: AddGift},
+	}
+	newYear := Event{
+		Id:         "new-year",
+		Location:   time.UTC,
+		Recurrence: &Recurrence{Month: time.December, Day: 31},
+		StartTime:  18 * time.Hour,
+		Duration:   12 * time.Hour,
+		Effect:     Effect{Type: AddGift},
+	}
+
+	tests := []struct {
+		name  string
+		event Event
+		at    time.Time
+		want  bool
+	}{
+		{name: "Start is inclusive", event: bf, at: time.Date(2021, 11, 26, 0, 0, 0, 0, saoPaulo), want: true},
+		{name: "End is exclusive", event: bf, at: time.Date(2021, 11, 27, 0, 0, 0, 0, saoPaulo), want: false},
+		{name: "Same instant in another zone", event: bf, at: time.Date(2021, 11, 27, 2, 0, 0, 0, time.UTC), want: true},
+		{name: "Day before", event: bf, at: time.Date(2021, 11, 25, 23, 59, 0, 0, saoPaulo), want: false},
+		{name: "Crossing into the next year", event: newYear, at: time.Date(2022, 1, 1, 3, 0, 0, 0, time.UTC), want: true},
+		{name: "After crossing into the next year", event: newYear, at: time.Date(2022, 1, 1, 6, 0, 0, 0, time.UTC), want: false},
+	}
+
+	for _, tt := range tests {
+		got := tt.event.ActiveAt(tt.at)
+		if tt.want != got {
+			t.Errorf("%s: Incorrect ActiveAt: want=%t, got=%t", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestNewCalendarFromFile(t *testing.T) {
+	calendar, err := NewCalendarFromFile("../../data/promotions.json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	active := calendar.ActiveAt(time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC))
+	if len(active) != 2 {
+		t.Errorf("Incorrect active events on black friday: want=2, got=%d", len(active))
+	}
+
+	active = calendar.ActiveAt(time.Date(2021, 12, 22, 12, 0, 0, 0, time.UTC))
+	if len(active) != 1 || active[0].Effect.Type != StoreWideDiscount {
+		t.Errorf("Incorrect active events on christmas sale: got=%+v", active)
+	}
+}
+
+func TestFloatingEventActiveAt(t *testing.T) {
+	saoPaulo, _ := time.LoadLocation("America/Sao_Paulo")
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	bf := BlackFriday(time.November, 26, nil)
+	sale := Event{
+		Id:     "sale",
+		Start:  time.Date(2021, 12, 20, 0, 0, 0, 0, time.UTC),
+		End:    time.Date(2021, 12, 21, 0, 0, 0, 0, time.UTC),
+		Effect: Effect{Type: AddGift},
+	}
+
+	// 2021-11-26 20:00 in Sao Paulo is already 2021-11-27 in Tokyo
+	instant := time.Date(2021, 11, 26, 20, 0, 0, 0, saoPaulo)
+	if !bf.ActiveAt(instant) {
+		t.Errorf("Floating event should be active for a customer in Sao Paulo")
+	}
+	if bf.ActiveAt(instant.In(tokyo)) {
+		t.Errorf("Floating event should not be active for a customer in Tokyo")
+	}
+
+	instant = time.Date(2021, 12, 20, 1, 0, 0, 0, tokyo)
+	if !sale.ActiveAt(instant) {
+		t.Errorf("Floating one-off event should be active at local wall clock")
+	}
+	if sale.ActiveAt(instant.In(saoPaulo)) {
+		t.Errorf("Floating one-off event should not be active yet in Sao Paulo")
+	}
+}
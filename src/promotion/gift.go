@@ -0,0 +1,140 @@
+package promotion
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrGiftLimitReached = errors.New("promotion gift limit was reached")
+
+// GiftConditions make a gift effect a gift-with-purchase, every condition left at its zero value is ignored
+type GiftConditions struct {
+	// Quantity of gifts given when the conditions are met, defaults to 1
+	Quantity      int `json:"gift_quantity"`
+	MinCartAmount int `json:"min_cart_amount"`
+	// Every one of RequiredProductIds must be in the cart
+	RequiredProductIds []int `json:"required_product_ids"`
+	// At least one product of each of RequiredCategories must be in the cart
+	RequiredCategories []string `json:"required_categories"`
+	// SpendStep multiplies Quantity by how many times the cart amount reaches it
+	SpendStep   int `json:"spend_step"`
+	MaxPerOrder int `json:"max_per_order"`
+	// DailyCap limits the gifts given by the promotion in a day, across every order
+	DailyCap int `json:"daily_cap"`
+	// MaxPerCustomer limits the gifts each customer gets from the promotion, anonymous checkouts get none
+	MaxPerCustomer int `json:"max_per_customer"`
+}
+
+// Cart is what gift conditions are checked against
+type Cart struct {
+	// Amount is what is left to pay for the products that aren't gifts
+	Amount     int
+	ProductIds []int
+	Categories []string
+}
+
+// GiftQuantity returns how many gifts the cart earns, before the daily cap
+func (g GiftConditions) GiftQuantity(cart Cart) int {
+	if len(cart.ProductIds) == 0 || cart.Amount < g.MinCartAmount {
+		return 0
+	}
+	if !containsAllInts(cart.ProductIds, g.RequiredProductIds) || !containsAllStrings(cart.Categories, g.RequiredCategories) {
+		return 0
+	}
+
+	quantity := g.Quantity
+	if quantity <= 0 {
+		quantity = 1
+	}
+	if g.SpendStep > 0 {
+		quantity *= cart.Amount / g.SpendStep
+	}
+	if g.MaxPerOrder > 0 && quantity > g.MaxPerOrder {
+		quantity = g.MaxPerOrder
+	}
+	return quantity
+}
+
+func containsAllInts(have []int, want []int) bool {
+	set := make(map[int]bool, len(have))
+	for _, h := range have {
+		set[h] = true
+	}
+	for _, w := range want {
+		if !set[w] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAllStrings(have []string, want []string) bool {
+	set := make(map[string]bool, len(have))
+	for _, h := range have {
+		set[h] = true
+	}
+	for _, w := range want {
+		if !set[w] {
+			return false
+		}
+	}
+	return true
+}
+
+// GiftCounter keeps how many gifts each promotion gave in each bucket, such as a day or a customer.
+// It is safe for concurrent use
+type GiftCounter struct {
+	mu    sync.Mutex
+	given map[string]int
+}
+
+// Limit caps the gifts a promotion gives in Bucket
+type Limit struct {
+	Bucket string
+	Cap    int
+}
+
+func NewGiftCounter() *GiftCounter {
+	return &GiftCounter{given: make(map[string]int)}
+}
+
+// Reserve grants up to quantity gifts without going over any of the limits, and returns how many were granted.
+// Nothing is recorded when record is false, so previews don't use up the caps
+func (c *GiftCounter) Reserve(eventId string, quantity int, record bool, limits ...Limit) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, l := range limits {
+		if left := l.Cap - c.given[eventId+"/"+l.Bucket]; quantity > left {
+			quantity = left
+		}
+	}
+	if quantity < 0 {
+		quantity = 0
+	}
+	if record {
+		for _, l := range limits {
+			c.given[eventId+"/"+l.Bucket] += quantity
+		}
+	}
+	return quantity
+}
+
+func (c *GiftCounter) Given(eventId string, bucket string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.given[eventId+"/"+bucket]
+}
+
+// Release gives back quantity gifts reserved with the same limits, for orders that were not placed in the end
+func (c *GiftCounter) Release(eventId string, quantity int, limits ...Limit) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, l := range limits {
+		key := eventId + "/" + l.Bucket
+		if c.given[key] -= quantity; c.given[key] <= 0 {
+			delete(c.given, key)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package promotion
+
+import (
+	"testing"
+)
+
+func TestGiftConditionsGiftQuantity(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		conditions GiftConditions
+		cart       Cart
+		want       int
+	}{
+		{
+			name: "Should give one gift without conditions",
+			cart: Cart{Amount: 100, ProductIds: []int{1}},
+			want: 1,
+		},
+		{
+			name: "Should not give gifts to empty carts",
+			cart: Cart{},
+			want: 0,
+		},
+		{
+			name:       "Should not give gifts below the minimum cart amount",
+			conditions: GiftConditions{MinCartAmount: 101},
+			cart:       Cart{Amount: 100, ProductIds: []int{1}},
+			want:       0,
+		},
+		{
+			name:       "Should require every product",
+			conditions: GiftConditions{RequiredProductIds: []int{1, 2}},
+			cart:       Cart{Amount: 100, ProductIds: []int{1, 3}},
+			want:       0,
+		},
+		{
+			name:       "Should require every category",
+			conditions: GiftConditions{RequiredCategories: []string{"home", "food"}},
+			cart:       Cart{Amount: 100, ProductIds: []int{1, 2}, Categories: []string{"food", "home"}},
+			want:       1,
+		},
+		{
+			name:       "Should scale gifts by spend",
+			conditions: GiftConditions{Quantity: 2, SpendStep: 100},
+			cart:       Cart{Amount: 350, ProductIds: []int{1}},
+			want:       6,
+		},
+		{
+			name:       "Should limit gifts per order",
+			conditions: GiftConditions{SpendStep: 100, MaxPerOrder: 2},
+			cart:       Cart{Amount: 350, ProductIds: []int{1}},
+			want:       2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.conditions.GiftQuantity(tt.cart)
+			if tt.want != got {
+				t.Errorf("%s: Incorrect GiftQuantity: want=%d, got=%d", tt.name, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGiftCounterReserve(t *testing.T) {
+	counter := NewGiftCounter()
+
+	want := 2
+	got := counter.Reserve("gwp", 2, false, Limit{Bucket: "2021-11-26", Cap: 3})
+	if want != got {
+		t.Errorf("Incorrect previewed gifts: want=%d, got=%d", want, got)
+	}
+
+	want = 2
+	got = counter.Reserve("gwp", 2, true, Limit{Bucket: "2021-11-26", Cap: 3})
+	if want != got {
+		t.Errorf("Incorrect reserved gifts: want=%d, got=%d", want, got)
+	}
+
+	want = 1
+	got = counter.Reserve("gwp", 2, true, Limit{Bucket: "2021-11-26", Cap: 3})
+	if want != got {
+		t.Errorf("Incorrect reserved gifts after the cap: want=%d, got=%d", want, got)
+	}
+
+	want = 2
+	got = counter.Reserve("gwp", 2, true, Limit{Bucket: "2021-11-27", Cap: 3})
+	if want != got {
+		t.Errorf("Incorrect reserved gifts on the next day: want=%d, got=%d", want, got)
+	}
+
+	// The customer limit is reached first, so the day isn't used up beyond it
+	want = 1
+	got = counter.Reserve("gwp", 2, true, Limit{Bucket: "2021-11-28", Cap: 3}, Limit{Bucket: "customer/c1", Cap: 1})
+	if want != got {
+		t.Errorf("Incorrect reserved gifts with a customer limit: want=%d, got=%d", want, got)
+	}
+	if day := counter.Given("gwp", "2021-11-28"); day != 1 {
+		t.Errorf("Incorrect gifts given in the day: want=1, got=%d", day)
+	}
+
+	counter.Release("gwp", 1, Limit{Bucket: "2021-11-28", Cap: 3}, Limit{Bucket: "customer/c1", Cap: 1})
+	if day, cust := counter.Given("gwp", "2021-11-28"), counter.Given("gwp", "customer/c1"); day != 0 || cust != 0 {
+		t.Errorf("Incorrect gifts given after a release: want=0, got day=%d customer=%d", day, cust)
+	}
+}
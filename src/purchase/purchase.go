@@ -0,0 +1,206 @@
+package purchase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidLimit    = errors.New("invalid purchase limit")
+	ErrUnknownAction   = errors.New("unknown purchase limit action")
+	ErrDuplicatedLimit = errors.New("product has more than one purchase limit")
+	ErrLimitReached    = errors.New("customer purchase limit was reached")
+)
+
+// Action is what happens to a line asking for more than its limit allows
+type Action string
+
+const (
+	// Cap lowers the quantity to what the limit allows
+	Cap Action = "cap"
+	// Reject drops the whole line
+	Reject Action = "reject"
+)
+
+func ParseAction(s string) (Action, error) {
+	switch strings.ToLower(s) {
+	case "", string(Cap):
+		return Cap, nil
+	case string(Reject):
+		return Reject, nil
+	default:
+		return Cap, ErrUnknownAction
+	}
+}
+
+// Limit caps how many units of a product can be bought, every limit left at 0 being ignored.
+// MaxPerCustomer counts what each customer bought in the last Window, or ever when Window is 0
+type Limit struct {
+	ProductId      int
+	MaxPerOrder    int
+	MaxPerCustomer int
+	Window         time.Duration
+	Action         Action
+}
+
+// Limits are the purchase limits by product id
+type Limits struct {
+	byProduct map[int]Limit
+}
+
+func NewLimits(limits ...Limit) (Limits, error) {
+	l := Limits{byProduct: make(map[int]Limit, len(limits))}
+	for _, limit := range limits {
+		if _, ok := l.byProduct[limit.ProductId]; ok {
+			return l, fmt.Errorf("%w: %d", ErrDuplicatedLimit, limit.ProductId)
+		}
+		l.byProduct[limit.ProductId] = limit
+	}
+	return l, nil
+}
+
+func (l Limits) For(productId int) (Limit, bool) {
+	limit, ok := l.byProduct[productId]
+	return limit, ok
+}
+
+func (l Limits) Len() int {
+	return len(l.byProduct)
+}
+
+// limitJSON is one limit of the purchase limits file, Window being a duration such as "24h"
+type limitJSON struct {
+	ProductId      int    `json:"product_id"`
+	MaxPerOrder    int    `json:"max_per_order"`
+	MaxPerCustomer int    `json:"max_per_customer"`
+	Window         string `json:"window"`
+	Action         string `json:"action"`
+}
+
+// LoadLimitsFromFile reads the purchase limits, in the format {"limits": [...]}
+func LoadLimitsFromFile(jsonFilePath string) (Limits, error) {
+
+	var content struct {
+		Limits []limitJSON `json:"limits"`
+	}
+
+	file, err := ioutil.ReadFile(jsonFilePath)
+	if err != nil {
+		return Limits{}, errors.New("error opening file " + jsonFilePath + ": " + err.Error())
+	}
+
+	err = json.Unmarshal(file, &content)
+	if err != nil {
+		return Limits{}, errors.New("error unmarshalling json: " + err.Error())
+	}
+
+	limits := make([]Limit, 0, len(content.Limits))
+	for _, lj := range content.Limits {
+		l, err := newLimit(lj)
+		if err != nil {
+			return Limits{}, err
+		}
+		limits = append(limits, l)
+	}
+	return NewLimits(limits...)
+}
+
+func newLimit(lj limitJSON) (Limit, error) {
+	l := Limit{ProductId: lj.ProductId, MaxPerOrder: lj.MaxPerOrder, MaxPerCustomer: lj.MaxPerCustomer}
+	if lj.ProductId <= 0 {
+		return l, fmt.Errorf("%w: missing product_id", ErrInvalidLimit)
+	}
+	if lj.MaxPerOrder < 0 || lj.MaxPerCustomer < 0 {
+		return l, fmt.Errorf("%w: product %d has a negative maximum", ErrInvalidLimit, lj.ProductId)
+	}
+
+	var err error
+	if lj.Window != "" {
+		if l.Window, err = time.ParseDuration(lj.Window); err != nil || l.Window < 0 {
+			return l, fmt.Errorf("%w: product %d window %q", ErrInvalidLimit, lj.ProductId, lj.Window)
+		}
+	}
+	if l.Action, err = ParseAction(lj.Action); err != nil {
+		return l, fmt.Errorf("%w: product %d action %q", err, lj.ProductId, lj.Action)
+	}
+	return l, nil
+}
+
+type purchase struct {
+	At       time.Time
+	Quantity int
+}
+
+// Counter keeps what each customer bought of each limited product. It is safe for concurrent use
+type Counter struct {
+	mu        sync.Mutex
+	purchases map[string][]purchase
+}
+
+func NewCounter() *Counter {
+	return &Counter{purchases: make(map[string][]purchase)}
+}
+
+// Reserve grants up to quantity units of the product to the customer without going over
+// l.MaxPerCustomer, and returns how many were granted. Limits that reject grant all of
+// quantity or nothing. Nothing is recorded when record is false, so previews don't use up the limit.
+// pending are units granted in the same order that are not recorded, such as earlier lines of a preview
+func (c *Counter) Reserve(customerId string, l Limit, quantity int, pending int, now time.Time, record bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := fmt.Sprintf("%s/%d", customerId, l.ProductId)
+	var kept []purchase
+	bought := pending
+	for _, p := range c.purchases[key] {
+		if l.Window == 0 || now.Sub(p.At) < l.Window {
+			kept = append(kept, p)
+			bought += p.Quantity
+		}
+	}
+
+	if left := l.MaxPerCustomer - bought; quantity > left {
+		quantity = left
+		if l.Action == Reject {
+			quantity = 0
+		}
+	}
+	if quantity < 0 {
+		quantity = 0
+	}
+
+	// The history is only pruned when recording, previews as of a later instant must leave it alone
+	if !record {
+		return quantity
+	}
+	if quantity > 0 {
+		kept = append(kept, purchase{At: now, Quantity: quantity})
+	}
+	c.purchases[key] = kept
+	return quantity
+}
+
+// Release gives back quantity units reserved for the customer, the most recent first,
+// for orders that were not placed in the end
+func (c *Counter) Release(customerId string, l Limit, quantity int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := fmt.Sprintf("%s/%d", customerId, l.ProductId)
+	purchases := c.purchases[key]
+	for quantity > 0 && len(purchases) > 0 {
+		last := &purchases[len(purchases)-1]
+		if last.Quantity > quantity {
+			last.Quantity -= quantity
+			break
+		}
+		quantity -= last.Quantity
+		purchases = purchases[:len(purchases)-1]
+	}
+	c.purchases[key] = purchases
+}
@@ -0,0 +1,113 @@
+package purchase
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCounterReserve(t *testing.T) {
+	now := time.Date(2026, 11, 27, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		limit     Limit
+		bought    []int
+		boughtAgo time.Duration
+		quantity  int
+		want      int
+	}{
+		{name: "Below the limit", limit: Limit{ProductId: 1, MaxPerCustomer: 3}, quantity: 2, want: 2},
+		{name: "Capped to what is left", limit: Limit{ProductId: 1, MaxPerCustomer: 3}, bought: []int{2}, quantity: 2, want: 1},
+		{name: "Rejected when above what is left", limit: Limit{ProductId: 1, MaxPerCustomer: 3, Action: Reject}, bought: []int{2}, quantity: 2, want: 0},
+		{name: "Nothing left", limit: Limit{ProductId: 1, MaxPerCustomer: 3}, bought: []int{1, 2}, quantity: 1, want: 0},
+		{name: "Purchases out of the window", limit: Limit{ProductId: 1, MaxPerCustomer: 3, Window: 24 * time.Hour}, bought: []int{3}, boughtAgo: 25 * time.Hour, quantity: 3, want: 3},
+		{name: "Purchases in the window", limit: Limit{ProductId: 1, MaxPerCustomer: 3, Window: 24 * time.Hour}, bought: []int{3}, boughtAgo: 23 * time.Hour, quantity: 3, want: 0},
+		{name: "Purchases without a window never expire", limit: Limit{ProductId: 1, MaxPerCustomer: 3}, bought: []int{3}, boughtAgo: 1000 * time.Hour, quantity: 1, want: 0},
+	}
+
+	for _, tt := range tests {
+		c := NewCounter()
+		for _, q := range tt.bought {
+			c.Reserve("jane", Limit{ProductId: tt.limit.ProductId, MaxPerCustomer: 100}, q, 0, now.Add(-tt.boughtAgo), true)
+		}
+
+		got := c.Reserve("jane", tt.limit, tt.quantity, 0, now, true)
+		if tt.want != got {
+			t.Errorf("%s: Incorrect quantity granted: want=%d, got=%d", tt.name, tt.want, got)
+		}
+		if other := c.Reserve("john", tt.limit, tt.quantity, 0, now, false); other != tt.quantity {
+			t.Errorf("%s: Other customers should not share the limit: want=%d, got=%d", tt.name, tt.quantity, other)
+		}
+	}
+}
+
+func TestCounterReserveWithoutRecording(t *testing.T) {
+	now := time.Date(2026, 11, 27, 10, 0, 0, 0, time.UTC)
+	c := NewCounter()
+	limit := Limit{ProductId: 1, MaxPerCustomer: 2}
+
+	c.Reserve("jane", limit, 2, 0, now, false)
+	if got := c.Reserve("jane", limit, 2, 0, now, true); got != 2 {
+		t.Errorf("Previews should not use up the limit: want=%d, got=%d", 2, got)
+	}
+	if got := c.Reserve("john", limit, 2, 2, now, false); got != 0 {
+		t.Errorf("Previews should count the units pending in the same order: want=%d, got=%d", 0, got)
+	}
+
+	// A preview far in the future sees the purchases expired, but they are still there afterwards
+	windowed := Limit{ProductId: 2, MaxPerCustomer: 2, Window: 24 * time.Hour}
+	c.Reserve("jane", windowed, 2, 0, now, true)
+	if got := c.Reserve("jane", windowed, 2, 0, now.Add(1000*time.Hour), false); got != 2 {
+		t.Errorf("Purchases out of the window should not count: want=%d, got=%d", 2, got)
+	}
+	if got := c.Reserve("jane", windowed, 1, 0, now, false); got != 0 {
+		t.Errorf("Previews should not forget purchases: want=%d, got=%d", 0, got)
+	}
+}
+
+func TestCounterRelease(t *testing.T) {
+	now := time.Date(2026, 11, 27, 10, 0, 0, 0, time.UTC)
+	c := NewCounter()
+	limit := Limit{ProductId: 1, MaxPerCustomer: 5}
+
+	c.Reserve("jane", limit, 2, 0, now, true)
+	c.Reserve("jane", limit, 2, 0, now, true)
+	c.Release("jane", limit, 3)
+	if got := c.Reserve("jane", limit, 5, 0, now, false); got != 4 {
+		t.Errorf("Incorrect quantity left after a release: want=%d, got=%d", 4, got)
+	}
+}
+
+func TestNewLimit(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		limit      limitJSON
+		wantWindow time.Duration
+		wantAction Action
+		wantErr    error
+	}{
+		{name: "Defaults to cap without window", limit: limitJSON{ProductId: 1, MaxPerOrder: 2}, wantAction: Cap},
+		{name: "Window and reject", limit: limitJSON{ProductId: 1, MaxPerCustomer: 2, Window: "24h", Action: "reject"}, wantWindow: 24 * time.Hour, wantAction: Reject},
+		{name: "Missing product", limit: limitJSON{MaxPerOrder: 2}, wantErr: ErrInvalidLimit},
+		{name: "Negative maximum", limit: limitJSON{ProductId: 1, MaxPerOrder: -1}, wantErr: ErrInvalidLimit},
+		{name: "Invalid window", limit: limitJSON{ProductId: 1, Window: "a day"}, wantErr: ErrInvalidLimit},
+		{name: "Unknown action", limit: limitJSON{ProductId: 1, Action: "ban"}, wantErr: ErrUnknownAction},
+	}
+
+	for _, tt := range tests {
+		l, err := newLimit(tt.limit)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Incorrect error: want=%v, got=%v", tt.name, tt.wantErr, err)
+			continue
+		}
+		if err == nil && (l.Window != tt.wantWindow || l.Action != tt.wantAction) {
+			t.Errorf("%s: Incorrect limit: want window=%s action=%s, got=%+v", tt.name, tt.wantWindow, tt.wantAction, l)
+		}
+	}
+
+	if _, err := NewLimits(Limit{ProductId: 1}, Limit{ProductId: 1}); !errors.Is(err, ErrDuplicatedLimit) {
+		t.Errorf("Incorrect error for duplicated limits: want=%v, got=%v", ErrDuplicatedLimit, err)
+	}
+}
@@ -0,0 +1,110 @@
+package quote
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/checkout"
+	"github.com/gussf/backend-challenge/src/clock"
+)
+
+var (
+	ErrTamperedQuote = errors.New("quote signature is invalid")
+	ErrQuoteExpired  = errors.New("quote expired")
+)
+
+// Quote freezes the prices of a checkout until ExpiresAt
+type Quote struct {
+	Id        string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	// TimeZone promotions were evaluated in, the checkout loses its zone name once encoded
+	TimeZone string
+	Request  checkout.CheckoutRequest
+	Checkout checkout.CheckoutResponse
+}
+
+// Signer turns quotes into tokens that can be handed to clients and trusted when they come back.
+// Tokens are the quote JSON and its HMAC-SHA256, both base64 encoded and separated by a dot
+type Signer struct {
+	key   []byte
+	ttl   time.Duration
+	clock clock.Clock
+}
+
+func NewSigner(key []byte, ttl time.Duration, cl clock.Clock) Signer {
+	return Signer{key: key, ttl: ttl, clock: cl}
+}
+
+// Sign returns the quote for the priced request, and its token
+func (s Signer) Sign(req checkout.CheckoutRequest, resp *checkout.CheckoutResponse) (Quote, string, error) {
+	now := s.clock.Now()
+	q := Quote{
+		Id:        NewId(),
+		IssuedAt:  now,
+		ExpiresAt: now.Add(s.ttl),
+		TimeZone:  resp.EvaluatedAt.Location().String(),
+		Request:   req,
+		Checkout:  *resp,
+	}
+	// The trace is only meant for whoever asked for the quote, and would make tokens much longer
+	q.Checkout.Trace = nil
+	q.Request.Explain = false
+
+	payload, err := json.Marshal(q)
+	if err != nil {
+		return q, "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return q, encoded + "." + base64.RawURLEncoding.EncodeToString(s.mac([]byte(encoded))), nil
+}
+
+// Verify returns the quote of token, as long as it was signed with the same key and hasn't expired
+func (s Signer) Verify(token string) (Quote, error) {
+	var q Quote
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return q, ErrTamperedQuote
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, s.mac([]byte(parts[0]))) {
+		return q, ErrTamperedQuote
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return q, ErrTamperedQuote
+	}
+	if err := json.Unmarshal(payload, &q); err != nil {
+		return q, ErrTamperedQuote
+	}
+
+	if !s.clock.Now().Before(q.ExpiresAt) {
+		return q, ErrQuoteExpired
+	}
+	return q, nil
+}
+
+func (s Signer) mac(b []byte) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write(b)
+	return h.Sum(nil)
+}
+
+// NewId returns a random id, orders made from the quote are named after it
+func NewId() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic("failed to generate quote id: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
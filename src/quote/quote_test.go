@@ -0,0 +1,49 @@
+package quote
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gussf/backend-challenge/src/checkout"
+	"github.com/gussf/backend-challenge/src/clock"
+)
+
+func TestSignerVerify(t *testing.T) {
+	cl := &clock.MovableClock{T: time.Date(2021, 11, 26, 12, 0, 0, 0, time.UTC)}
+	signer := NewSigner([]byte("secret"), 15*time.Minute, cl)
+
+	req := checkout.CheckoutRequest{Products: []checkout.ProductRequest{{Id: 1, Quantity: 2}}}
+	resp := &checkout.CheckoutResponse{TotalAmount: 200, TotalDiscount: 20, EvaluatedAt: cl.T}
+	q, token, err := signer.Sign(req, resp)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got, err := signer.Verify(token)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.Id != q.Id || got.Checkout.TotalDiscount != 20 || got.Request.Products[0].Quantity != 2 {
+		t.Errorf("Incorrect quote: want=%+v, got=%+v", q, got)
+	}
+
+	// Changing the payload, even to something valid, breaks the signature
+	parts := strings.Split(token, ".")
+	_, other, _ := signer.Sign(req, &checkout.CheckoutResponse{TotalAmount: 200, TotalDiscount: 200})
+	tampered := strings.Split(other, ".")[0] + "." + parts[1]
+	if _, err := signer.Verify(tampered); err != ErrTamperedQuote {
+		t.Errorf("Incorrect error for tampered quote: want=%v, got=%v", ErrTamperedQuote, err)
+	}
+	if _, err := NewSigner([]byte("other"), time.Hour, cl).Verify(token); err != ErrTamperedQuote {
+		t.Errorf("Incorrect error for quote signed with another key: want=%v, got=%v", ErrTamperedQuote, err)
+	}
+	if _, err := signer.Verify("garbage"); err != ErrTamperedQuote {
+		t.Errorf("Incorrect error for garbage: want=%v, got=%v", ErrTamperedQuote, err)
+	}
+
+	cl.T = cl.T.Add(15 * time.Minute)
+	if _, err := signer.Verify(token); err != ErrQuoteExpired {
+		t.Errorf("Incorrect error for stale quote: want=%v, got=%v", ErrQuoteExpired, err)
+	}
+}
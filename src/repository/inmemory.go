@@ -3,17 +3,22 @@ package repository
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"sort"
+
+	"github.com/gussf/backend-challenge/src/money"
 )
 
 type InMemoryRepository struct {
 	Products []ProductDAO
 }
 
-func NewInMemoryRepository(jsonFilePath string) (InMemoryRepository, error) {
+// NewInMemoryRepository loads a catalog priced in a single currency, products without one are assumed to be in it
+func NewInMemoryRepository(jsonFilePath string, currency string) (InMemoryRepository, error) {
 
 	ret := InMemoryRepository{}
+	currency = money.NormalizeCurrency(currency)
 
 	content, err := ioutil.ReadFile(jsonFilePath)
 	if err != nil {
@@ -25,6 +30,15 @@ func NewInMemoryRepository(jsonFilePath string) (InMemoryRepository, error) {
 		return ret, errors.New("error unmarshalling json: " + err.Error())
 	}
 
+	for i, p := range ret.Products {
+		switch money.NormalizeCurrency(p.Currency) {
+		case "", currency:
+			ret.Products[i].Currency = currency
+		default:
+			return ret, fmt.Errorf("product %d (%s): %w %s", p.Id, p.Currency, ErrCurrencyMismatch, currency)
+		}
+	}
+
 	return ret, nil
 }
 
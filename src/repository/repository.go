@@ -2,11 +2,14 @@ package repository
 
 import (
 	"errors"
+
+	"github.com/gussf/backend-challenge/src/money"
 )
 
 var (
-	ErrProductNotFound = errors.New("product not found in repository")
-	ErrNoGiftFound     = errors.New("no gift was found in repository")
+	ErrProductNotFound  = errors.New("product not found in repository")
+	ErrNoGiftFound      = errors.New("no gift was found in repository")
+	ErrCurrencyMismatch = errors.New("product currency differs from the catalog currency")
 )
 
 type ProductDAO struct {
@@ -14,7 +17,26 @@ type ProductDAO struct {
 	Title       string
 	Description string
 	Amount      int
-	Is_gift     bool
+	Currency    string
+	Category    string
+	// Tax_category decides the tax rate of the product, tax.DefaultCategory when empty
+	Tax_category string
+	// Weight and dimensions of one packaged unit, for shipping
+	Weight_grams int
+	Length_cm    int
+	Width_cm     int
+	Height_cm    int
+	Is_gift      bool
+	// No_discount keeps the product out of every discount, No_coupon only out of coupons,
+	// and No_gift_eligibility out of the conditions of gift promotions
+	No_discount         bool
+	No_coupon           bool
+	No_gift_eligibility bool
+}
+
+// Price is the unit price of the product, in the catalog currency once loaded by NewInMemoryRepository
+func (p ProductDAO) Price() money.Money {
+	return money.New(p.Amount, p.Currency)
 }
 
 type Repository interface {
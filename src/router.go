@@ -1,42 +1,275 @@
 package main
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"log"
+	"math"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/gussf/backend-challenge/src/cart"
 	"github.com/gussf/backend-challenge/src/checkout"
+	"github.com/gussf/backend-challenge/src/customer"
+	"github.com/gussf/backend-challenge/src/discount"
+	"github.com/gussf/backend-challenge/src/idempotency"
+	"github.com/gussf/backend-challenge/src/order"
+	"github.com/gussf/backend-challenge/src/quote"
+	"github.com/gussf/backend-challenge/src/shipping"
+	"github.com/gussf/backend-challenge/src/tax"
 )
 
 type CheckoutJSONResponse struct {
-	Total_amount               int                   `json:"total_amount"`
-	Total_amount_with_discount int                   `json:"total_amount_with_discount"`
-	Total_discount             int                   `json:"total_discount"`
-	Products                   []ProductJSONResponse `json:"products"`
+	Currency                   string                       `json:"currency"`
+	Total_amount               int                          `json:"total_amount"`
+	Total_amount_with_discount int                          `json:"total_amount_with_discount"`
+	Total_discount             int                          `json:"total_discount"`
+	Total_tax                  int                          `json:"total_tax"`
+	Total_amount_due           int                          `json:"total_amount_due"`
+	Tax_mode                   string                       `json:"tax_mode"`
+	Destination_region         string                       `json:"destination_region,omitempty"`
+	Total_shipping             int                          `json:"total_shipping"`
+	Shipping                   *ShippingOptionJSONResponse  `json:"shipping,omitempty"`
+	Shipping_options           []ShippingOptionJSONResponse `json:"shipping_options,omitempty"`
+	Shipping_address           *shipping.Address            `json:"shipping_address,omitempty"`
+	Customer                   *customer.Customer           `json:"customer,omitempty"`
+	Cart_discount              *CartDiscountJSONResponse    `json:"cart_discount,omitempty"`
+	Products                   []ProductJSONResponse        `json:"products"`
+	Presentment                *PresentmentJSONResponse     `json:"presentment,omitempty"`
+	Warnings                   []WarningJSONResponse        `json:"warnings,omitempty"`
+	Evaluated_at               string                       `json:"evaluated_at"`
+	Time_zone                  string                       `json:"time_zone"`
+	What_if                    bool                         `json:"what_if,omitempty"`
+	Quote                      *QuoteJSONResponse           `json:"quote,omitempty"`
+	Trace                      *TraceJSONResponse           `json:"trace,omitempty"`
+}
+
+// TraceJSONResponse is only present when an admin asked to explain the checkout
+type TraceJSONResponse struct {
+	Lines []LineTraceJSONResponse `json:"lines"`
+	Steps []TraceStepJSONResponse `json:"steps"`
+}
+
+type LineTraceJSONResponse struct {
+	Id               int                        `json:"id"`
+	Quantity         int                        `json:"quantity"`
+	Lookup           string                     `json:"lookup"`
+	Discount         *DiscountTraceJSONResponse `json:"discount,omitempty"`
+	Exact_discount   string                     `json:"exact_discount,omitempty"`
+	Rounded_discount int                        `json:"rounded_discount"`
+	Rounding_mode    string                     `json:"rounding_mode,omitempty"`
+}
+
+// Raw is null when the provider answered NaN or an infinite percentage, JSON has no way to encode them
+type DiscountTraceJSONResponse struct {
+	Provider     string   `json:"provider,omitempty"`
+	Raw          *float32 `json:"raw_percentage"`
+	Basis_points int      `json:"basis_points"`
+	Amount       int      `json:"amount,omitempty"`
+	Currency     string   `json:"currency,omitempty"`
+	Exact        bool     `json:"exact"`
+	Anomaly      string   `json:"anomaly,omitempty"`
+	Error        string   `json:"error,omitempty"`
+	Reason       string   `json:"reason,omitempty"`
+	Ignored      string   `json:"ignored,omitempty"`
+}
+
+type TraceStepJSONResponse struct {
+	Step      string `json:"step"`
+	Reference string `json:"reference"`
+	Outcome   string `json:"outcome"`
+	Amount    int    `json:"amount,omitempty"`
+}
+
+// QuoteJSONResponse is only present when the request asked for a quote, the token is what confirms it
+type QuoteJSONResponse struct {
+	Id         string `json:"id"`
+	Token      string `json:"token"`
+	Expires_at string `json:"expires_at"`
 }
 
 type ProductJSONResponse struct {
-	Id           int  `json:"id"`
-	Quantity     int  `json:"quantity"`
-	Unit_amount  int  `json:"unit_amount"`
-	Total_amount int  `json:"total_amount"`
-	Discount     int  `json:"discount"`
-	Is_gift      bool `json:"is_gift"`
+	Id           int                             `json:"id"`
+	Quantity     int                             `json:"quantity"`
+	Unit_amount  int                             `json:"unit_amount"`
+	Total_amount int                             `json:"total_amount"`
+	Discount     int                             `json:"discount"`
+	Cart_share   int                             `json:"cart_discount,omitempty"`
+	Tax          int                             `json:"tax"`
+	Tax_rate     int                             `json:"tax_rate_basis_points"`
+	Is_gift      bool                            `json:"is_gift"`
+	Presentment  *ProductPresentmentJSONResponse `json:"presentment,omitempty"`
+	Adjustments  []AdjustmentJSONResponse        `json:"adjustments,omitempty"`
+	Exclusions   []ExclusionJSONResponse         `json:"exclusions,omitempty"`
+}
+
+type AdjustmentJSONResponse struct {
+	Kind      string `json:"kind"`
+	Reference string `json:"reference"`
+	Amount    int    `json:"amount"`
+}
+
+// ExclusionJSONResponse is a discount or promotion the line was kept out of, Reason being the product flag
+type ExclusionJSONResponse struct {
+	Kind      string `json:"kind"`
+	Reference string `json:"reference,omitempty"`
+	Reason    string `json:"reason"`
+}
+
+type WarningJSONResponse struct {
+	Kind      string `json:"kind"`
+	Reference string `json:"reference"`
+	Reason    string `json:"reason"`
+	Message   string `json:"message"`
+}
+
+// CartDiscountJSONResponse is the order level discount, which is also part of the line discounts
+type CartDiscountJSONResponse struct {
+	Id     string `json:"id,omitempty"`
+	Reason string `json:"reason"`
+	Amount int    `json:"amount"`
+}
+
+type ShippingOptionJSONResponse struct {
+	Id             string `json:"id"`
+	Name           string `json:"name"`
+	Amount         int    `json:"amount"`
+	Estimated_days int    `json:"estimated_days,omitempty"`
+	Free           bool   `json:"free,omitempty"`
+}
+
+// Only present when the request asked for a currency other than the catalog one
+type PresentmentJSONResponse struct {
+	Currency                   string `json:"currency"`
+	Total_amount               int    `json:"total_amount"`
+	Total_amount_with_discount int    `json:"total_amount_with_discount"`
+	Total_discount             int    `json:"total_discount"`
+	Total_tax                  int    `json:"total_tax"`
+	Total_shipping             int    `json:"total_shipping"`
+	Total_amount_due           int    `json:"total_amount_due"`
+}
+
+type ProductPresentmentJSONResponse struct {
+	Currency     string `json:"currency"`
+	Unit_amount  int    `json:"unit_amount"`
+	Total_amount int    `json:"total_amount"`
+	Discount     int    `json:"discount"`
+	Tax          int    `json:"tax"`
 }
 
 type ECommerceRouter struct {
 	checkoutSvc checkout.CheckoutService
+	orderSvc    *order.OrderService
+	cartSvc     *cart.CartService
+	idempotency *idempotency.Cache
+	quotes      *quote.Signer
+	customers   *customer.Authenticator
+	overrides   *discount.OverrideStore
+	anomalies   *discount.AnomalyCounters
+	adminToken  string
+	whatIf      bool
+}
+
+// RouterOption configures optional behavior of the ECommerceRouter
+type RouterOption func(*ECommerceRouter)
+
+// WithAdminToken enables admin only features for requests sending "Authorization: Bearer <token>"
+func WithAdminToken(token string) RouterOption {
+	return func(router *ECommerceRouter) {
+		router.adminToken = token
+	}
 }
 
-func NewECommerceRouter(cs checkout.CheckoutService) ECommerceRouter {
-	return ECommerceRouter{
+// WithWhatIfPricing lets admins price carts as of another instant
+func WithWhatIfPricing(enabled bool) RouterOption {
+	return func(router *ECommerceRouter) {
+		router.whatIf = enabled
+	}
+}
+
+// WithOrders enables confirming checkouts into orders, and looking them up
+func WithOrders(svc order.OrderService) RouterOption {
+	return func(router *ECommerceRouter) {
+		router.orderSvc = &svc
+	}
+}
+
+// WithCarts enables server side carts
+func WithCarts(svc cart.CartService) RouterOption {
+	return func(router *ECommerceRouter) {
+		router.cartSvc = &svc
+	}
+}
+
+// WithQuotes lets checkout requests ask for signed quotes, which can be confirmed into orders
+func WithQuotes(signer quote.Signer) RouterOption {
+	return func(router *ECommerceRouter) {
+		router.quotes = &signer
+	}
+}
+
+// WithCustomers identifies customers sending "X-Customer-Token: <token>", see CustomerId
+func WithCustomers(a customer.Authenticator) RouterOption {
+	return func(router *ECommerceRouter) {
+		router.customers = &a
+	}
+}
+
+// WithDiscountOverrides lets admins pin the discount of products, see DiscountOverrides
+func WithDiscountOverrides(store *discount.OverrideStore) RouterOption {
+	return func(router *ECommerceRouter) {
+		router.overrides = store
+	}
+}
+
+// WithDiscountAnomalies lets admins read how many discounts validation had to fix, see DiscountAnomalies
+func WithDiscountAnomalies(counters *discount.AnomalyCounters) RouterOption {
+	return func(router *ECommerceRouter) {
+		router.anomalies = counters
+	}
+}
+
+// WithIdempotency stores responses to requests with an Idempotency-Key header, see Idempotent
+func WithIdempotency(cache *idempotency.Cache) RouterOption {
+	return func(router *ECommerceRouter) {
+		router.idempotency = cache
+	}
+}
+
+func NewECommerceRouter(cs checkout.CheckoutService, opts ...RouterOption) ECommerceRouter {
+	router := ECommerceRouter{
 		checkoutSvc: cs,
 	}
+	for _, opt := range opts {
+		opt(&router)
+	}
+	return router
 }
 
-func (router ECommerceRouter) Checkout(w http.ResponseWriter, r *http.Request) {
+// IsAdmin is always false when no admin token is configured
+func (router ECommerceRouter) IsAdmin(r *http.Request) bool {
+	if router.adminToken == "" {
+		return false
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(router.adminToken)) == 1
+}
 
-	enc := json.NewEncoder(w)
+// CustomerId returns who made the request, an empty id for anonymous requests.
+// Tokens that can't be verified are an error, instead of being taken as anonymous
+func (router ECommerceRouter) CustomerId(r *http.Request) (string, error) {
+	token := r.Header.Get("X-Customer-Token")
+	if token == "" {
+		return "", nil
+	}
+	if router.customers == nil {
+		return "", customer.ErrInvalidToken
+	}
+	return router.customers.Verify(token)
+}
+
+func (router ECommerceRouter) Checkout(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -44,24 +277,118 @@ func (router ECommerceRouter) Checkout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	checkoutReq, ok := router.ParseAndValidateCheckoutRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if checkoutReq.Quote {
+		router.Quote(w, checkoutReq)
+		return
+	}
+
+	resp := router.checkoutSvc.Quote(checkoutReq)
+	WriteJSON(w, ConvertCheckoutResponseToCheckoutJSONResponse(resp))
+}
+
+// WriteJSON encodes v as the response body, or answers 500 when it can't be encoded.
+// Nothing is written before v was fully encoded, so the status can still be changed
+func WriteJSON(w http.ResponseWriter, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Failed to encode response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode response"))
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(append(body, '\n'))
+}
+
+// Quote prices the request without redeeming anything, and signs the result so that it can be confirmed as is
+func (router ECommerceRouter) Quote(w http.ResponseWriter, checkoutReq checkout.CheckoutRequest) {
+
+	if router.quotes == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Quotes are not enabled"))
+		return
+	}
+
+	if checkoutReq.IsWhatIf() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Pricing as of another instant cannot be quoted"))
+		return
+	}
+
+	resp := router.checkoutSvc.Quote(checkoutReq)
+	q, token, err := router.quotes.Sign(checkoutReq, resp)
+	if err != nil {
+		log.Printf("Failed to sign quote: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to sign quote"))
+		return
+	}
+
+	jsonResp := ConvertCheckoutResponseToCheckoutJSONResponse(resp)
+	jsonResp.Quote = &QuoteJSONResponse{Id: q.Id, Token: token, Expires_at: q.ExpiresAt.Format(time.RFC3339)}
+	WriteJSON(w, jsonResp)
+}
+
+// ParseAndValidateCheckoutRequest writes the error response itself when the request can't be priced
+func (router ECommerceRouter) ParseAndValidateCheckoutRequest(w http.ResponseWriter, r *http.Request) (checkout.CheckoutRequest, bool) {
+
 	checkoutReq, err := ParseCheckoutRequestFromBody(r)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte("Failed to parse request: " + err.Error()))
 		log.Println("Failed to parse request: " + err.Error())
-		return
+		return checkoutReq, false
 	}
 
 	if checkoutReq.HasNoProducts() {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte("Request must have at least one product"))
-		return
+		return checkoutReq, false
 	}
 
-	resp := router.checkoutSvc.ProcessRequest(checkoutReq)
-	w.Header().Add("Content-Type", "application/json")
-	jsonResp := ConvertCheckoutResponseToCheckoutJSONResponse(resp)
-	enc.Encode(jsonResp)
+	checkoutReq.CustomerId, err = router.CustomerId(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Failed to authenticate customer: " + err.Error()))
+		return checkoutReq, false
+	}
+
+	if checkoutReq.IsWhatIf() && !(router.whatIf && router.IsAdmin(r)) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("Pricing as of another instant is only allowed for admins"))
+		return checkoutReq, false
+	}
+
+	if checkoutReq.Explain && !router.IsAdmin(r) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("Explaining prices is only allowed for admins"))
+		return checkoutReq, false
+	}
+
+	if !router.checkoutSvc.SupportsCurrency(checkoutReq.Currency) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Unsupported currency: " + checkoutReq.Currency))
+		return checkoutReq, false
+	}
+
+	if _, err := router.checkoutSvc.EvaluationTime(checkoutReq); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to resolve time zone: " + err.Error()))
+		return checkoutReq, false
+	}
+
+	if a := checkoutReq.ShippingAddress; a != nil && shipping.NormalizeCountry(a.Country) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(shipping.ErrMissingAddress.Error()))
+		return checkoutReq, false
+	}
+
+	return checkoutReq, true
 }
 
 func ParseCheckoutRequestFromBody(r *http.Request) (checkout.CheckoutRequest, error) {
@@ -85,20 +412,142 @@ func ConvertCheckoutResponseToCheckoutJSONResponse(r *checkout.CheckoutResponse)
 		resp.Products = append(resp.Products, ConvertProductResponseToProductJSONResponse(p))
 	}
 
+	resp.Currency = r.Currency
 	resp.Total_amount = r.TotalAmount
 	resp.Total_amount_with_discount = r.TotalAmount - r.TotalDiscount
 	resp.Total_discount = r.TotalDiscount
+	resp.Total_tax = r.TotalTax
+	resp.Total_amount_due = r.AmountDue()
+	resp.Tax_mode = r.TaxMode.String()
+	resp.Destination_region = r.TaxRegion
+	resp.Total_shipping = r.TotalShipping
+	resp.Shipping_address = r.ShippingAddress
+	resp.Customer = r.Customer
+	if d := r.CartDiscount; d != nil {
+		resp.Cart_discount = &CartDiscountJSONResponse{Id: d.Id, Reason: d.Reason, Amount: d.Amount}
+	}
+	if r.Shipping != nil {
+		option := ConvertShippingOptionToShippingOptionJSONResponse(*r.Shipping)
+		resp.Shipping = &option
+	}
+	for _, o := range r.ShippingOptions {
+		resp.Shipping_options = append(resp.Shipping_options, ConvertShippingOptionToShippingOptionJSONResponse(o))
+	}
+	resp.Evaluated_at = r.EvaluatedAt.Format(time.RFC3339)
+	resp.Time_zone = r.EvaluatedAt.Location().String()
+	resp.What_if = r.WhatIf
+
+	if r.Presentment != nil {
+		resp.Presentment = &PresentmentJSONResponse{
+			Currency:                   r.Presentment.TotalAmount.Currency,
+			Total_amount:               r.Presentment.TotalAmount.Amount,
+			Total_amount_with_discount: r.Presentment.TotalAmount.Amount - r.Presentment.TotalDiscount.Amount,
+			Total_discount:             r.Presentment.TotalDiscount.Amount,
+			Total_tax:                  r.Presentment.TotalTax.Amount,
+			Total_shipping:             r.Presentment.TotalShipping.Amount,
+			Total_amount_due:           r.Presentment.TotalAmount.Amount - r.Presentment.TotalDiscount.Amount + r.Presentment.TotalShipping.Amount,
+		}
+		if r.TaxMode == tax.Exclusive {
+			resp.Presentment.Total_amount_due += r.Presentment.TotalTax.Amount
+		}
+	}
+
+	if r.Trace != nil {
+		resp.Trace = ConvertTraceToTraceJSONResponse(r.Trace)
+	}
+
+	for _, w := range r.Warnings {
+		resp.Warnings = append(resp.Warnings, WarningJSONResponse{
+			Kind:      w.Kind,
+			Reference: w.Reference,
+			Reason:    w.Reason,
+			Message:   w.Message,
+		})
+	}
 
 	return resp
 }
 
+func ConvertShippingOptionToShippingOptionJSONResponse(o shipping.Option) ShippingOptionJSONResponse {
+	return ShippingOptionJSONResponse{Id: o.Id, Name: o.Name, Amount: o.Amount, Estimated_days: o.EstimatedDays, Free: o.Free}
+}
+
 func ConvertProductResponseToProductJSONResponse(p checkout.ProductResponse) ProductJSONResponse {
-	return ProductJSONResponse{
+	resp := ProductJSONResponse{
 		Id:           p.Id,
 		Quantity:     p.Quantity,
 		Unit_amount:  p.UnitAmount,
 		Total_amount: p.TotalAmount,
 		Discount:     p.DiscountGiven,
+		Cart_share:   p.CartDiscountShare(),
+		Tax:          p.TaxAmount,
+		Tax_rate:     int(p.TaxBasisPoints),
 		Is_gift:      p.IsGift,
 	}
+
+	if p.Presentment != nil {
+		resp.Presentment = &ProductPresentmentJSONResponse{
+			Currency:     p.Presentment.UnitAmount.Currency,
+			Unit_amount:  p.Presentment.UnitAmount.Amount,
+			Total_amount: p.Presentment.TotalAmount.Amount,
+			Discount:     p.Presentment.DiscountGiven.Amount,
+			Tax:          p.Presentment.TaxAmount.Amount,
+		}
+	}
+
+	for _, a := range p.Adjustments {
+		resp.Adjustments = append(resp.Adjustments, AdjustmentJSONResponse{Kind: a.Kind, Reference: a.Reference, Amount: a.Amount})
+	}
+	for _, e := range p.Exclusions {
+		resp.Exclusions = append(resp.Exclusions, ExclusionJSONResponse{Kind: e.Kind, Reference: e.Reference, Reason: e.Reason})
+	}
+
+	return resp
+}
+
+// FinitePercentage returns nil for percentages JSON can't encode
+func FinitePercentage(p float32) *float32 {
+	if f := float64(p); math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	return &p
+}
+
+func ConvertTraceToTraceJSONResponse(t *checkout.Trace) *TraceJSONResponse {
+	resp := &TraceJSONResponse{
+		Lines: make([]LineTraceJSONResponse, 0, len(t.Lines)),
+		Steps: make([]TraceStepJSONResponse, 0, len(t.Steps)),
+	}
+
+	for _, l := range t.Lines {
+		line := LineTraceJSONResponse{
+			Id:               l.ProductId,
+			Quantity:         l.Quantity,
+			Lookup:           l.Lookup,
+			Exact_discount:   l.ExactDiscount,
+			Rounded_discount: l.RoundedDiscount,
+			Rounding_mode:    l.RoundingMode,
+		}
+		if d := l.Discount; d != nil {
+			line.Discount = &DiscountTraceJSONResponse{
+				Provider:     d.Provider,
+				Raw:          FinitePercentage(d.Raw),
+				Basis_points: int(d.BasisPoints),
+				Amount:       d.AmountCents,
+				Currency:     d.Currency,
+				Exact:        d.Exact,
+				Anomaly:      d.Anomaly,
+				Error:        d.Error,
+				Reason:       d.Reason,
+				Ignored:      d.Ignored,
+			}
+		}
+		resp.Lines = append(resp.Lines, line)
+	}
+
+	for _, s := range t.Steps {
+		resp.Steps = append(resp.Steps, TraceStepJSONResponse{Step: s.Step, Reference: s.Reference, Outcome: s.Outcome, Amount: s.Amount})
+	}
+
+	return resp
 }
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gussf/backend-challenge/src/checkout"
+)
+
+func TestWriteJSONTrace(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		raw     float32
+		wantRaw string
+	}{
+		{name: "Finite percentages are kept", raw: 0.1, wantRaw: `"raw_percentage":0.1`},
+		{name: "NaN becomes null", raw: float32(math.NaN()), wantRaw: `"raw_percentage":null`},
+		{name: "Infinite percentages become null", raw: float32(math.Inf(1)), wantRaw: `"raw_percentage":null`},
+	}
+
+	for _, tt := range tests {
+		trace := &checkout.Trace{Lines: []checkout.LineTrace{{ProductId: 1, Quantity: 1, Lookup: checkout.LookupFound, Discount: &checkout.DiscountTrace{Raw: tt.raw}}}}
+		rec := httptest.NewRecorder()
+		WriteJSON(rec, ConvertTraceToTraceJSONResponse(trace))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: Incorrect status: want=%d, got=%d", tt.name, http.StatusOK, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), tt.wantRaw) {
+			t.Errorf("%s: Incorrect body: want=%s, got=%s", tt.name, tt.wantRaw, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	WriteJSON(rec, math.NaN())
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Incorrect status of an unencodable response: want=%d, got=%d", http.StatusInternalServerError, rec.Code)
+	}
+}
@@ -0,0 +1,88 @@
+package shipping
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// providerJSON is one option of the shipping rates file, Type being "flat_rate" or "weight_tiers".
+// FreeOver makes the option free for shipments of at least that amount
+type providerJSON struct {
+	Id                 string   `json:"id"`
+	Name               string   `json:"name"`
+	Type               string   `json:"type"`
+	EstimatedDays      int      `json:"estimated_days"`
+	Countries          []string `json:"countries"`
+	Amount             int      `json:"amount"`
+	Tiers              []Tier   `json:"tiers"`
+	DimensionalDivisor int      `json:"dimensional_divisor"`
+	FreeOver           int      `json:"free_over"`
+}
+
+// LoadProvidersFromFile reads the shipping options, which are offered in the order they are defined
+func LoadProvidersFromFile(jsonFilePath string) ([]ShippingRateProvider, error) {
+
+	var content []providerJSON
+
+	file, err := ioutil.ReadFile(jsonFilePath)
+	if err != nil {
+		return nil, errors.New("error opening file " + jsonFilePath + ": " + err.Error())
+	}
+
+	err = json.Unmarshal(file, &content)
+	if err != nil {
+		return nil, errors.New("error unmarshalling json: " + err.Error())
+	}
+
+	seen := make(map[string]bool)
+	providers := make([]ShippingRateProvider, 0, len(content))
+	for _, pj := range content {
+		if seen[pj.Id] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicatedOption, pj.Id)
+		}
+		seen[pj.Id] = true
+
+		p, err := newProvider(pj)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+
+	return providers, nil
+}
+
+func newProvider(pj providerJSON) (ShippingRateProvider, error) {
+	if pj.Id == "" {
+		return nil, fmt.Errorf("%w: missing id", ErrInvalidProvider)
+	}
+	if pj.Amount < 0 || pj.FreeOver < 0 || pj.DimensionalDivisor < 0 {
+		return nil, fmt.Errorf("%w: %s has negative values", ErrInvalidProvider, pj.Id)
+	}
+
+	svc := Service{Id: pj.Id, Name: pj.Name, EstimatedDays: pj.EstimatedDays, Countries: pj.Countries}
+	var p ShippingRateProvider
+	switch pj.Type {
+	case "flat_rate":
+		p = FlatRate{Service: svc, Amount: pj.Amount}
+	case "weight_tiers":
+		if len(pj.Tiers) == 0 {
+			return nil, fmt.Errorf("%w: %s has no tiers", ErrInvalidProvider, pj.Id)
+		}
+		for i, t := range pj.Tiers {
+			if t.Amount < 0 || t.UpToGrams <= 0 || (i > 0 && t.UpToGrams <= pj.Tiers[i-1].UpToGrams) {
+				return nil, fmt.Errorf("%w: %s tiers must have growing weights and non-negative amounts", ErrInvalidProvider, pj.Id)
+			}
+		}
+		p = WeightTiers{Service: svc, Tiers: pj.Tiers, DimensionalDivisor: pj.DimensionalDivisor}
+	default:
+		return nil, fmt.Errorf("%w: %s (%s)", ErrUnknownProvider, pj.Id, pj.Type)
+	}
+
+	if pj.FreeOver > 0 {
+		p = FreeOverThreshold{Provider: p, Threshold: pj.FreeOver}
+	}
+	return p, nil
+}
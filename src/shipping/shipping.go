@@ -0,0 +1,206 @@
+package shipping
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrUnavailable      = errors.New("shipping option is not available for this shipment")
+	ErrUnknownProvider  = errors.New("unknown shipping provider type")
+	ErrInvalidProvider  = errors.New("invalid shipping provider")
+	ErrUnknownOption    = errors.New("shipping option not found")
+	ErrNoOptions        = errors.New("no shipping option is available for this shipment")
+	ErrMissingAddress   = errors.New("shipping address needs a country")
+	ErrDuplicatedOption = errors.New("shipping option id is already used")
+)
+
+// Address is where the order is shipped to, Country being an ISO 3166 code such as "US"
+type Address struct {
+	Country    string `json:"country"`
+	Region     string `json:"region,omitempty"`
+	City       string `json:"city,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+	Street     string `json:"street,omitempty"`
+}
+
+func NormalizeCountry(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Dimensions of one packaged unit, in centimeters
+type Dimensions struct {
+	LengthCm int
+	WidthCm  int
+	HeightCm int
+}
+
+func (d Dimensions) Volume() int {
+	return d.LengthCm * d.WidthCm * d.HeightCm
+}
+
+// Item is one line of the shipment, gifts ship along with the order at no cost
+type Item struct {
+	ProductId   int
+	Quantity    int
+	WeightGrams int
+	Dimensions  Dimensions
+	IsGift      bool
+}
+
+// Shipment is what providers price, Amount being what the customer pays for the goods after discounts
+type Shipment struct {
+	Address Address
+	Items   []Item
+	Amount  int
+}
+
+// WeightGrams is the weight that is charged for, gifts left out. With a divisor, in cubic
+// centimeters per kilogram, units are charged by their dimensional weight when it is greater
+func (s Shipment) WeightGrams(divisor int) int {
+	total := 0
+	for _, item := range s.Items {
+		if item.IsGift {
+			continue
+		}
+		weight := item.WeightGrams
+		if divisor > 0 {
+			if dimensional := item.Dimensions.Volume() * 1000 / divisor; dimensional > weight {
+				weight = dimensional
+			}
+		}
+		total += weight * item.Quantity
+	}
+	return total
+}
+
+// Option is a way the shipment can be delivered and what it costs
+type Option struct {
+	Id            string
+	Name          string
+	Amount        int
+	EstimatedDays int
+	// Free tells the option would cost something, but the order qualified for free shipping
+	Free bool
+}
+
+// ShippingRateProvider prices shipments with one option, or ErrUnavailable when it can't deliver them
+type ShippingRateProvider interface {
+	Quote(s Shipment) (Option, error)
+}
+
+// Service describes an option that providers offer, Countries limiting where it delivers to
+type Service struct {
+	Id            string
+	Name          string
+	EstimatedDays int
+	Countries     []string
+}
+
+func (svc Service) Delivers(a Address) bool {
+	if len(svc.Countries) == 0 {
+		return true
+	}
+	for _, c := range svc.Countries {
+		if NormalizeCountry(c) == NormalizeCountry(a.Country) {
+			return true
+		}
+	}
+	return false
+}
+
+func (svc Service) option(amount int) Option {
+	return Option{Id: svc.Id, Name: svc.Name, Amount: amount, EstimatedDays: svc.EstimatedDays}
+}
+
+// FlatRate charges the same for any shipment
+type FlatRate struct {
+	Service
+	Amount int
+}
+
+func (f FlatRate) Quote(s Shipment) (Option, error) {
+	if !f.Delivers(s.Address) {
+		return Option{}, ErrUnavailable
+	}
+	return f.option(f.Amount), nil
+}
+
+// Tier charges Amount for shipments up to UpToGrams
+type Tier struct {
+	UpToGrams int `json:"up_to_grams"`
+	Amount    int `json:"amount"`
+}
+
+// WeightTiers charges by the first tier the shipment weight fits in, tiers being sorted by weight.
+// Shipments heavier than the last tier can't be delivered
+type WeightTiers struct {
+	Service
+	Tiers []Tier
+	// DimensionalDivisor enables dimensional weight, see Shipment.WeightGrams
+	DimensionalDivisor int
+}
+
+func (w WeightTiers) Quote(s Shipment) (Option, error) {
+	if !w.Delivers(s.Address) {
+		return Option{}, ErrUnavailable
+	}
+	weight := s.WeightGrams(w.DimensionalDivisor)
+	for _, t := range w.Tiers {
+		if weight <= t.UpToGrams {
+			return w.option(t.Amount), nil
+		}
+	}
+	return Option{}, ErrUnavailable
+}
+
+// FreeOverThreshold makes the option of Provider free for shipments of at least Threshold
+type FreeOverThreshold struct {
+	Provider  ShippingRateProvider
+	Threshold int
+}
+
+func (f FreeOverThreshold) Quote(s Shipment) (Option, error) {
+	o, err := f.Provider.Quote(s)
+	if err != nil {
+		return o, err
+	}
+	if s.Amount >= f.Threshold && o.Amount > 0 {
+		o.Amount, o.Free = 0, true
+	}
+	return o, nil
+}
+
+// Options quotes the shipment with every provider, in the order they were given, leaving out
+// the ones that can't deliver it
+func Options(providers []ShippingRateProvider, s Shipment) []Option {
+	var options []Option
+	for _, p := range providers {
+		if o, err := p.Quote(s); err == nil {
+			options = append(options, o)
+		}
+	}
+	return options
+}
+
+// Select finds the option with id, or the cheapest one when id is empty
+func Select(options []Option, id string) (Option, error) {
+	if len(options) == 0 {
+		return Option{}, ErrNoOptions
+	}
+	if id == "" {
+		cheapest := options[0]
+		for _, o := range options[1:] {
+			if o.Amount < cheapest.Amount {
+				cheapest = o
+			}
+		}
+		return cheapest, nil
+	}
+	for _, o := range options {
+		if o.Id == id {
+			return o, nil
+		}
+	}
+	return Option{}, ErrUnknownOption
+}
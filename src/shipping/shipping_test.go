@@ -0,0 +1,115 @@
+package shipping
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProviders(t *testing.T) {
+
+	us := Address{Country: "us"}
+	tiers := WeightTiers{
+		Service: Service{Id: "standard", Countries: []string{"US"}},
+		Tiers:   []Tier{{UpToGrams: 1000, Amount: 990}, {UpToGrams: 5000, Amount: 1990}},
+	}
+
+	tests := []struct {
+		name       string
+		provider   ShippingRateProvider
+		shipment   Shipment
+		wantAmount int
+		wantErr    error
+	}{
+		{name: "Flat rate", provider: FlatRate{Service: Service{Id: "flat"}, Amount: 500}, shipment: Shipment{Address: us}, wantAmount: 500},
+		{name: "Country not delivered to", provider: tiers, shipment: Shipment{Address: Address{Country: "BR"}}, wantErr: ErrUnavailable},
+		{
+			name:       "First tier the weight fits in",
+			provider:   tiers,
+			shipment:   Shipment{Address: us, Items: []Item{{Quantity: 2, WeightGrams: 600}}},
+			wantAmount: 1990,
+		},
+		{
+			name:       "Gifts weigh nothing",
+			provider:   tiers,
+			shipment:   Shipment{Address: us, Items: []Item{{Quantity: 1, WeightGrams: 600}, {Quantity: 1, WeightGrams: 3000, IsGift: true}}},
+			wantAmount: 990,
+		},
+		{
+			name:     "Heavier than every tier",
+			provider: tiers,
+			shipment: Shipment{Address: us, Items: []Item{{Quantity: 1, WeightGrams: 6000}}},
+			wantErr:  ErrUnavailable,
+		},
+		{
+			name:       "Free over threshold",
+			provider:   FreeOverThreshold{Provider: tiers, Threshold: 10000},
+			shipment:   Shipment{Address: us, Amount: 10000, Items: []Item{{Quantity: 1, WeightGrams: 600}}},
+			wantAmount: 0,
+		},
+		{
+			name:       "Below threshold",
+			provider:   FreeOverThreshold{Provider: tiers, Threshold: 10000},
+			shipment:   Shipment{Address: us, Amount: 9999, Items: []Item{{Quantity: 1, WeightGrams: 600}}},
+			wantAmount: 990,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.provider.Quote(tt.shipment)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Incorrect error: want=%v, got=%v", tt.name, tt.wantErr, err)
+		}
+		if tt.wantAmount != got.Amount {
+			t.Errorf("%s: Incorrect Amount: want=%d, got=%d", tt.name, tt.wantAmount, got.Amount)
+		}
+	}
+}
+
+func TestShipmentWeightGrams(t *testing.T) {
+
+	// 40x30x20cm is 24000cm³, 4800g at 5000cm³/kg
+	s := Shipment{Items: []Item{{Quantity: 2, WeightGrams: 1000, Dimensions: Dimensions{LengthCm: 40, WidthCm: 30, HeightCm: 20}}}}
+
+	if got := s.WeightGrams(0); got != 2000 {
+		t.Errorf("Incorrect weight without dimensional weight: want=2000, got=%d", got)
+	}
+	if got := s.WeightGrams(5000); got != 9600 {
+		t.Errorf("Incorrect dimensional weight: want=9600, got=%d", got)
+	}
+}
+
+func TestSelect(t *testing.T) {
+
+	options := []Option{{Id: "standard", Amount: 990}, {Id: "pickup", Amount: 0}, {Id: "express", Amount: 2990}}
+
+	if got, _ := Select(options, ""); got.Id != "pickup" {
+		t.Errorf("Incorrect default option: want=pickup, got=%s", got.Id)
+	}
+	if got, _ := Select(options, "express"); got.Id != "express" {
+		t.Errorf("Incorrect selected option: want=express, got=%s", got.Id)
+	}
+	if _, err := Select(options, "drone"); err != ErrUnknownOption {
+		t.Errorf("Incorrect error for unknown option: want=%v, got=%v", ErrUnknownOption, err)
+	}
+	if _, err := Select(nil, ""); err != ErrNoOptions {
+		t.Errorf("Incorrect error without options: want=%v, got=%v", ErrNoOptions, err)
+	}
+}
+
+func TestLoadProvidersFromFile(t *testing.T) {
+
+	providers, err := LoadProvidersFromFile("../../data/shipping_rates.json")
+	if err != nil {
+		t.Fatalf("Failed to load shipping rates: %v", err)
+	}
+	if len(providers) != 3 {
+		t.Errorf("Incorrect number of providers: want=3, got=%d", len(providers))
+	}
+
+	if _, err := newProvider(providerJSON{Id: "x", Type: "drone"}); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("Unknown types should be rejected: got=%v", err)
+	}
+	if _, err := newProvider(providerJSON{Id: "x", Type: "weight_tiers", Tiers: []Tier{{UpToGrams: 5000}, {UpToGrams: 1000}}}); !errors.Is(err, ErrInvalidProvider) {
+		t.Errorf("Unsorted tiers should be rejected: got=%v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package tax
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/gussf/backend-challenge/src/money"
+)
+
+// DefaultCategory is the tax category of products without one, and the rate
+// used for categories a region doesn't list
+const DefaultCategory = "standard"
+
+var (
+	ErrUnknownRegion = errors.New("region not found in tax table")
+	ErrUnknownMode   = errors.New("unknown tax mode")
+	ErrInvalidRate   = errors.New("tax rate must be between 0 and 10000 basis points")
+)
+
+// Mode tells whether catalog prices already include tax
+type Mode int
+
+const (
+	// Exclusive prices have tax added on top of them
+	Exclusive Mode = iota
+	// Inclusive prices already have tax in them, which is only broken down
+	Inclusive
+)
+
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(s) {
+	case "", "exclusive":
+		return Exclusive, nil
+	case "inclusive":
+		return Inclusive, nil
+	default:
+		return Exclusive, ErrUnknownMode
+	}
+}
+
+func (m Mode) String() string {
+	if m == Inclusive {
+		return "inclusive"
+	}
+	return "exclusive"
+}
+
+// Table holds the rates of each destination region, by tax category
+type Table struct {
+	regions map[string]map[string]money.BasisPoints
+}
+
+type tableJSON struct {
+	Regions map[string]map[string]money.BasisPoints `json:"regions"`
+}
+
+func NewTable(regions map[string]map[string]money.BasisPoints) (Table, error) {
+	t := Table{regions: make(map[string]map[string]money.BasisPoints)}
+	for region, rates := range regions {
+		normalized := make(map[string]money.BasisPoints)
+		for category, rate := range rates {
+			if rate < 0 || rate > money.OneHundredPercent {
+				return Table{}, fmt.Errorf("%s %s: %w", region, category, ErrInvalidRate)
+			}
+			normalized[strings.ToLower(category)] = rate
+		}
+		t.regions[NormalizeRegion(region)] = normalized
+	}
+	return t, nil
+}
+
+// NewTableFromFile reads rates in basis points, such as {"regions": {"US-CA": {"standard": 725}}}
+func NewTableFromFile(jsonFilePath string) (Table, error) {
+
+	var content tableJSON
+
+	file, err := ioutil.ReadFile(jsonFilePath)
+	if err != nil {
+		return Table{}, errors.New("error opening file " + jsonFilePath + ": " + err.Error())
+	}
+
+	err = json.Unmarshal(file, &content)
+	if err != nil {
+		return Table{}, errors.New("error unmarshalling json: " + err.Error())
+	}
+
+	return NewTable(content.Regions)
+}
+
+func NormalizeRegion(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (t Table) Regions() int {
+	return len(t.regions)
+}
+
+// Rate finds the rate of category in region, falling back to the region standard rate
+func (t Table) Rate(region string, category string) (money.BasisPoints, error) {
+	rates, ok := t.regions[NormalizeRegion(region)]
+	if !ok {
+		return 0, ErrUnknownRegion
+	}
+	if rate, ok := rates[strings.ToLower(category)]; ok && category != "" {
+		return rate, nil
+	}
+	return rates[DefaultCategory], nil
+}
+
+// Line is what is left to pay for a line after every discount, and the rate it is taxed at
+type Line struct {
+	Amount int
+	Rate   money.BasisPoints
+}
+
+// Calculate returns the tax of each line. Exclusive tax is the rate applied to the amount,
+// inclusive tax is the part of the amount that the rate added to it.
+// At order level, the tax of all lines with the same rate is rounded once and
+// spread back into them, so that the lines still add up to it
+func Calculate(lines []Line, mode Mode, rounding money.Rounding) []int {
+	taxes := make([]int, len(lines))
+	numerators := make([]int64, len(lines))
+	for i, l := range lines {
+		if l.Amount > 0 {
+			numerators[i] = int64(l.Amount) * int64(l.Rate)
+		}
+	}
+
+	if rounding.Level == money.LineLevel {
+		for i, l := range lines {
+			taxes[i] = int(rounding.Mode.Divide(numerators[i], denominator(l.Rate, mode)))
+		}
+		return taxes
+	}
+
+	byRate := make(map[money.BasisPoints][]int)
+	var rates []money.BasisPoints
+	for i, l := range lines {
+		if _, ok := byRate[l.Rate]; !ok {
+			rates = append(rates, l.Rate)
+		}
+		byRate[l.Rate] = append(byRate[l.Rate], i)
+	}
+
+	for _, rate := range rates {
+		indexes := byRate[rate]
+		group := make([]int64, len(indexes))
+		for j, i := range indexes {
+			group[j] = numerators[i]
+		}
+		for j, tax := range rounding.Mode.Allocate(group, denominator(rate, mode)) {
+			taxes[indexes[j]] = int(tax)
+		}
+	}
+	return taxes
+}
+
+func denominator(rate money.BasisPoints, mode Mode) int64 {
+	if mode == Inclusive {
+		return int64(money.OneHundredPercent + rate)
+	}
+	return int64(money.OneHundredPercent)
+}
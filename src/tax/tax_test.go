@@ -0,0 +1,83 @@
+package tax
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gussf/backend-challenge/src/money"
+)
+
+func TestTableRate(t *testing.T) {
+
+	table, err := NewTable(map[string]map[string]money.BasisPoints{
+		"us-ca": {"standard": 725, "food": 0},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	tests := []struct {
+		region   string
+		category string
+		want     money.BasisPoints
+		wantErr  error
+	}{
+		{region: "US-CA", category: "food", want: 0},
+		{region: " us-ca", category: "Clothing", want: 725},
+		{region: "US-CA", category: "", want: 725},
+		{region: "US-NY", category: "food", wantErr: ErrUnknownRegion},
+	}
+
+	for _, tt := range tests {
+		got, err := table.Rate(tt.region, tt.category)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s/%s: Incorrect error: want=%v, got=%v", tt.region, tt.category, tt.wantErr, err)
+		}
+		if tt.want != got {
+			t.Errorf("%s/%s: Incorrect rate: want=%d, got=%d", tt.region, tt.category, tt.want, got)
+		}
+	}
+
+	if _, err := NewTable(map[string]map[string]money.BasisPoints{"DE": {"standard": -1}}); !errors.Is(err, ErrInvalidRate) {
+		t.Errorf("Negative rates should be rejected: got=%v", err)
+	}
+}
+
+func TestNewTableFromFile(t *testing.T) {
+
+	table, err := NewTableFromFile("../../data/tax_rates.json")
+	if err != nil {
+		t.Fatalf("Failed to load tax rates: %v", err)
+	}
+	if table.Regions() == 0 {
+		t.Errorf("Tax rates file has no regions")
+	}
+}
+
+func TestCalculate(t *testing.T) {
+
+	lines := []Line{{Amount: 1005, Rate: 1000}, {Amount: 1005, Rate: 1000}, {Amount: 999, Rate: 0}}
+
+	tests := []struct {
+		name     string
+		mode     Mode
+		rounding money.Rounding
+		want     []int
+	}{
+		// 100.5 cents each
+		{name: "Exclusive line rounding", mode: Exclusive, rounding: money.Rounding{Mode: money.HalfUp, Level: money.LineLevel}, want: []int{101, 101, 0}},
+		{name: "Exclusive order rounding", mode: Exclusive, rounding: money.Rounding{Mode: money.HalfUp, Level: money.OrderLevel}, want: []int{101, 100, 0}},
+		// 1005/11 = 91.36 cents each
+		{name: "Inclusive line rounding", mode: Inclusive, rounding: money.Rounding{Mode: money.HalfUp, Level: money.LineLevel}, want: []int{91, 91, 0}},
+		{name: "Inclusive order rounding", mode: Inclusive, rounding: money.Rounding{Mode: money.HalfUp, Level: money.OrderLevel}, want: []int{92, 91, 0}},
+	}
+
+	for _, tt := range tests {
+		got := Calculate(lines, tt.mode, tt.rounding)
+		for i := range tt.want {
+			if tt.want[i] != got[i] {
+				t.Errorf("%s: Incorrect tax of line %d: want=%d, got=%d", tt.name, i, tt.want[i], got[i])
+			}
+		}
+	}
+}